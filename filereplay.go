@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	sbf "Septentrio-GNSS-Adapter/sbf"
+)
+
+/**
+ * File connection type: recorded SBF files, plain or gzip compressed, are
+ * replayed through the same decoding and publishing as live data. Files are
+ * read as fast as possible, or paced by the time of their SBF blocks in real
+ * time or at a multiple of it.
+ */
+
+/* Data without an SBF block is passed on once this much is buffered */
+const replayMaxPending = 65536 + 8
+
+/* Jumps of the receiver time that restart the pace, e.g. between files */
+const replayResyncBack = time.Minute
+const replayResyncGap = 10 * time.Minute
+
+/* Receiver time of the latest replayed block, the clock of the decoder */
+var replayClock time.Time
+
+/* Wall clock and receiver time pacing the replay */
+var replayStart time.Time
+var replayReference time.Time
+
+// Returns the files to replay, sorted by name: filePath is a file, a glob
+// pattern or a directory, of which the .sbf and .sbf.gz files are replayed
+func replayFiles() []string {
+	if info, err := os.Stat(adapterSettings.FilePath); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(adapterSettings.FilePath)
+		if err != nil {
+			log.Printf("[ERROR] replayFiles - Error reading directory %s: %s\n", adapterSettings.FilePath, err.Error())
+			return nil
+		}
+		var files []string
+		for _, entry := range entries {
+			if !entry.IsDir() && isSBFFile(entry.Name()) {
+				files = append(files, filepath.Join(adapterSettings.FilePath, entry.Name()))
+			}
+		}
+		sort.Strings(files)
+		return files
+	}
+	files, err := filepath.Glob(adapterSettings.FilePath)
+	if err != nil {
+		log.Printf("[ERROR] replayFiles - Invalid filePath pattern %s: %s\n", adapterSettings.FilePath, err.Error())
+		return nil
+	}
+	sort.Strings(files)
+	return files
+}
+
+func readFromFiles() {
+	sbf.SetClock(func() time.Time {
+		if replayClock.IsZero() {
+			return time.Now()
+		}
+		return replayClock
+	})
+
+	files := replayFiles()
+	if len(files) == 0 {
+		log.Printf("[ERROR] readFromFiles - No SBF files found at %s\n", adapterSettings.FilePath)
+	}
+	for _, name := range files {
+		if !replayFile(name) {
+			log.Println("[DEBUG] readFromFiles - stopping file read worker")
+			return
+		}
+	}
+	log.Printf("[INFO] readFromFiles - Replay of %d files finished\n", len(files))
+
+	<-endWorkersChannel
+	log.Println("[DEBUG] readFromFiles - stopping file read worker")
+}
+
+// Replays one file, returns false when the adapter is stopped during the
+// replay
+func replayFile(name string) bool {
+	file, err := os.Open(name)
+	if err != nil {
+		log.Printf("[ERROR] replayFile - Error opening SBF file %s: %s\n", name, err.Error())
+		return true
+	}
+	defer file.Close()
+	log.Printf("[INFO] replayFile - Replaying %s\n", name)
+	replayStart = time.Time{}
+
+	// gzip files are recognized by their magic number rather than their name
+	var reader io.Reader = bufio.NewReader(file)
+	if magic, err := reader.(*bufio.Reader).Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			log.Printf("[ERROR] replayFile - Error opening gzip file %s: %s\n", name, err.Error())
+			return true
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	var pending []byte
+	buff := make([]byte, 4096)
+	for {
+		select {
+		case <-endWorkersChannel:
+			return false
+		default:
+		}
+
+		n, err := reader.Read(buff)
+		pending = append(pending, buff[:n]...)
+
+		// Blocks are passed on one at a time so they can be paced
+		for {
+			block, ok := sbf.NextBlock(pending)
+			if !ok {
+				break
+			}
+			if !paceReplay(block) {
+				return false
+			}
+			replayData(pending[:block.End])
+			pending = pending[block.End:]
+		}
+		if len(pending) > replayMaxPending {
+			replayData(pending[:len(pending)-replayMaxPending])
+			pending = pending[len(pending)-replayMaxPending:]
+		}
+
+		if err == io.EOF {
+			replayData(pending)
+			return true
+		} else if err != nil {
+			log.Printf("[ERROR] replayFile - Error reading SBF file %s: %s\n", name, err.Error())
+			return true
+		}
+	}
+}
+
+// Waits until a block is due at the replay speed, returns false when the
+// adapter is stopped meanwhile. The pace restarts with every file and when
+// the receiver time jumps back or leaps forward, so gaps in the recordings are
+// not waited for.
+func paceReplay(block sbf.BlockInfo) bool {
+	if block.Time == nil {
+		return true
+	}
+	blockTime := *block.Time
+	if !replayClock.IsZero() && (replayClock.Sub(blockTime) > replayResyncBack || blockTime.Sub(replayClock) > replayResyncGap) {
+		replayStart = time.Time{}
+	}
+	replayClock = blockTime
+	if adapterSettings.ReplaySpeed <= 0 {
+		return true
+	}
+
+	if replayStart.IsZero() {
+		replayStart = time.Now()
+		replayReference = blockTime
+		return true
+	}
+	due := replayStart.Add(time.Duration(float64(blockTime.Sub(replayReference)) / adapterSettings.ReplaySpeed))
+	if wait := time.Until(due); wait > 0 {
+		select {
+		case <-endWorkersChannel:
+			return false
+		case <-time.After(wait):
+		}
+	}
+	return true
+}
+
+func replayData(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	buffer = append(buffer, data...)
+	publishPayloads(sbf.Parse(&buffer))
+}
+
+// Tells whether a file name looks like a recorded SBF file
+func isSBFFile(name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".gz")
+	return strings.HasSuffix(name, ".sbf")
+}
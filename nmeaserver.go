@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+ * SBF to NMEA gateway: the NMEA sentences generated from the receiver's SBF
+ * output are served to every client connected to nmeaTcpPort, for chart
+ * plotters, autopilots and other consumers that only speak NMEA 0183. Every
+ * client has its own queue and writer so a slow client never blocks the
+ * reader, clients whose queue is full are disconnected.
+ */
+
+/* Clients slower than this are disconnected */
+const nmeaClientWriteTimeout = 5 * time.Second
+
+/* Number of outputs queued for a client before it is disconnected */
+const nmeaClientQueueLength = 64
+
+/* Wait before accepting clients again after an error */
+const nmeaAcceptRetryDelay = time.Second
+
+type nmeaClient struct {
+	conn      net.Conn
+	sentences chan []byte
+}
+
+var (
+	nmeaClients      = map[net.Conn]*nmeaClient{}
+	nmeaClientsMutex sync.Mutex
+)
+
+func startNMEAServer() {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(adapterSettings.NMEATcpPort))
+	if err != nil {
+		log.Fatalf("[FATAL] startNMEAServer - Error listening on NMEA tcp port %d: %s\n", adapterSettings.NMEATcpPort, err.Error())
+	}
+	log.Printf("[INFO] startNMEAServer - Serving NMEA sentences on tcp port %d\n", adapterSettings.NMEATcpPort)
+
+	go func() {
+		<-endWorkersChannel
+		log.Println("[DEBUG] startNMEAServer - stopping NMEA server")
+		listener.Close()
+		nmeaClientsMutex.Lock()
+		for _, client := range nmeaClients {
+			dropNMEAClient(client)
+		}
+		nmeaClientsMutex.Unlock()
+	}()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				log.Printf("[ERROR] startNMEAServer - Error accepting NMEA client: %s\n", err.Error())
+				time.Sleep(nmeaAcceptRetryDelay)
+				continue
+			}
+			log.Printf("[INFO] startNMEAServer - NMEA client %s connected\n", conn.RemoteAddr().String())
+			client := &nmeaClient{conn: conn, sentences: make(chan []byte, nmeaClientQueueLength)}
+			nmeaClientsMutex.Lock()
+			nmeaClients[conn] = client
+			nmeaClientsMutex.Unlock()
+			go writeToNMEAClient(client)
+		}
+	}()
+}
+
+// Queues sentences for every connected NMEA client, dropping the clients
+// that do not keep up
+func writeToNMEAClients(sentences []byte) {
+	nmeaClientsMutex.Lock()
+	defer nmeaClientsMutex.Unlock()
+
+	for _, client := range nmeaClients {
+		select {
+		case client.sentences <- sentences:
+		default:
+			log.Printf("[INFO] writeToNMEAClients - NMEA client %s too slow, disconnecting\n", client.conn.RemoteAddr().String())
+			dropNMEAClient(client)
+		}
+	}
+}
+
+// Writes the sentences queued for a client until it is dropped
+func writeToNMEAClient(client *nmeaClient) {
+	for sentences := range client.sentences {
+		client.conn.SetWriteDeadline(time.Now().Add(nmeaClientWriteTimeout))
+		if _, err := client.conn.Write(sentences); err != nil {
+			log.Printf("[INFO] writeToNMEAClient - NMEA client %s disconnected: %s\n", client.conn.RemoteAddr().String(), err.Error())
+			nmeaClientsMutex.Lock()
+			dropNMEAClient(client)
+			nmeaClientsMutex.Unlock()
+			return
+		}
+	}
+}
+
+// Disconnects a client, nmeaClientsMutex must be held
+func dropNMEAClient(client *nmeaClient) {
+	if nmeaClients[client.conn] != client {
+		return
+	}
+	delete(nmeaClients, client.conn)
+	close(client.sentences)
+	client.conn.Close()
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sbf "Septentrio-GNSS-Adapter/sbf"
+)
+
+/**
+ * NTRIP client: the RTCM corrections of a caster mountpoint are written to the
+ * receiver port, for receivers without an NTRIP client or internet access of
+ * their own. The position of the receiver is sent to the caster as GGA for VRS
+ * mountpoints. A closed or silent stream is reconnected with an exponential
+ * backoff, and the state of the stream is published on ntrip/status.
+ */
+
+const ntripStatusTopic = "ntrip/status"
+
+/* Seconds between two NTRIPStatus messages */
+const ntripStatusInterval = 10
+
+const ntripUserAgent = "NTRIP SeptentrioGNSSAdapter/1.0"
+const ntripDialTimeout = 10 * time.Second
+
+/* A stream without data for this long is reconnected */
+const ntripReadTimeout = 30 * time.Second
+
+/* Waits before reconnecting, doubled after every failed connection */
+const ntripMinBackoff = time.Second
+const ntripMaxBackoff = 2 * time.Minute
+
+// NTRIPStatus is the state of the adapter's NTRIP client
+type NTRIPStatus struct {
+	Time          time.Time `json:"time"`
+	Mountpoint    string    `json:"mountpoint"`
+	Connected     bool      `json:"connected"`
+	Connections   int       `json:"connections"`             // Successful connections since the adapter started
+	Bytes         int       `json:"bytes"`                   // Correction bytes written to the receiver
+	LastDataAge   *float64  `json:"lastDataAge,omitempty"`   // s since the caster last sent data
+	CorrectionAge *float64  `json:"correctionAge,omitempty"` // s, mean age of the corrections used by the receiver
+	LastError     string    `json:"lastError,omitempty"`
+}
+
+var (
+	ntripStatus      = NTRIPStatus{}
+	ntripLastData    time.Time
+	ntripStatusMutex sync.Mutex
+
+	// GGA sentence of the receiver position, replaced by the tests
+	ntripGGA = sbf.LatestGGA
+	// First wait before reconnecting and its timer, replaced by the tests
+	ntripBackoff = ntripMinBackoff
+	ntripAfter   = time.After
+)
+
+func ntripWorker() {
+	log.Printf("[INFO] ntripWorker - Starting NTRIP client for %s:%d/%s\n", adapterSettings.NtripCaster, adapterSettings.NtripPort, adapterSettings.NtripMountpoint)
+	ntripStatus.Mountpoint = adapterSettings.NtripMountpoint
+
+	stop := make(chan struct{})
+	go func() {
+		<-endWorkersChannel
+		log.Println("[DEBUG] ntripWorker - stopping NTRIP client")
+		close(stop)
+	}()
+	go func() {
+		ticker := time.NewTicker(ntripStatusInterval * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				publishNTRIPStatus()
+			}
+		}
+	}()
+
+	backoff := ntripBackoff
+	for {
+		received, err := ntripSession(stop)
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if received {
+			backoff = ntripBackoff
+		}
+		log.Printf("[ERROR] ntripWorker - NTRIP stream ended, reconnecting in %s: %s\n", backoff, err.Error())
+		ntripStatusMutex.Lock()
+		ntripStatus.Connected = false
+		ntripStatus.LastError = err.Error()
+		ntripStatusMutex.Unlock()
+		publishNTRIPStatus()
+
+		select {
+		case <-stop:
+			return
+		case <-ntripAfter(backoff):
+		}
+		backoff *= 2
+		if backoff > ntripMaxBackoff {
+			backoff = ntripMaxBackoff
+		}
+	}
+}
+
+// Connects to the caster and forwards its stream to the receiver until the
+// stream ends or the adapter is stopped. Returns whether corrections were
+// received.
+func ntripSession(stop chan struct{}) (bool, error) {
+	address := net.JoinHostPort(adapterSettings.NtripCaster, strconv.Itoa(adapterSettings.NtripPort))
+	conn, err := net.DialTimeout("tcp", address, ntripDialTimeout)
+	if err != nil {
+		return false, err
+	}
+	done := make(chan struct{})
+	// The position sender is stopped and waited for when the session ends
+	var sender sync.WaitGroup
+	defer sender.Wait()
+	defer close(done)
+	go func() {
+		// Unblocks the reads when the adapter is stopped
+		select {
+		case <-stop:
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	conn.SetDeadline(time.Now().Add(ntripReadTimeout))
+	if _, err := conn.Write([]byte(ntripRequest(ntripGGA()))); err != nil {
+		return false, err
+	}
+	stream, err := ntripResponse(bufio.NewReader(conn))
+	if err != nil {
+		return false, err
+	}
+	log.Printf("[INFO] ntripSession - Connected to NTRIP mountpoint %s\n", adapterSettings.NtripMountpoint)
+	ntripStatusMutex.Lock()
+	ntripStatus.Connected = true
+	ntripStatus.Connections++
+	ntripStatus.LastError = ""
+	ntripStatusMutex.Unlock()
+	publishNTRIPStatus()
+
+	sender.Add(1)
+	go func() {
+		defer sender.Done()
+		sendNTRIPPosition(conn, done)
+	}()
+
+	received := false
+	buff := make([]byte, 4096)
+	for {
+		conn.SetReadDeadline(time.Now().Add(ntripReadTimeout))
+		n, err := stream.Read(buff)
+		if n > 0 {
+			log.Printf("[DEBUG] ntripSession - %d correction bytes received\n", n)
+			received = true
+			ntripStatusMutex.Lock()
+			ntripStatus.Bytes += n
+			ntripLastData = time.Now()
+			ntripStatusMutex.Unlock()
+			writeToPort(buff[:n])
+		}
+		if err == io.EOF {
+			return received, errors.New("stream closed by the caster")
+		} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return received, fmt.Errorf("no data received for %s", ntripReadTimeout)
+		} else if err != nil {
+			return received, err
+		}
+	}
+}
+
+// Sends the receiver position to the caster every ntripGgaInterval seconds
+// while the stream is open, starting as soon as the receiver has a fix. VRS
+// mountpoints of NTRIP 1 casters only stream once they got the first one.
+func sendNTRIPPosition(conn net.Conn, done chan struct{}) {
+	ticker := time.NewTicker(time.Duration(adapterSettings.NtripGGAInterval * float64(time.Second)))
+	defer ticker.Stop()
+
+	for {
+		if gga := ntripGGA(); gga != "" {
+			conn.SetWriteDeadline(time.Now().Add(ntripReadTimeout))
+			if _, err := conn.Write([]byte(gga)); err != nil {
+				log.Printf("[ERROR] sendNTRIPPosition - Error sending GGA to the caster: %s\n", err.Error())
+				return
+			}
+			log.Printf("[DEBUG] sendNTRIPPosition - Sent %s", gga)
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Builds the NTRIP 1 or NTRIP 2 request of the mountpoint, an NTRIP 2 request
+// has the position in its Ntrip-GGA header
+func ntripRequest(gga string) string {
+	var request strings.Builder
+	mountpoint := strings.TrimPrefix(adapterSettings.NtripMountpoint, "/")
+	if adapterSettings.NtripVersion == 1 {
+		fmt.Fprintf(&request, "GET /%s HTTP/1.0\r\n", mountpoint)
+	} else {
+		fmt.Fprintf(&request, "GET /%s HTTP/1.1\r\n", mountpoint)
+		fmt.Fprintf(&request, "Host: %s\r\n", net.JoinHostPort(adapterSettings.NtripCaster, strconv.Itoa(adapterSettings.NtripPort)))
+		request.WriteString("Ntrip-Version: Ntrip/2.0\r\n")
+		if gga != "" {
+			fmt.Fprintf(&request, "Ntrip-GGA: %s\r\n", strings.TrimSpace(gga))
+		}
+		request.WriteString("Connection: close\r\n")
+	}
+	fmt.Fprintf(&request, "User-Agent: %s\r\n", ntripUserAgent)
+	if adapterSettings.NtripUsername != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(adapterSettings.NtripUsername + ":" + adapterSettings.NtripPassword))
+		fmt.Fprintf(&request, "Authorization: Basic %s\r\n", credentials)
+	}
+	request.WriteString("\r\n")
+	return request.String()
+}
+
+// Reads the response of the caster and returns the correction stream. NTRIP 1
+// casters answer ICY 200 OK, NTRIP 2 casters an HTTP response that may be
+// chunked, and both answer with their source table when the mountpoint is
+// unknown.
+func ntripResponse(reader *bufio.Reader) (io.Reader, error) {
+	start, err := reader.Peek(4)
+	if err != nil {
+		return nil, err
+	}
+	switch string(start) {
+	case "ICY ":
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(line, "200") {
+			return nil, fmt.Errorf("caster refused the connection: %s", strings.TrimSpace(line))
+		}
+		return &icyStream{reader: reader}, nil
+	case "SOUR":
+		return nil, fmt.Errorf("mountpoint %s not found on the caster", adapterSettings.NtripMountpoint)
+	case "HTTP":
+	default:
+		// NTRIP 1 casters answer errors with a line of text, e.g. for a bad password
+		line, _ := reader.ReadString('\n')
+		return nil, fmt.Errorf("caster refused the connection: %s", strings.TrimSpace(line))
+	}
+
+	response, err := http.ReadResponse(reader, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("caster refused the connection: %s", response.Status)
+	}
+	if strings.HasPrefix(response.Header.Get("Content-Type"), "gnss/sourcetable") {
+		return nil, fmt.Errorf("mountpoint %s not found on the caster", adapterSettings.NtripMountpoint)
+	}
+	return response.Body, nil
+}
+
+// icyStream is the correction stream of an NTRIP 1 caster. Some casters end
+// the ICY 200 OK response with a blank line, which is not data. It is skipped
+// on the first read rather than waited for, as VRS mountpoints send nothing
+// before they got the receiver position.
+type icyStream struct {
+	reader  *bufio.Reader
+	started bool
+}
+
+func (s *icyStream) Read(p []byte) (int, error) {
+	if !s.started {
+		s.started = true
+		if end, err := s.reader.Peek(2); err == nil && string(end) == "\r\n" {
+			s.reader.Discard(2)
+		}
+	}
+	return s.reader.Read(p)
+}
+
+func publishNTRIPStatus() {
+	ntripStatusMutex.Lock()
+	status := ntripStatus
+	if !ntripLastData.IsZero() {
+		age := time.Since(ntripLastData).Seconds()
+		status.LastDataAge = &age
+	}
+	ntripStatusMutex.Unlock()
+
+	status.Time = time.Now().UTC()
+	status.CorrectionAge = sbf.CorrectionAge()
+	publish(adapterConfig.TopicRoot+"/"+portRead+"/"+ntripStatusTopic, status)
+}
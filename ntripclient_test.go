@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	adapter_library "github.com/clearblade/adapter-go-library"
+)
+
+/**
+ * Tests of the NTRIP client against a stand-in caster listening on a local
+ * port. The receiver port is one end of a pipe, the corrections written to it
+ * are collected from the other end.
+ */
+
+const testMountpoint = "VRS"
+
+// testReceiver collects the bytes written to the receiver port
+type testReceiver struct {
+	mutex sync.Mutex
+	data  []byte
+}
+
+func (r *testReceiver) received() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return string(r.data)
+}
+
+// setupNTRIPTest configures the NTRIP client for the stand-in caster on
+// casterPort and returns the receiver the corrections are written to
+func setupNTRIPTest(t *testing.T, version int, casterPort int) *testReceiver {
+	t.Helper()
+	previousSettings, previousConfig, previousPort, previousPublish, previousGGA := adapterSettings, adapterConfig, port, mqttPublish, ntripGGA
+	adapterSettings = &SeptentrioGNSSAdapterSettings{
+		ConnectionType:   "tcp",
+		TcpHost:          "127.0.0.1",
+		NtripCaster:      "127.0.0.1",
+		NtripPort:        casterPort,
+		NtripMountpoint:  testMountpoint,
+		NtripUsername:    "user",
+		NtripPassword:    "password",
+		NtripVersion:     version,
+		NtripGGAInterval: 10,
+	}
+	adapterConfig = &adapter_library.AdapterConfig{TopicRoot: "gnss"}
+	mqttPublish = func(topic string, data []byte) error { return nil }
+	ntripGGA = func() string { return "" }
+	ntripStatusMutex.Lock()
+	ntripStatus = NTRIPStatus{}
+	ntripLastData = time.Time{}
+	ntripStatusMutex.Unlock()
+
+	receiverSide, adapterSide := net.Pipe()
+	port = adapterSide
+	receiver := &testReceiver{}
+	go func() {
+		buff := make([]byte, 1024)
+		for {
+			n, err := receiverSide.Read(buff)
+			receiver.mutex.Lock()
+			receiver.data = append(receiver.data, buff[:n]...)
+			receiver.mutex.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	t.Cleanup(func() {
+		adapterSide.Close()
+		receiverSide.Close()
+		adapterSettings, adapterConfig, port, mqttPublish, ntripGGA = previousSettings, previousConfig, previousPort, previousPublish, previousGGA
+	})
+	return receiver
+}
+
+// startStandInCaster accepts connections on a local port and answers the
+// requests in turn with the responses, closing the connection afterwards. The
+// requests are sent on the returned channel.
+func startStandInCaster(t *testing.T, responses ...func(net.Conn)) (int, chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	requests := make(chan string, len(responses))
+	go func() {
+		for _, respond := range responses {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := bufio.NewReader(conn)
+			var request strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				request.WriteString(line)
+				if err != nil || line == "\r\n" {
+					break
+				}
+			}
+			requests <- request.String()
+			respond(conn)
+			conn.Close()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, requests
+}
+
+func waitForRequest(t *testing.T, requests chan string) string {
+	t.Helper()
+	select {
+	case request := <-requests:
+		return request
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request received by the caster")
+		return ""
+	}
+}
+
+func waitForCorrections(t *testing.T, receiver *testReceiver, expected string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for receiver.received() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("receiver got %q, expected %q", receiver.received(), expected)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNTRIPSession(t *testing.T) {
+	corrections := "\xd3\x00\x03\x3e\xd0\x00\x01\x02\x03"
+	credentials := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte("user:password")) + "\r\n"
+
+	tests := []struct {
+		name     string
+		version  int
+		response string
+		request  []string
+	}{
+		{
+			name:     "NTRIP 1",
+			version:  1,
+			response: "ICY 200 OK\r\n\r\n" + corrections,
+			request:  []string{"GET /VRS HTTP/1.0\r\n", credentials},
+		},
+		{
+			name:     "NTRIP 2",
+			version:  2,
+			response: "HTTP/1.1 200 OK\r\nContent-Type: gnss/data\r\nConnection: close\r\n\r\n" + corrections,
+			request:  []string{"GET /VRS HTTP/1.1\r\n", "Ntrip-Version: Ntrip/2.0\r\n", credentials},
+		},
+		{
+			name:    "NTRIP 2 chunked",
+			version: 2,
+			response: "HTTP/1.1 200 OK\r\nContent-Type: gnss/data\r\nTransfer-Encoding: chunked\r\n\r\n" +
+				"4\r\n" + corrections[:4] + "\r\n" + "5\r\n" + corrections[4:] + "\r\n0\r\n\r\n",
+			request: []string{"GET /VRS HTTP/1.1\r\n", "Ntrip-Version: Ntrip/2.0\r\n", credentials},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			casterPort, requests := startStandInCaster(t, func(conn net.Conn) {
+				io.WriteString(conn, test.response)
+			})
+			receiver := setupNTRIPTest(t, test.version, casterPort)
+
+			received, err := ntripSession(make(chan struct{}))
+			if !received || err == nil || err.Error() != "stream closed by the caster" {
+				t.Errorf("session returned %v, %v", received, err)
+			}
+			request := waitForRequest(t, requests)
+			if !strings.HasPrefix(request, test.request[0]) {
+				t.Errorf("request %q does not start with %q", request, test.request[0])
+			}
+			for _, header := range test.request[1:] {
+				if !strings.Contains(request, header) {
+					t.Errorf("request %q has no %q", request, header)
+				}
+			}
+			waitForCorrections(t, receiver, corrections)
+			if ntripStatus.Connections != 1 || ntripStatus.Bytes != len(corrections) {
+				t.Errorf("status has %d connections and %d bytes", ntripStatus.Connections, ntripStatus.Bytes)
+			}
+		})
+	}
+}
+
+func TestNTRIPVirtualReferenceStation(t *testing.T) {
+	corrections := "\xd3\x00\x03\x3e\xd0\x00\x01\x02\x03"
+	gga := "$GNGGA,000127.00,5048.0000000,N,00421.0000000,E,4,12,0.9,100.000,M,47.000,M,1.0,0000*4B\r\n"
+
+	// Like a VRS mountpoint, the caster only streams once it got the
+	// position, and then reads the positions sent periodically
+	type position struct {
+		sentence string
+		time     time.Time
+	}
+	positions := make(chan position, 3)
+	casterPort, requests := startStandInCaster(t, func(conn net.Conn) {
+		io.WriteString(conn, "ICY 200 OK\r\n")
+		reader := bufio.NewReader(conn)
+		for i := 0; i < 3; i++ {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			positions <- position{line, time.Now()}
+			if i == 0 {
+				io.WriteString(conn, corrections)
+			}
+		}
+	})
+	receiver := setupNTRIPTest(t, 1, casterPort)
+	adapterSettings.NtripGGAInterval = 0.1
+	ntripGGA = func() string { return gga }
+
+	received, err := ntripSession(make(chan struct{}))
+	if !received || err == nil || err.Error() != "stream closed by the caster" {
+		t.Errorf("session returned %v, %v", received, err)
+	}
+	if request := waitForRequest(t, requests); strings.Contains(request, "GNGGA") {
+		t.Errorf("NTRIP 1 request %q has the position", request)
+	}
+	waitForCorrections(t, receiver, corrections)
+	if len(positions) != 3 {
+		t.Fatalf("caster got %d positions, expected 3", len(positions))
+	}
+	first := <-positions
+	last := first
+	for len(positions) > 0 {
+		last = <-positions
+		if last.sentence != gga {
+			t.Errorf("caster got %q, expected %q", last.sentence, gga)
+		}
+	}
+	if first.sentence != gga || last.time.Sub(first.time) < 2*90*time.Millisecond {
+		t.Errorf("caster got %q and the next positions after %s", first.sentence, last.time.Sub(first.time))
+	}
+}
+
+func TestNTRIPResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		stream   string
+		err      string
+	}{
+		{"NTRIP 1", "ICY 200 OK\r\ndata", "data", ""},
+		{"NTRIP 2", "HTTP/1.1 200 OK\r\nContent-Type: gnss/data\r\n\r\ndata", "data", ""},
+		{"NTRIP 2 chunked", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nda\r\n2\r\nta\r\n0\r\n\r\n", "data", ""},
+		{"NTRIP 1 source table", "SOURCETABLE 200 OK\r\nContent-Type: text/plain\r\n\r\nENDSOURCETABLE\r\n", "", "mountpoint VRS not found on the caster"},
+		{"NTRIP 2 source table", "HTTP/1.1 200 OK\r\nContent-Type: gnss/sourcetable\r\n\r\nENDSOURCETABLE\r\n", "", "mountpoint VRS not found on the caster"},
+		{"NTRIP 1 refused", "ICY 401 Unauthorized\r\n", "", "caster refused the connection: ICY 401 Unauthorized"},
+		{"NTRIP 1 error line", "ERROR - Bad Password\r\n", "", "caster refused the connection: ERROR - Bad Password"},
+		{"NTRIP 2 refused", "HTTP/1.1 401 Unauthorized\r\nContent-Length: 0\r\n\r\n", "", "caster refused the connection: 401 Unauthorized"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setupNTRIPTest(t, 2, 2101)
+			stream, err := ntripResponse(bufio.NewReader(strings.NewReader(test.response)))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(stream)
+			if err != nil || string(data) != test.stream {
+				t.Errorf("stream has %q, %v, expected %q", data, err, test.stream)
+			}
+		})
+	}
+}
+
+func TestNTRIPWorkerReconnect(t *testing.T) {
+	refuse := func(conn net.Conn) {
+		io.WriteString(conn, "HTTP/1.1 401 Unauthorized\r\nContent-Length: 0\r\n\r\n")
+	}
+	stream := func(conn net.Conn) {
+		io.WriteString(conn, "ICY 200 OK\r\n\r\n\xd3\x00")
+	}
+	casterPort, requests := startStandInCaster(t, refuse, refuse, stream, stream)
+	receiver := setupNTRIPTest(t, 1, casterPort)
+	previousEndWorkers, previousBackoff, previousAfter := endWorkersChannel, ntripBackoff, ntripAfter
+	t.Cleanup(func() {
+		endWorkersChannel, ntripBackoff, ntripAfter = previousEndWorkers, previousBackoff, previousAfter
+	})
+	endWorkersChannel = make(chan string)
+	ntripBackoff = 10 * time.Millisecond
+	waits := make(chan time.Duration, 10)
+	ntripAfter = func(wait time.Duration) <-chan time.Time {
+		waits <- wait
+		return time.After(wait)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		ntripWorker()
+		close(stopped)
+	}()
+
+	for i := 0; i < 4; i++ {
+		waitForRequest(t, requests)
+	}
+	waitForCorrections(t, receiver, "\xd3\x00\xd3\x00")
+
+	// The backoff doubles after every refused connection and restarts from
+	// the first wait once the caster sent corrections
+	for _, expected := range []time.Duration{ntripBackoff, 2 * ntripBackoff, ntripBackoff} {
+		if wait := <-waits; wait != expected {
+			t.Errorf("reconnected after %s, expected %s", wait, expected)
+		}
+	}
+
+	endWorkersChannel <- "Stop Channel"
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("NTRIP worker not stopped")
+	}
+	ntripStatusMutex.Lock()
+	defer ntripStatusMutex.Unlock()
+	if ntripStatus.Connections != 2 {
+		t.Errorf("status has %d connections, expected 2", ntripStatus.Connections)
+	}
+}
@@ -0,0 +1,61 @@
+package sbf
+
+import "log"
+
+/**
+ * Alarms raised by the monitoring decoders. An alarm is only published when
+ * it changes state, so a condition that persists does not flood MQTT.
+ */
+
+// Alarm reports a monitored condition being raised or cleared
+type Alarm struct {
+	BlockTime
+	Alarm   string   `json:"alarm"`
+	Active  bool     `json:"active"`
+	Message string   `json:"message"`
+	Value   *float64 `json:"value,omitempty"`
+	Limit   *float64 `json:"limit,omitempty"`
+}
+
+// updateAlarm returns the alarm as a payload for topic when its state
+// changed. Alarms start cleared, so a clear alarm is not published until it
+// has been raised.
+func updateAlarm(topic string, alarm Alarm) []interface{} {
+	key := topic + "/" + alarm.Alarm
+	if state.alarmStates[key] == alarm.Active {
+		return []interface{}{}
+	}
+	state.alarmStates[key] = alarm.Active
+
+	if alarm.Active {
+		log.Printf("[WARN] updateAlarm - %s alarm raised: %s\n", alarm.Alarm, alarm.Message)
+	} else {
+		log.Printf("[INFO] updateAlarm - %s alarm cleared: %s\n", alarm.Alarm, alarm.Message)
+	}
+	return []interface{}{Payload{Topic: topic, Data: alarm}}
+}
+
+// alarmHysteresis debounces a condition: it becomes active after the
+// condition held for raiseCount consecutive updates and inactive after it
+// was absent for clearCount consecutive updates
+type alarmHysteresis struct {
+	active bool
+	count  int
+}
+
+func (h *alarmHysteresis) update(condition bool, raiseCount int, clearCount int) bool {
+	if condition == h.active {
+		h.count = 0
+		return h.active
+	}
+	h.count++
+	if (condition && h.count >= raiseCount) || (!condition && h.count >= clearCount) {
+		h.active = condition
+		h.count = 0
+	}
+	return h.active
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,152 @@
+package sbf
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Audit stream of the commands applied to the receiver, the user comments and
+ * the ASCII strings received from external devices. ASCIIIn strings are also
+ * published on their own topic, parsed with the first of
+ * settings.ASCIIInPatterns that matches them.
+ */
+
+const auditTopic = "audit"
+const asciiInTopic = "asciiin"
+
+// AuditEntry is a command, comment or external ASCII string logged by the
+// receiver
+type AuditEntry struct {
+	BlockTime
+	Kind       string `json:"kind"`                 // command, comment or asciiIn
+	Connection string `json:"connection,omitempty"` // Port an ASCIIIn string came in on
+	Text       string `json:"text"`
+}
+
+// ASCIIIn is a string received from an external device such as a
+// meteorological sensor
+type ASCIIIn struct {
+	BlockTime
+	Connection  string                 `json:"connection"`
+	SensorModel string                 `json:"sensorModel"`
+	SensorType  string                 `json:"sensorType"`
+	Text        string                 `json:"text"`
+	Pattern     string                 `json:"pattern,omitempty"` // Name of the pattern that parsed the string
+	Fields      map[string]interface{} `json:"fields,omitempty"`  // Named groups of the pattern, numbers converted
+}
+
+// ASCIIInPattern parses ASCIIIn strings with the named groups of a regular
+// expression, e.g. {"name": "met", "pattern": "^\\$WIMDA,(?P<pressure>[\\d.]+)"}
+type ASCIIInPattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+type compiledPattern struct {
+	name   string
+	regexp *regexp.Regexp
+}
+
+var asciiInPatterns = []compiledPattern{}
+
+// compileASCIIInPatterns compiles settings.ASCIIInPatterns, skipping the
+// invalid ones
+func compileASCIIInPatterns() {
+	asciiInPatterns = []compiledPattern{}
+	for _, pattern := range settings.ASCIIInPatterns {
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			log.Printf("[ERROR] compileASCIIInPatterns - Invalid pattern %s: %s\n", pattern.Name, err.Error())
+			continue
+		}
+		asciiInPatterns = append(asciiInPatterns, compiledPattern{pattern.Name, re})
+	}
+}
+
+func handleCommands(buffer []byte) []interface{} {
+	block := Commands_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleCommands - Error decoding Commands block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	blockTime := newBlockTime(block.TOW, block.WNc)
+
+	// A block can hold several commands, one per line. The Commands block does
+	// not tell which port a command came in on, so the connection is left out.
+	payloads := []interface{}{}
+	text := cString(block.CmdData[:])
+	for _, command := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		entry := AuditEntry{BlockTime: blockTime, Kind: "command", Text: strings.TrimSpace(command)}
+		if entry.Text == "" {
+			continue
+		}
+		log.Printf("[INFO] handleCommands - Command applied: %s\n", entry.Text)
+		payloads = append(payloads, Payload{Topic: auditTopic, Data: entry})
+	}
+	return payloads
+}
+
+func handleComment(buffer []byte) []interface{} {
+	block := Comment_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleComment - Error decoding Comment block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	length := int(block.CommentLn)
+	if length > len(block.Comment) {
+		length = len(block.Comment)
+	}
+	return []interface{}{Payload{Topic: auditTopic, Data: AuditEntry{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Kind:      "comment",
+		Text:      cString(block.Comment[:length]),
+	}}}
+}
+
+func handleASCIIIn(buffer []byte) []interface{} {
+	block := ASCIIIn_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleASCIIIn - Error decoding ASCIIIn block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	length := int(block.StringLn)
+	if length > len(block.ASCIIString) {
+		length = len(block.ASCIIString)
+	}
+	in := ASCIIIn{
+		BlockTime:   newBlockTime(block.TOW, block.WNc),
+		Connection:  connectionDescriptorString(block.CD),
+		SensorModel: cString(block.SensorModel[:]),
+		SensorType:  cString(block.SensorType[:]),
+		Text:        strings.TrimRight(cString(block.ASCIIString[:length]), "\r\n"),
+	}
+	for _, pattern := range asciiInPatterns {
+		match := pattern.regexp.FindStringSubmatch(in.Text)
+		if match == nil {
+			continue
+		}
+		in.Pattern = pattern.name
+		in.Fields = map[string]interface{}{}
+		for i, name := range pattern.regexp.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			if value, err := strconv.ParseFloat(match[i], 64); err == nil {
+				in.Fields[name] = value
+			} else {
+				in.Fields[name] = match[i]
+			}
+		}
+		break
+	}
+
+	return []interface{}{
+		Payload{Topic: asciiInTopic, Data: in},
+		Payload{Topic: auditTopic, Data: AuditEntry{BlockTime: in.BlockTime, Kind: "asciiIn", Connection: in.Connection, Text: in.Text}},
+	}
+}
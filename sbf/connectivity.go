@@ -0,0 +1,492 @@
+package sbf
+
+import (
+	"log"
+	"net"
+	"strconv"
+	"time"
+)
+
+/**
+ * Decoding of the connectivity status blocks: data flowing through every
+ * receiver connection descriptor, the IP interface, cellular, Wi-Fi and
+ * Bluetooth links and the NTRIP and DynDNS clients. The receiver reports
+ * ever increasing byte and message counters, they are published as the
+ * increase since the previous block of the same connection.
+ */
+
+const connectivityInputTopic = "connectivity/input"
+const connectivityOutputTopic = "connectivity/output"
+const connectivityIPTopic = "connectivity/ip"
+const connectivityDynDNSTopic = "connectivity/dyndns"
+const connectivityNTRIPTopic = "connectivity/ntrip"
+const connectivityWiFiTopic = "connectivity/wifi"
+const connectivityCellularTopic = "connectivity/cellular"
+const connectivityBluetoothTopic = "connectivity/bluetooth"
+
+/* Offsets of the first sub-block */
+const inputLinkSubBlockOffset = 16
+const outputLinkSubBlockOffset = 20
+const ntripStatusSubBlockOffset = 16
+const wifiAPSubBlockOffset = 36
+const bluetoothSubBlockOffset = 20
+
+// InputLinks lists the data received on every connection
+type InputLinks struct {
+	BlockTime
+	Interval *float64    `json:"interval,omitempty"` // s since the previous InputLink block
+	Links    []InputLink `json:"links"`
+}
+
+// InputLink is the data received on one connection since the previous block.
+// The counters are omitted for the first block of a connection.
+type InputLink struct {
+	Connection       string   `json:"connection"`
+	Type             string   `json:"type"`
+	AgeOfLastMessage *float64 `json:"ageOfLastMessage,omitempty"` // s
+	BytesReceived    *uint32  `json:"bytesReceived,omitempty"`
+	BytesAccepted    *uint32  `json:"bytesAccepted,omitempty"`
+	MsgReceived      *uint32  `json:"msgReceived,omitempty"`
+	MsgAccepted      *uint32  `json:"msgAccepted,omitempty"`
+}
+
+// OutputLinks lists the data sent on every connection
+type OutputLinks struct {
+	BlockTime
+	Interval *float64     `json:"interval,omitempty"` // s since the previous OutputLink block
+	Links    []OutputLink `json:"links"`
+}
+
+// OutputLink is the data sent on one connection since the previous block.
+// The counters are omitted for the first block of a connection.
+type OutputLink struct {
+	Connection    string       `json:"connection"`
+	AllowedRate   uint16       `json:"allowedRate"` // kbyte/s
+	BytesProduced *uint32      `json:"bytesProduced,omitempty"`
+	BytesSent     *uint32      `json:"bytesSent,omitempty"`
+	Clients       *uint8       `json:"clients,omitempty"`
+	Types         []OutputType `json:"types"`
+}
+
+// OutputType is the share of one data type in the output of a connection
+type OutputType struct {
+	Type       uint8 `json:"type"`       // Output type as numbered by the SBF reference guide
+	Percentage uint8 `json:"percentage"` // %
+}
+
+// IPStatus is the state of the receiver's IP interface
+type IPStatus struct {
+	BlockTime
+	MACAddress string `json:"macAddress"`
+	IPAddress  string `json:"ipAddress"`
+	Gateway    string `json:"gateway"`
+	Netmask    uint8  `json:"netmask"` // Prefix length
+	HostName   string `json:"hostName,omitempty"`
+}
+
+// DynDNSStatus is the state of the dynamic DNS client
+type DynDNSStatus struct {
+	BlockTime
+	Status    string `json:"status"`
+	ErrorCode uint8  `json:"errorCode"`
+	IPAddress string `json:"ipAddress,omitempty"`
+}
+
+// NTRIPStatus lists the NTRIP client or server connections
+type NTRIPStatus struct {
+	BlockTime
+	Role        string            `json:"role"` // client or server
+	Connections []NTRIPConnection `json:"connections"`
+}
+
+// NTRIPConnection is the state of one NTRIP connection
+type NTRIPConnection struct {
+	Connection string `json:"connection"`
+	Status     string `json:"status"`
+	ErrorCode  uint8  `json:"errorCode"`
+	Info       uint8  `json:"info"`
+}
+
+// WiFiStatus is the state of the Wi-Fi access point or client
+type WiFiStatus struct {
+	BlockTime
+	Role      string       `json:"role"` // accessPoint or client
+	IPAddress string       `json:"ipAddress"`
+	Mode      *uint8       `json:"mode,omitempty"`     // Access point only
+	Hotspot   *uint8       `json:"hotspot,omitempty"`  // Access point only
+	Clients   []WiFiClient `json:"clients,omitempty"`  // Access point only
+	SSID      string       `json:"ssid,omitempty"`     // Client only
+	SigLevel  *int8        `json:"sigLevel,omitempty"` // dBm, client only
+	Status    *uint8       `json:"status,omitempty"`   // Client only
+	ErrorCode *uint8       `json:"errorCode,omitempty"`
+}
+
+// WiFiClient is a device connected to the receiver's access point
+type WiFiClient struct {
+	HostName   string `json:"hostName"`
+	MACAddress string `json:"macAddress"`
+	IPAddress  string `json:"ipAddress"`
+}
+
+// CellularStatus is the state of the cellular modem
+type CellularStatus struct {
+	BlockTime
+	ConnectionType uint8  `json:"connectionType"`
+	RSSI           *int8  `json:"rssi,omitempty"` // dBm
+	Operator       string `json:"operator"`
+	Status         uint8  `json:"status"`
+	ErrorCode      uint8  `json:"errorCode"`
+	DataCall       *uint8 `json:"dataCall,omitempty"`
+	Info           *uint8 `json:"info,omitempty"`
+}
+
+// BluetoothStatus lists the paired Bluetooth devices
+type BluetoothStatus struct {
+	BlockTime
+	Mode    uint8             `json:"mode"`
+	Devices []BluetoothDevice `json:"devices"`
+}
+
+// BluetoothDevice is one paired Bluetooth device
+type BluetoothDevice struct {
+	Name  string `json:"name"`
+	Flags uint8  `json:"flags"`
+}
+
+var inputTypes = map[uint8]string{
+	0: "CMD",
+	1: "RTCMv2",
+	2: "RTCMv3",
+	3: "CMRv2",
+	4: "CMR+",
+}
+
+var ntripStatuses = map[uint8]string{
+	0: "idle",
+	1: "connecting",
+	2: "connected",
+	3: "error",
+}
+
+var dynDNSStatuses = map[uint8]string{
+	0: "disabled",
+	1: "updated",
+	2: "updating",
+	3: "error",
+}
+
+/* Counters of the previous InputLink and OutputLink blocks, keyed by connection */
+type inputCounters struct {
+	bytesReceived, bytesAccepted, msgReceived, msgAccepted uint32
+}
+type outputCounters struct {
+	bytesProduced, bytesSent uint32
+}
+
+// counterDelta is the increase of a receiver counter. The uint32 difference
+// also holds when the counter wrapped around, while a counter reset by a
+// reboot counts from zero again.
+func counterDelta(current uint32, previous uint32, reset bool) *uint32 {
+	delta := current - previous
+	if reset {
+		delta = current
+	}
+	return &delta
+}
+
+// countersReset tells whether the receiver rebooted since the previous block,
+// when its time went back or the latest ReceiverStatus started after it
+func countersReset(previous *BlockTime, current BlockTime) bool {
+	if previous == nil || previous.Time == nil || current.Time == nil {
+		return false
+	}
+	if current.Time.Before(*previous.Time) {
+		return true
+	}
+	// The uptime is in whole seconds
+	return state.receiverBootTime != nil && state.receiverBootTime.After(previous.Time.Add(time.Second))
+}
+
+// blockInterval returns the seconds between two block times, nil when there
+// is no previous block
+func blockInterval(previous *BlockTime, current BlockTime) *float64 {
+	if previous == nil || previous.Time == nil || current.Time == nil {
+		return nil
+	}
+	interval := current.Time.Sub(*previous.Time).Seconds()
+	return &interval
+}
+
+// ipString formats an SBF IP address field. IPv4 addresses are stored in the
+// first 4 bytes, the other bytes are 0. An unset address gives "".
+func ipString(address []uint8) string {
+	ip := net.IP(address)
+	if ip.IsUnspecified() {
+		return ""
+	}
+	if len(address) == 16 && net.IP(address[4:]).Equal(net.IPv6zero[4:]) {
+		return net.IP(address[:4]).String()
+	}
+	return ip.String()
+}
+
+func handleInputLink(buffer []byte) []interface{} {
+	block := InputLink_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleInputLink - Error decoding InputLink block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	links := InputLinks{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Links:     []InputLink{},
+	}
+	links.Interval = blockInterval(state.previousInputTime, links.BlockTime)
+	reset := countersReset(state.previousInputTime, links.BlockTime)
+	state.previousInputTime = &links.BlockTime
+
+	for i := 0; i < int(block.N); i++ {
+		sb := InputStatsSub_1_0_t{}
+		if !decodeSizedSubBlock(buffer, inputLinkSubBlockOffset+i*int(block.SBLength), int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleInputLink - InputLink block too short for %d connections\n", block.N)
+			break
+		}
+		link := InputLink{
+			Connection:       connectionDescriptorString(sb.CD),
+			Type:             mapString(inputTypes, sb.Type),
+			AgeOfLastMessage: optScaled(int64(sb.AgeOfLastMessage), 65535, 1),
+		}
+		counters := inputCounters{sb.NrBytesReceived, sb.NrBytesAccepted, sb.NrMsgReceived, sb.NrMsgAccepted}
+		if previous, ok := state.previousInputCounters[link.Connection]; ok {
+			link.BytesReceived = counterDelta(counters.bytesReceived, previous.bytesReceived, reset)
+			link.BytesAccepted = counterDelta(counters.bytesAccepted, previous.bytesAccepted, reset)
+			link.MsgReceived = counterDelta(counters.msgReceived, previous.msgReceived, reset)
+			link.MsgAccepted = counterDelta(counters.msgAccepted, previous.msgAccepted, reset)
+		}
+		state.previousInputCounters[link.Connection] = counters
+		links.Links = append(links.Links, link)
+	}
+
+	return []interface{}{Payload{Topic: connectivityInputTopic, Data: links}}
+}
+
+func handleOutputLink(buffer []byte) []interface{} {
+	block := OutputLink_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleOutputLink - Error decoding OutputLink block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	revision := block.Header.ID >> 13
+
+	links := OutputLinks{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Links:     []OutputLink{},
+	}
+	links.Interval = blockInterval(state.previousOutputTime, links.BlockTime)
+	reset := countersReset(state.previousOutputTime, links.BlockTime)
+	state.previousOutputTime = &links.BlockTime
+
+	offset := outputLinkSubBlockOffset
+	for i := 0; i < int(block.N1); i++ {
+		sb := OutputStatsSub_1_1_t{}
+		if !decodeSizedSubBlock(buffer, offset, int(block.SB1Length), &sb) {
+			log.Printf("[ERROR] handleOutputLink - OutputLink block too short for %d connections\n", block.N1)
+			break
+		}
+		offset += int(block.SB1Length)
+
+		link := OutputLink{
+			Connection:  connectionDescriptorString(sb.CD),
+			AllowedRate: sb.AllowedRate,
+			Types:       []OutputType{},
+		}
+		if revision >= 1 {
+			clients := sb.NrClients
+			link.Clients = &clients
+		}
+		counters := outputCounters{sb.NrBytesProduced, sb.NrBytesSent}
+		if previous, ok := state.previousOutputCounters[link.Connection]; ok {
+			link.BytesProduced = counterDelta(counters.bytesProduced, previous.bytesProduced, reset)
+			link.BytesSent = counterDelta(counters.bytesSent, previous.bytesSent, reset)
+		}
+		state.previousOutputCounters[link.Connection] = counters
+
+		for j := 0; j < int(sb.N2); j++ {
+			outputType := OutputTypeSub_1_0_t{}
+			if !decodeSizedSubBlock(buffer, offset, int(block.SB2Length), &outputType) {
+				log.Printf("[ERROR] handleOutputLink - OutputLink block too short for %d output types of %s\n", sb.N2, link.Connection)
+				break
+			}
+			offset += int(block.SB2Length)
+			link.Types = append(link.Types, OutputType{Type: outputType.Type, Percentage: outputType.Percentage})
+		}
+		links.Links = append(links.Links, link)
+	}
+
+	return []interface{}{Payload{Topic: connectivityOutputTopic, Data: links}}
+}
+
+func handleIPStatus(buffer []byte) []interface{} {
+	block := IPStatus_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleIPStatus - Error decoding IPStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	return []interface{}{Payload{Topic: connectivityIPTopic, Data: IPStatus{
+		BlockTime:  newBlockTime(block.TOW, block.WNc),
+		MACAddress: net.HardwareAddr(block.MACAddress[:]).String(),
+		IPAddress:  ipString(block.IPAddress[:]),
+		Gateway:    ipString(block.Gateway[:]),
+		Netmask:    block.Netmask,
+		HostName:   cString(block.HostName[:]),
+	}}}
+}
+
+func handleDynDNSStatus(buffer []byte) []interface{} {
+	block := DynDNSStatus_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleDynDNSStatus - Error decoding DynDNSStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	return []interface{}{Payload{Topic: connectivityDynDNSTopic, Data: DynDNSStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Status:    mapString(dynDNSStatuses, block.Status),
+		ErrorCode: block.ErrorCode,
+		IPAddress: ipString(block.IPAddress[:]),
+	}}}
+}
+
+// handleNTRIPStatus decodes NTRIPClientStatus and NTRIPServerStatus, which
+// share their layout
+func handleNTRIPStatus(buffer []byte, role string) []interface{} {
+	block := NTRIPClientStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleNTRIPStatus - Error decoding NTRIP %s status block: %s\n", role, err.Error())
+		return []interface{}{}
+	}
+
+	status := NTRIPStatus{
+		BlockTime:   newBlockTime(block.TOW, block.WNc),
+		Role:        role,
+		Connections: []NTRIPConnection{},
+	}
+	for i := 0; i < int(block.N); i++ {
+		sb := NTRIPClientConnection_1_0_t{}
+		if !decodeSizedSubBlock(buffer, ntripStatusSubBlockOffset+i*int(block.SBLength), int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleNTRIPStatus - NTRIP %s status block too short for %d connections\n", role, block.N)
+			break
+		}
+		status.Connections = append(status.Connections, NTRIPConnection{
+			Connection: "NTR" + strconv.Itoa(int(sb.CDIndex)+1),
+			Status:     mapString(ntripStatuses, sb.Status),
+			ErrorCode:  sb.ErrorCode,
+			Info:       sb.Info,
+		})
+	}
+
+	return []interface{}{Payload{Topic: connectivityNTRIPTopic + "/" + role, Data: status}}
+}
+
+func handleWiFiAPStatus(buffer []byte) []interface{} {
+	block := WiFiAPStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleWiFiAPStatus - Error decoding WiFiAPStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := WiFiStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Role:      "accessPoint",
+		IPAddress: ipString(block.APIPAddress[:]),
+		Mode:      &block.Mode,
+		Hotspot:   &block.Hotspot,
+		Clients:   []WiFiClient{},
+	}
+	for i := 0; i < int(block.N); i++ {
+		sb := WiFiClient_1_0_t{}
+		if !decodeSizedSubBlock(buffer, wifiAPSubBlockOffset+i*int(block.SBLength), int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleWiFiAPStatus - WiFiAPStatus block too short for %d clients\n", block.N)
+			break
+		}
+		status.Clients = append(status.Clients, WiFiClient{
+			HostName:   cString(sb.ClientHostName[:]),
+			MACAddress: net.HardwareAddr(sb.ClientMACAddress[:]).String(),
+			IPAddress:  ipString(sb.ClientIPAddress[:]),
+		})
+	}
+
+	return []interface{}{Payload{Topic: connectivityWiFiTopic, Data: status}}
+}
+
+func handleWiFiClientStatus(buffer []byte) []interface{} {
+	block := WiFiClientStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleWiFiClientStatus - Error decoding WiFiClientStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := WiFiStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Role:      "client",
+		IPAddress: ipString(block.IPAddress[:]),
+		SSID:      cString(block.SSID_AP[:]),
+		Status:    &block.Status,
+		ErrorCode: &block.ErrorCode,
+	}
+	if block.SigLevel != -128 {
+		status.SigLevel = &block.SigLevel
+	}
+
+	return []interface{}{Payload{Topic: connectivityWiFiTopic, Data: status}}
+}
+
+func handleCellularStatus(buffer []byte) []interface{} {
+	block := CellularStatus_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleCellularStatus - Error decoding CellularStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := CellularStatus{
+		BlockTime:      newBlockTime(block.TOW, block.WNc),
+		ConnectionType: block.ConnectionType,
+		Operator:       cString(block.OperatorName[:]),
+		Status:         block.Status,
+		ErrorCode:      block.ErrorCode,
+	}
+	if block.RSSI != -128 {
+		status.RSSI = &block.RSSI
+	}
+	if block.Header.ID>>13 >= 1 {
+		status.DataCall = &block.DataCall
+		status.Info = &block.Info
+	}
+
+	return []interface{}{Payload{Topic: connectivityCellularTopic, Data: status}}
+}
+
+func handleBluetoothStatus(buffer []byte) []interface{} {
+	block := BluetoothStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleBluetoothStatus - Error decoding BluetoothStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := BluetoothStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Mode:      block.Mode,
+		Devices:   []BluetoothDevice{},
+	}
+	for i := 0; i < int(block.N); i++ {
+		sb := BTDevice_1_0_t{}
+		if !decodeSizedSubBlock(buffer, bluetoothSubBlockOffset+i*int(block.SBLength), int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleBluetoothStatus - BluetoothStatus block too short for %d devices\n", block.N)
+			break
+		}
+		status.Devices = append(status.Devices, BluetoothDevice{Name: cString(sb.DeviceName[:]), Flags: sb.Flags})
+	}
+
+	return []interface{}{Payload{Topic: connectivityBluetoothTopic, Data: status}}
+}
@@ -0,0 +1,480 @@
+package sbf
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Software base station: RTCM3 corrections generated from the MeasEpoch
+ * observations. Every settings.CorrectionsInterval seconds an MSM4 or MSM7
+ * message is generated per constellation, and every
+ * settings.CorrectionsStationInterval seconds the 1005 or 1006 position of
+ * settings.ReferencePosition and the 1033 descriptor of the receiver and
+ * antenna of ReceiverSetup. The frames of an epoch are published together as
+ * raw binary on the corrections topic, ready to be forwarded to rovers.
+ */
+
+const correctionsTopic = "corrections"
+
+// ReferencePosition is the surveyed position of the marker of a base station
+type ReferencePosition struct {
+	Latitude  float64 `json:"latitude"`  // °, WGS84
+	Longitude float64 `json:"longitude"` // °
+	Height    float64 `json:"height"`    // m, ellipsoidal
+}
+
+/* First MSM message number per constellation, MSMn is this number + n */
+var msmMessageBases = map[string]uint16{
+	"GPS":     1070,
+	"GLONASS": 1080,
+	"Galileo": 1090,
+	"QZSS":    1110,
+	"BeiDou":  1120,
+}
+
+/* Order in which the MSM messages of an epoch are sent */
+var msmSystemOrder = []string{"GPS", "GLONASS", "Galileo", "QZSS", "BeiDou"}
+
+// rtcmWriter writes the big endian bit fields of an RTCM3 message
+type rtcmWriter struct {
+	data []byte
+	pos  int
+}
+
+// u writes the n lowest bits of value
+func (w *rtcmWriter) u(n int, value uint64) {
+	for i := n - 1; i >= 0; i-- {
+		if w.pos/8 >= len(w.data) {
+			w.data = append(w.data, 0)
+		}
+		if value>>uint(i)&1 == 1 {
+			w.data[w.pos/8] |= 1 << (7 - uint(w.pos%8))
+		}
+		w.pos++
+	}
+}
+
+// s writes a two's complement field of n bits
+func (w *rtcmWriter) s(n int, value int64) {
+	w.u(n, uint64(value)&(1<<uint(n)-1))
+}
+
+// text writes a string preceded by its 8 bit length
+func (w *rtcmWriter) text(value string) {
+	if len(value) > 255 {
+		value = value[:255]
+	}
+	w.u(8, uint64(len(value)))
+	for i := 0; i < len(value); i++ {
+		w.u(8, uint64(value[i]))
+	}
+}
+
+// frame wraps the message in an RTCM3 frame with its CRC-24Q
+func (w *rtcmWriter) frame() []byte {
+	frame := []byte{rtcm3Preamble, byte(len(w.data) >> 8 & 0x03), byte(len(w.data))}
+	frame = append(frame, w.data...)
+	crc := crc24q(frame)
+	return append(frame, byte(crc>>16), byte(crc>>8), byte(crc))
+}
+
+// generateCorrections returns the corrections of an epoch when they are due
+func generateCorrections(observations Observations) []interface{} {
+	if settings.CorrectionsInterval <= 0 || settings.ReferencePosition == nil || observations.Time == nil {
+		return []interface{}{}
+	}
+
+	// Allow for the rounding of the time of week when comparing intervals
+	t := float64(observations.WNc)*604800 + observations.TOW
+	if t >= state.lastCorrectionsTime && t-state.lastCorrectionsTime < settings.CorrectionsInterval-0.001 {
+		return []interface{}{}
+	}
+	state.lastCorrectionsTime = t
+
+	data := []byte{}
+	if t < state.lastStationTime || t-state.lastStationTime >= settings.CorrectionsStationInterval-0.001 {
+		state.lastStationTime = t
+		data = append(data, encodeRTCMStation()...)
+		if state.receiverSetupSeen {
+			data = append(data, encodeRTCMDescriptor()...)
+		}
+	}
+
+	towMs := uint32(math.Round(observations.TOW * 1000))
+	messages := [][]byte{}
+	for _, system := range msmSystemOrder {
+		messages = append(messages, encodeRTCMMSM(observations, system, towMs)...)
+	}
+	for i, message := range messages {
+		// The multiple message bit tells the rover more MSM messages of this epoch follow
+		if i < len(messages)-1 {
+			message[rtcm3HeaderLength+6] |= 0x02
+			crc := crc24q(message[:len(message)-rtcm3CRCLength])
+			message[len(message)-3], message[len(message)-2], message[len(message)-1] = byte(crc>>16), byte(crc>>8), byte(crc)
+		}
+		data = append(data, message...)
+	}
+
+	if len(data) == 0 {
+		return []interface{}{}
+	}
+	return []interface{}{Payload{Topic: correctionsTopic, Data: data}}
+}
+
+// encodeRTCMStation encodes the antenna reference point as a 1006 message
+// when ReceiverSetup gave the antenna offset, as a 1005 message otherwise
+func encodeRTCMStation() []byte {
+	position := settings.ReferencePosition
+	x, y, z := geodeticToECEF(position.Latitude, position.Longitude, position.Height)
+	var antennaHeight *float64
+	if offset := state.inventory.AntennaOffset; state.receiverSetupSeen && offset != nil && offset.DeltaH != nil {
+		east, north := 0.0, 0.0
+		if offset.DeltaE != nil {
+			east = *offset.DeltaE
+		}
+		if offset.DeltaN != nil {
+			north = *offset.DeltaN
+		}
+		dx, dy, dz := enuToECEF(position.Latitude, position.Longitude, east, north, *offset.DeltaH)
+		x, y, z = x+dx, y+dy, z+dz
+		antennaHeight = offset.DeltaH
+	}
+
+	w := &rtcmWriter{}
+	if antennaHeight != nil {
+		w.u(12, 1006)
+	} else {
+		w.u(12, 1005)
+	}
+	w.u(12, uint64(settings.CorrectionsStationID))
+	w.u(6, 0) // ITRF realization year, not specified
+	w.u(1, 1) // GPS
+	w.u(1, 1) // GLONASS
+	w.u(1, 1) // Galileo
+	w.u(1, 0) // Physical reference station
+	w.s(38, int64(math.Round(x/0.0001)))
+	w.u(1, 1) // Single receiver oscillator
+	w.u(1, 0) // Reserved
+	w.s(38, int64(math.Round(y/0.0001)))
+	w.u(2, 0) // Quarter cycle indicator, no correction
+	w.s(38, int64(math.Round(z/0.0001)))
+	if antennaHeight != nil {
+		w.u(16, uint64(math.Round(math.Max(*antennaHeight, 0)/0.0001)))
+	}
+	return w.frame()
+}
+
+// encodeRTCMDescriptor encodes the antenna and receiver of ReceiverSetup as
+// a 1033 message
+func encodeRTCMDescriptor() []byte {
+	w := &rtcmWriter{}
+	w.u(12, 1033)
+	w.u(12, uint64(settings.CorrectionsStationID))
+	w.text(state.inventory.AntennaType)
+	w.u(8, 0) // Antenna setup ID
+	w.text(state.inventory.AntennaSerial)
+	w.text(state.inventory.ReceiverName)
+	w.text(state.inventory.ReceiverVersion)
+	w.text(state.inventory.ReceiverSerial)
+	return w.frame()
+}
+
+// msmSatellite is a satellite of an MSM message with its signals by mask bit
+type msmSatellite struct {
+	number          int
+	frequencyNumber *int
+	signals         map[int]SignalObservation
+}
+
+// encodeRTCMMSM encodes the observations of a constellation as MSM4 or MSM7
+// messages. A message holds at most 64 cells, the satellites are spread over
+// several messages when there are more.
+func encodeRTCMMSM(observations Observations, system string, towMs uint32) [][]byte {
+	signalBits := map[string]int{}
+	for bit, code := range msmSignals[system] {
+		signalBits[code] = bit
+	}
+
+	satellites := []msmSatellite{}
+	signalSet := map[int]bool{}
+	for _, observed := range observations.Satellites {
+		if observed.System != system {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimLeft(observed.Satellite, "GRECJ"))
+		if err != nil || number < 1 || number > 64 {
+			continue
+		}
+		satellite := msmSatellite{number: number, frequencyNumber: observed.FrequencyNumber, signals: map[int]SignalObservation{}}
+		for _, signal := range observed.Signals {
+			bit, ok := signalBits[signal.Signal]
+			if !ok || signal.Pseudorange == nil {
+				continue
+			}
+			if _, duplicate := satellite.signals[bit]; !duplicate {
+				satellite.signals[bit] = signal
+				signalSet[bit] = true
+			}
+		}
+		if len(satellite.signals) > 0 {
+			satellites = append(satellites, satellite)
+		}
+	}
+	if len(satellites) == 0 {
+		return [][]byte{}
+	}
+	sort.Slice(satellites, func(i, j int) bool { return satellites[i].number < satellites[j].number })
+	signals := []int{}
+	for bit := range signalSet {
+		signals = append(signals, bit)
+	}
+	sort.Ints(signals)
+
+	messages := [][]byte{}
+	perMessage := 64 / len(signals)
+	for start := 0; start < len(satellites); start += perMessage {
+		end := start + perMessage
+		if end > len(satellites) {
+			end = len(satellites)
+		}
+		messages = append(messages, encodeMSMMessage(system, satellites[start:end], signals, towMs))
+	}
+	return messages
+}
+
+func encodeMSMMessage(system string, satellites []msmSatellite, signals []int, towMs uint32) []byte {
+	msm7 := settings.CorrectionsMSM == 7
+	msToMeters := speedOfLight / 1000
+
+	w := &rtcmWriter{}
+	if msm7 {
+		w.u(12, uint64(msmMessageBases[system]+7))
+	} else {
+		w.u(12, uint64(msmMessageBases[system]+4))
+	}
+	w.u(12, uint64(settings.CorrectionsStationID))
+	w.u(30, uint64(msmEpochFromGPS(system, towMs)))
+	w.u(1, 0) // Multiple message bit, set afterwards
+	w.u(3, 0) // IODS
+	w.u(7, 0) // Reserved
+	w.u(2, 0) // Clock steering unknown
+	w.u(2, 0) // External clock unknown
+	w.u(1, 0) // No divergence free smoothing
+	w.u(3, 0) // Smoothing interval
+
+	var satelliteMask uint64
+	for _, satellite := range satellites {
+		satelliteMask |= 1 << uint(64-satellite.number)
+	}
+	w.u(64, satelliteMask)
+	var signalMask uint64
+	for _, bit := range signals {
+		signalMask |= 1 << uint(32-bit)
+	}
+	w.u(32, signalMask)
+
+	type cell struct {
+		signal    SignalObservation
+		frequency float64
+		satellite int
+	}
+	cells := []cell{}
+	for i, satellite := range satellites {
+		for _, bit := range signals {
+			signal, ok := satellite.signals[bit]
+			if ok {
+				w.u(1, 1)
+				cells = append(cells, cell{signal, carrierFrequency(system, signal.Signal, satellite.frequencyNumber), i})
+			} else {
+				w.u(1, 0)
+			}
+		}
+	}
+
+	// Rough range (ms, in 1/1024 ms units) and range rate (m/s) per satellite,
+	// from the first signal with a measurement
+	rough := make([]int64, len(satellites))
+	roughRate := make([]int64, len(satellites))
+	for i := range satellites {
+		rough[i] = -1
+		roughRate[i] = -8192
+	}
+	for _, c := range cells {
+		if rough[c.satellite] < 0 {
+			units := int64(math.Round(*c.signal.Pseudorange / msToMeters * 1024))
+			if units>>10 < 255 {
+				rough[c.satellite] = units
+			}
+		}
+		if roughRate[c.satellite] == -8192 && c.signal.Doppler != nil && c.frequency != 0 {
+			rate := int64(math.Round(-*c.signal.Doppler * speedOfLight / c.frequency))
+			if rate > -8192 && rate < 8192 {
+				roughRate[c.satellite] = rate
+			}
+		}
+	}
+
+	for i := range satellites {
+		if rough[i] < 0 {
+			w.u(8, 255)
+		} else {
+			w.u(8, uint64(rough[i]>>10))
+		}
+	}
+	if msm7 {
+		for _, satellite := range satellites {
+			if system == "GLONASS" && satellite.frequencyNumber != nil {
+				w.u(4, uint64(*satellite.frequencyNumber+7))
+			} else if system == "GLONASS" {
+				w.u(4, 15)
+			} else {
+				w.u(4, 0)
+			}
+		}
+	}
+	for i := range satellites {
+		if rough[i] < 0 {
+			w.u(10, 0)
+		} else {
+			w.u(10, uint64(rough[i]&0x3FF))
+		}
+	}
+	if msm7 {
+		for i := range satellites {
+			w.s(14, roughRate[i])
+		}
+	}
+
+	pseudorangeBits, phaseBits, pseudorangeScale, phaseScale := 15, 22, math.Pow(2, -24), math.Pow(2, -29)
+	if msm7 {
+		pseudorangeBits, phaseBits, pseudorangeScale, phaseScale = 20, 24, math.Pow(2, -29), math.Pow(2, -31)
+	}
+	for _, c := range cells {
+		w.s(pseudorangeBits, msmFineValue(c.signal.Pseudorange, rough[c.satellite], 1/msToMeters, pseudorangeScale, pseudorangeBits))
+	}
+	for _, c := range cells {
+		var phase *float64
+		if c.signal.CarrierPhase != nil && c.frequency != 0 {
+			phase = floatPtr(*c.signal.CarrierPhase * speedOfLight / c.frequency)
+		}
+		w.s(phaseBits, msmFineValue(phase, rough[c.satellite], 1/msToMeters, phaseScale, phaseBits))
+	}
+	for _, c := range cells {
+		lockTime := 0.0
+		if c.signal.LockTime != nil {
+			lockTime = *c.signal.LockTime * 1000
+		}
+		if msm7 {
+			w.u(10, msmExtendedLockTimeIndicator(lockTime))
+		} else {
+			w.u(4, msmLockTimeIndicator(lockTime))
+		}
+	}
+	for _, c := range cells {
+		if c.signal.HalfCycleAmbiguity {
+			w.u(1, 1)
+		} else {
+			w.u(1, 0)
+		}
+	}
+	for _, c := range cells {
+		cn0 := 0.0
+		if c.signal.CN0 != nil {
+			cn0 = *c.signal.CN0
+		}
+		if msm7 {
+			w.u(10, uint64(math.Max(0, math.Min(1023, math.Round(cn0*16)))))
+		} else {
+			w.u(6, uint64(math.Max(0, math.Min(63, math.Round(cn0)))))
+		}
+	}
+	if msm7 {
+		for _, c := range cells {
+			fine := int64(-16384)
+			if c.signal.Doppler != nil && c.frequency != 0 && roughRate[c.satellite] != -8192 {
+				rate := -*c.signal.Doppler * speedOfLight / c.frequency
+				value := int64(math.Round((rate - float64(roughRate[c.satellite])) / 0.0001))
+				if value > -16384 && value < 16384 {
+					fine = value
+				}
+			}
+			w.s(15, fine)
+		}
+	}
+	return w.frame()
+}
+
+// msmFineValue returns the difference between a range (m) and the rough range
+// of its satellite in units of scale ms, or the invalid value of the field when
+// either is unknown or the difference does not fit
+func msmFineValue(value *float64, rough int64, metersToMs float64, scale float64, bits int) int64 {
+	invalid := -int64(1) << uint(bits-1)
+	if value == nil || rough < 0 {
+		return invalid
+	}
+	fine := int64(math.Round((*value*metersToMs - float64(rough)/1024) / scale))
+	if fine <= invalid || fine >= -invalid {
+		return invalid
+	}
+	return fine
+}
+
+// msmLockTimeIndicator is the MSM4 indicator of the minimum lock time (ms)
+func msmLockTimeIndicator(lockTime float64) uint64 {
+	indicator := uint64(0)
+	for i := uint64(1); i <= 15 && msmLockTime(i) <= lockTime; i++ {
+		indicator = i
+	}
+	return indicator
+}
+
+// msmExtendedLockTimeIndicator is the MSM7 indicator of the lock time (ms)
+func msmExtendedLockTimeIndicator(lockTime float64) uint64 {
+	indicator := uint64(0)
+	for i := uint64(1); i <= 704 && msmExtendedLockTime(i) <= lockTime; i++ {
+		indicator = i
+	}
+	return indicator
+}
+
+// msmEpochFromGPS converts a GPS time of week (ms) to the epoch time of an
+// MSM message of a constellation, the reverse of msmEpochToGPS
+func msmEpochFromGPS(system string, towMs uint32) uint32 {
+	const weekMs = 7 * 86400000
+	switch system {
+	case "GLONASS":
+		moscow := (int64(towMs) - int64(gpsUtcLeapSeconds)*1000 + 3*3600000 + weekMs) % weekMs
+		return uint32(moscow/86400000)<<27 | uint32(moscow%86400000)
+	case "BeiDou":
+		return uint32((int64(towMs) - 14000 + weekMs) % weekMs)
+	}
+	return towMs
+}
+
+// geodeticToECEF converts a WGS84 latitude and longitude (°) and ellipsoidal
+// height (m) to ECEF coordinates (m)
+func geodeticToECEF(latitude, longitude, height float64) (float64, float64, float64) {
+	const a = 6378137.0
+	const f = 1 / 298.257223563
+	e2 := f * (2 - f)
+
+	lat := latitude * math.Pi / 180
+	lon := longitude * math.Pi / 180
+	n := a / math.Sqrt(1-e2*math.Sin(lat)*math.Sin(lat))
+	return (n + height) * math.Cos(lat) * math.Cos(lon),
+		(n + height) * math.Cos(lat) * math.Sin(lon),
+		(n*(1-e2) + height) * math.Sin(lat)
+}
+
+// enuToECEF rotates a local east, north, up vector (m) at a latitude and
+// longitude (°) to ECEF
+func enuToECEF(latitude, longitude, east, north, up float64) (float64, float64, float64) {
+	lat := latitude * math.Pi / 180
+	lon := longitude * math.Pi / 180
+	return -math.Sin(lon)*east - math.Sin(lat)*math.Cos(lon)*north + math.Cos(lat)*math.Cos(lon)*up,
+		math.Cos(lon)*east - math.Sin(lat)*math.Sin(lon)*north + math.Cos(lat)*math.Sin(lon)*up,
+		math.Cos(lat)*north + math.Sin(lat)*up
+}
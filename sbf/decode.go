@@ -0,0 +1,277 @@
+package sbf
+
+/**
+ * Helpers shared by the SBF block decoders
+ */
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Payload is a decoded piece of receiver data the adapter should publish.
+// Topic is relative to the adapter's receive topic.
+type Payload struct {
+	Topic string
+	Data  interface{}
+}
+
+// BlockTime is the receiver time stamp carried by every SBF block
+type BlockTime struct {
+	TOW  float64    `json:"tow"`            // Time of week in seconds
+	WNc  uint16     `json:"wnc"`            // GPS week number
+	Time *time.Time `json:"time,omitempty"` // UTC time, omitted when the receiver has no time yet
+}
+
+const towDoNotUse = 4294967295
+const wncDoNotUse = 65535
+const floatDoNotUse = -2e10
+
+// Leap seconds between GPS time and UTC, used until the receiver reports otherwise
+var gpsUtcLeapSeconds = 18
+
+var gpsEpoch = time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// decodeBlock reads an SBF block into one of the structs in structs.go. Blocks
+// are usually shorter than their struct (the trailing arrays are sized for the
+// worst case), so the buffer is zero padded to the size of the struct first.
+func decodeBlock(buffer []byte, block interface{}) error {
+	size := binary.Size(block)
+	if len(buffer) < size {
+		padded := make([]byte, size)
+		copy(padded, buffer)
+		buffer = padded
+	}
+	return binary.Read(bytes.NewReader(buffer), binary.LittleEndian, block)
+}
+
+// decodeSubBlock reads a sub-block starting at offset. It returns false when
+// the block is too short to contain it.
+func decodeSubBlock(buffer []byte, offset int, subBlock interface{}) bool {
+	if offset < 0 || offset+binary.Size(subBlock) > len(buffer) {
+		return false
+	}
+	return binary.Read(bytes.NewReader(buffer[offset:]), binary.LittleEndian, subBlock) == nil
+}
+
+// decodeSizedSubBlock reads a sub-block of size bytes starting at offset into
+// the struct of its latest revision. Sub-blocks of older revisions are shorter
+// than the struct, the fields they lack are left zero.
+func decodeSizedSubBlock(buffer []byte, offset int, size int, subBlock interface{}) bool {
+	if offset < 0 || size <= 0 || offset+size > len(buffer) {
+		return false
+	}
+	if size > binary.Size(subBlock) {
+		size = binary.Size(subBlock)
+	}
+	return decodeBlock(buffer[offset:offset+size], subBlock) == nil
+}
+
+// gpsTime converts an SBF TOW (ms) and WNc to GPS time. The second return
+// value is false when the receiver has not set its time yet.
+func gpsTime(tow uint32, wnc uint16) (time.Time, bool) {
+	if tow == towDoNotUse || wnc == wncDoNotUse {
+		return time.Time{}, false
+	}
+	return gpsEpoch.Add(time.Duration(wnc)*7*24*time.Hour + time.Duration(tow)*time.Millisecond), true
+}
+
+func newBlockTime(tow uint32, wnc uint16) BlockTime {
+	blockTime := BlockTime{WNc: wnc}
+	if tow != towDoNotUse {
+		blockTime.TOW = float64(tow) / 1000
+	}
+	if t, ok := gpsTime(tow, wnc); ok {
+		utc := t.Add(-time.Duration(gpsUtcLeapSeconds) * time.Second)
+		blockTime.Time = &utc
+	}
+	return blockTime
+}
+
+// optFloat returns nil for float fields set to the SBF do-not-use value. The
+// value is widened through its shortest decimal representation so 0.1 is not
+// published as 0.10000000149011612.
+func optFloat(value float32) *float64 {
+	if value == floatDoNotUse || math.IsNaN(float64(value)) {
+		return nil
+	}
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(float64(value), 'g', -1, 32), 64)
+	return &v
+}
+
+func optDouble(value SBFDOUBLE) *float64 {
+	if value == floatDoNotUse || math.IsNaN(float64(value)) {
+		return nil
+	}
+	v := float64(value)
+	return &v
+}
+
+// optScaled returns nil for integer fields set to their do-not-use value and
+// scales the others to their physical unit
+func optScaled(value int64, doNotUse int64, scale float64) *float64 {
+	if value == doNotUse {
+		return nil
+	}
+	v := float64(value) * scale
+	return &v
+}
+
+func radToDeg(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	v := *value * 180 / math.Pi
+	return &v
+}
+
+var connectionTypes = map[uint8]string{
+	CD_TYPE_COM: "COM",
+	CD_TYPE_USB: "USB",
+	CD_TYPE_IP:  "IP",
+	CD_TYPE_DSK: "DSK",
+	CD_TYPE_NTR: "NTR",
+	CD_TYPE_IPS: "IPS",
+}
+
+// connectionDescriptorString names a receiver connection descriptor, e.g. COM1
+// or IP10. The type is held in bits 5-7 and the port number in bits 0-4.
+func connectionDescriptorString(cd uint8) string {
+	name, ok := connectionTypes[cd&0xE0]
+	if !ok {
+		return "unknown"
+	}
+	if cd&0xE0 == CD_TYPE_IP || cd&0xE0 == CD_TYPE_IPS {
+		return name + strconv.Itoa(10+int(cd&0x1F))
+	}
+	return name + strconv.Itoa(int(cd&0x1F))
+}
+
+// mapString names an enumerated SBF field, keeping the value of unknown ones
+func mapString(names map[uint8]string, value uint8) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return "unknown(" + strconv.Itoa(int(value)) + ")"
+}
+
+// cString converts a NUL padded SBF character array to a string
+func cString(chars []byte) string {
+	if end := bytes.IndexByte(chars, 0); end >= 0 {
+		chars = chars[:end]
+	}
+	return string(chars)
+}
+
+var pvtModes = map[uint8]string{
+	MODE_NO_PVT_AVAILABLE:               "noPVT",
+	MODE_STAND_ALONE_PVT:                "standAlone",
+	MODE_DIFFERENTIAL_PVT:               "differential",
+	MODE_FIXED_LOCATION:                 "fixedLocation",
+	MODE_RTK_FIXED_AMBIGUITIES:          "rtkFixed",
+	MODE_RTK_FLOAT_AMBIGUITIES:          "rtkFloat",
+	MODE_SBAS_AIDED_PVT:                 "sbasAided",
+	MODE_MOVBASERTK_FIXED_AMBIGUITIES:   "movingBaseRtkFixed",
+	MODE_MOVBASERTK_FLOAT_AMBIGUITIES:   "movingBaseRtkFloat",
+	MODE_PPP_FIXED_AMBIGUITIES:          "pppFixed",
+	MODE_PPP_FLOAT_AMBIGUITIES:          "pppFloat",
+	MODE_RTK_FLOAT_WL_FIXED_AMBIGUITIES: "rtkFloatWideLaneFixed",
+	MODE_LOCATA_PVT:                     "locata",
+}
+
+// pvtModeString names the GNSS PVT mode held in bits 0-3 of an SBF Mode field
+func pvtModeString(mode uint8) string {
+	if name, ok := pvtModes[mode&0x0F]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+var pvtErrors = map[uint8]string{
+	SBF_PVTERR_NONE:                      "",
+	SBF_PVTERR_NOTENOUGHMEAS:             "not enough measurements",
+	SBF_PVTERR_NOTENOUGHEPH:              "not enough ephemerides available",
+	SBF_PVTERR_DOPTOOHIGH:                "DOP too large",
+	SBF_PVTERR_ETETOOLARGE:               "sum of squared residuals too large",
+	SBF_PVTERR_NOCONVERGENCE:             "no convergence",
+	SBF_PVTERR_TOOMANYOUTLIERS:           "not enough measurements after outlier rejection",
+	SBF_PVTERR_EXCEEDNATOLIMITS:          "position output prohibited due to export laws",
+	SBF_PVTERR_NOTENOUGHDIFFCORR:         "not enough differential corrections available",
+	SBF_PVTERR_NOBASEAVL:                 "base station coordinates unavailable",
+	SBF_PVTERR_AMBIGUITIESNOTFIXED:       "ambiguities not fixed",
+	SBF_PVTERR_NOTRANSFOPARAMS:           "datum transformation parameters unknown",
+	SBF_PVTERR_INSNOTREQUESTEDBYUSER:     "integrated PV not requested by user",
+	SBF_PVTERR_NOTENOUGHEXTSENSORMEAS:    "not enough external sensor measurements",
+	SBF_PVTERR_CALIBRATIONNOTREADY:       "calibration not ready",
+	SBF_PVTERR_ALIGNMENTNOTREADY:         "static alignment ongoing",
+	SBF_PVTERR_WAITINGFORGNSSPVT:         "waiting for GNSS PVT",
+	SBF_PVTERR_FINETIMENOTREACHED:        "fine time not reached",
+	SBF_PVTERR_INMOTIONALIGNMENTNOTREADY: "in-motion alignment ongoing",
+	SBF_PVTERR_WAITINGFORGNSSHEAD:        "waiting for GNSS heading",
+	SBF_PVTERR_WAITINGFORPPSSYNC:         "waiting for PPS synchronization",
+	SBF_PVTERR_STDLIMITEXCEEDED:          "standard deviation exceeds user limit",
+	SBF_PVTERR_UNSUPPORTEDSETTINGSINS:    "settings not supported by INS",
+}
+
+// pvtErrorString describes an SBF PVT error code, or returns "" for no error
+func pvtErrorString(err uint8) string {
+	if description, ok := pvtErrors[err]; ok {
+		return description
+	}
+	return "unknown error"
+}
+
+type svidRange struct {
+	first  uint8
+	last   uint8
+	system string
+	prefix string
+	offset int // Subtracted from the SVID to get the RINEX satellite number
+}
+
+/* SVID numbering of the SBF reference guide */
+var svidRanges = []svidRange{
+	{1, 37, "GPS", "G", 0},
+	{38, 61, "GLONASS", "R", 37},
+	{62, 62, "GLONASS", "R", 62},
+	{63, 68, "GLONASS", "R", 38},
+	{71, 106, "Galileo", "E", 70},
+	{107, 119, "L-band", "L", 106},
+	{120, 140, "SBAS", "S", 100},
+	{141, 180, "BeiDou", "C", 140},
+	{181, 190, "QZSS", "J", 180},
+	{191, 197, "NavIC", "I", 190},
+	{198, 215, "SBAS", "S", 157},
+	{216, 222, "NavIC", "I", 208},
+	{223, 245, "BeiDou", "C", 182},
+}
+
+// satelliteSystem returns the constellation of an SBF SVID and its satellite
+// number within it as used by RINEX: the PRN, the GLONASS slot or the SBAS PRN
+// minus 100. The system is "" for SVIDs outside the known ranges.
+func satelliteSystem(svid uint8) (string, int) {
+	for _, r := range svidRanges {
+		if svid >= r.first && svid <= r.last {
+			return r.system, int(svid) - r.offset
+		}
+	}
+	return "", int(svid)
+}
+
+// satelliteName names a satellite the RINEX way, e.g. G05 or E11. GLONASS
+// satellites with an unknown slot are named R00.
+func satelliteName(svid uint8) string {
+	for _, r := range svidRanges {
+		if svid >= r.first && svid <= r.last {
+			prn := int(svid) - r.offset
+			if prn < 10 {
+				return r.prefix + "0" + strconv.Itoa(prn)
+			}
+			return r.prefix + strconv.Itoa(prn)
+		}
+	}
+	return "unknown(" + strconv.Itoa(int(svid)) + ")"
+}
@@ -0,0 +1,214 @@
+package sbf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"log"
+)
+
+/**
+ * Unwrapping of EncapsulatedOutput and RawDataIn. The receiver wraps the NMEA,
+ * RTCM and other output of its streams in SBF, so a single SBF connection
+ * carries everything. The encapsulated data is split into its NMEA sentences
+ * and RTCM3 frames, which are published on their own topic for downstream
+ * users. Data of other formats is published as a whole. The NMEA sentences
+ * and RTCM3 messages are also decoded like the ones received outside SBF.
+ */
+
+const encapsulatedTopic = "encapsulated"
+
+/* Offsets of the encapsulated data */
+const encapsulatedOutputPayloadOffset = 20
+const rawDataInBytesOffset = 16
+
+/* EncapsulatedOutput modes, see the Mode field of the SBF reference guide */
+const (
+	encapsulatedModeRTCMv2       = 0
+	encapsulatedModeCMRv2        = 1
+	encapsulatedModeRTCMv3       = 2
+	encapsulatedModeNMEA         = 4
+	encapsulatedModeASCIIDisplay = 5
+)
+
+// EncapsulatedMessage is an NMEA sentence, RTCM3 frame or other piece of data
+// unwrapped from an SBF block
+type EncapsulatedMessage struct {
+	BlockTime
+	Block       string  `json:"block"` // EncapsulatedOutput or RawDataIn
+	Mode        uint8   `json:"mode"`
+	Source      string  `json:"source,omitempty"` // RawDataIn only
+	Format      string  `json:"format"`           // nmea, rtcm3, cmr or raw
+	Sentence    string  `json:"sentence,omitempty"`
+	MessageType *uint16 `json:"messageType,omitempty"` // RTCM3 message number
+	Data        string  `json:"data,omitempty"`        // Hex encoded RTCM3 frame or raw data
+}
+
+var rawDataInSources = map[uint8]string{
+	SBF_RAWDATAIN_UNKNOWN:   "unknown",
+	SBF_RAWDATAIN_LBMP_MAIN: "lbmpMain",
+	SBF_RAWDATAIN_LBMP_AUX1: "lbmpAux1",
+}
+
+func handleEncapsulatedOutput(buffer []byte) []interface{} {
+	block := EncapsulatedOutput_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleEncapsulatedOutput - Error decoding EncapsulatedOutput block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	length := int(block.N)
+	if encapsulatedOutputPayloadOffset+length > len(buffer) {
+		log.Printf("[ERROR] handleEncapsulatedOutput - EncapsulatedOutput block too short for %d bytes\n", block.N)
+		return []interface{}{}
+	}
+
+	return unwrapEncapsulated(buffer[encapsulatedOutputPayloadOffset:encapsulatedOutputPayloadOffset+length], EncapsulatedMessage{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Block:     "EncapsulatedOutput",
+		Mode:      block.Mode,
+	})
+}
+
+func handleRawDataIn(buffer []byte) []interface{} {
+	block := RawDataIn_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleRawDataIn - Error decoding RawDataIn block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	if len(buffer) <= rawDataInBytesOffset {
+		return []interface{}{}
+	}
+
+	// The block does not hold the number of bytes, the data runs up to the
+	// end of the block and may be followed by padding. The mode of RawDataIn
+	// is not the one of EncapsulatedOutput, its data is recognised by its
+	// first byte.
+	return unwrapRecognised(buffer[rawDataInBytesOffset:], EncapsulatedMessage{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Block:     "RawDataIn",
+		Mode:      block.Mode,
+		Source:    mapString(rawDataInSources, block.Source),
+	})
+}
+
+// unwrapEncapsulated splits encapsulated data into NMEA sentences and RTCM3
+// frames by its encapsulation mode
+func unwrapEncapsulated(data []byte, template EncapsulatedMessage) []interface{} {
+	payloads := []interface{}{}
+	switch template.Mode {
+	case encapsulatedModeNMEA:
+		for len(data) > 0 {
+			if isEncapsulatedPadding(data[0]) {
+				data = data[1:]
+				continue
+			}
+			var sentencePayloads []interface{}
+			sentencePayloads, data = unwrapNMEASentence(data, template)
+			payloads = append(payloads, sentencePayloads...)
+		}
+	case encapsulatedModeRTCMv3:
+		for len(data) > 0 {
+			if isEncapsulatedPadding(data[0]) {
+				data = data[1:]
+				continue
+			}
+			if data[0] != rtcm3Preamble {
+				// Skip to the next frame
+				log.Printf("[ERROR] unwrapEncapsulated - Data without RTCM3 preamble in %s\n", template.Block)
+				next := bytes.IndexByte(data, rtcm3Preamble)
+				if next < 0 {
+					break
+				}
+				data = data[next:]
+			}
+			var framePayloads []interface{}
+			framePayloads, data = unwrapRTCM3Frame(data, template)
+			payloads = append(payloads, framePayloads...)
+		}
+	case encapsulatedModeCMRv2:
+		payloads = append(payloads, unwrapRaw(data, template, "cmr"))
+	case encapsulatedModeRTCMv2, encapsulatedModeASCIIDisplay:
+		payloads = append(payloads, unwrapRaw(data, template, "raw"))
+	default:
+		payloads = unwrapRecognised(data, template)
+	}
+	return payloads
+}
+
+// unwrapRecognised splits data of an unknown format into NMEA sentences and
+// RTCM3 frames, recognised by their first byte
+func unwrapRecognised(data []byte, template EncapsulatedMessage) []interface{} {
+	payloads := []interface{}{}
+	for len(data) > 0 {
+		var messagePayloads []interface{}
+		switch {
+		case data[0] == '$' || data[0] == '!':
+			messagePayloads, data = unwrapNMEASentence(data, template)
+		case data[0] == rtcm3Preamble:
+			messagePayloads, data = unwrapRTCM3Frame(data, template)
+		case isEncapsulatedPadding(data[0]):
+			data = data[1:]
+		default:
+			messagePayloads = []interface{}{unwrapRaw(data, template, "raw")}
+			data = nil
+		}
+		payloads = append(payloads, messagePayloads...)
+	}
+	return payloads
+}
+
+// Padding and line ends between messages
+func isEncapsulatedPadding(b byte) bool {
+	return b == 0 || b == '\r' || b == '\n'
+}
+
+// unwrapNMEASentence returns the payloads of the NMEA sentence at the start of
+// data and the data after it
+func unwrapNMEASentence(data []byte, template EncapsulatedMessage) ([]interface{}, []byte) {
+	end := bytes.IndexByte(data, '\n')
+	if end < 0 {
+		end = len(data) - 1
+	}
+	sentence := string(bytes.TrimRight(data[:end+1], "\r\n\x00"))
+
+	message := template
+	message.Format = "nmea"
+	message.Sentence = sentence
+	payloads := []interface{}{Payload{Topic: encapsulatedTopic + "/nmea", Data: message}}
+	payloads = append(payloads, handleNMEASentence(sentence)...)
+	return payloads, data[end+1:]
+}
+
+// unwrapRTCM3Frame returns the payloads of the RTCM3 frame at the start of
+// data and the data after it
+func unwrapRTCM3Frame(data []byte, template EncapsulatedMessage) ([]interface{}, []byte) {
+	frameLength, ok := rtcm3FrameLength(data)
+	if !ok || frameLength > len(data) {
+		log.Printf("[ERROR] unwrapRTCM3Frame - Truncated RTCM3 frame in %s\n", template.Block)
+		return nil, nil
+	}
+	frame := data[:frameLength]
+	if !rtcm3CRCValid(frame) {
+		log.Printf("[ERROR] unwrapRTCM3Frame - RTCM3 CRC error in %s\n", template.Block)
+		return nil, data[frameLength:]
+	}
+
+	message := template
+	message.Format = "rtcm3"
+	message.Data = hex.EncodeToString(frame)
+	if frameLength >= rtcm3HeaderLength+rtcm3CRCLength+2 {
+		messageType := uint16(frame[3])<<4 | uint16(frame[4])>>4
+		message.MessageType = &messageType
+	}
+	payloads := []interface{}{Payload{Topic: encapsulatedTopic + "/rtcm3", Data: message}}
+	payloads = append(payloads, handleRTCM3Frame(frame)...)
+	return payloads, data[frameLength:]
+}
+
+// unwrapRaw returns the payload of data published as a whole
+func unwrapRaw(data []byte, template EncapsulatedMessage, format string) Payload {
+	message := template
+	message.Format = format
+	message.Data = hex.EncodeToString(bytes.TrimRight(data, "\x00"))
+	return Payload{Topic: encapsulatedTopic + "/raw", Data: message}
+}
@@ -0,0 +1,503 @@
+package sbf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+/**
+ * Decoding of the external event blocks. ExtEvent carries the time tag of a
+ * pulse on one of the EventA/EventB inputs; the ExtEvent* position and
+ * attitude blocks that follow it carry the same TOW/WNc and are matched to it
+ * so every message identifies the event it belongs to. Events with a position
+ * are also appended to a geotag file when settings.GeotagDirectory is set.
+ */
+
+const extEventTimeTopic = "events/time"
+const extEventPVTTopic = "events/pvt"
+const extEventINSTopic = "events/ins"
+const extEventBaseVectorTopic = "events/basevector"
+const extEventAttitudeTopic = "events/attitude"
+
+/* Offset of the first sub-block in ExtEventBaseVectGeod and ExtEventINSNavGeod */
+const extEventBaseVectGeodSubBlockOffset = 16
+const extEventINSNavGeodSubBlockOffset = 56
+
+/* Offset of the fields added in revision 2 of ExtEventPVTCartesian and ExtEventPVTGeodetic */
+const extEventPVTRev2Offset = 88
+
+const secondsPerWeek = 7 * 24 * 3600
+
+// EventTime identifies an external event and its precise time
+type EventTime struct {
+	Source   string    `json:"source"`
+	Polarity string    `json:"polarity"`
+	GPSWeek  uint16    `json:"gpsWeek"`
+	GPSTOW   float64   `json:"gpsTow"` // s, corrected for Offset and RxClkBias
+	UTC      time.Time `json:"utc"`
+}
+
+// ExtEvent is the time tag of a pulse on one of the event inputs
+type ExtEvent struct {
+	BlockTime
+	EventTime
+	Offset    float64  `json:"offset"`              // s, sub-millisecond part of the receiver time tag
+	RxClkBias *float64 `json:"rxClkBias,omitempty"` // ms
+	PVTAge    *float64 `json:"pvtAge,omitempty"`    // s, age of the PVT used to correct the time tag
+}
+
+// ExtEventPVT is the GNSS position and velocity at the instant of an event
+type ExtEventPVT struct {
+	BlockTime
+	Block        string     `json:"block"`
+	Event        *EventTime `json:"event,omitempty"`
+	Mode         string     `json:"mode"`
+	Error        string     `json:"error,omitempty"`
+	NrSV         *int       `json:"nrSV,omitempty"`
+	Datum        uint8      `json:"datum"`
+	Latitude     *float64   `json:"latitude,omitempty"`     // deg
+	Longitude    *float64   `json:"longitude,omitempty"`    // deg
+	Height       *float64   `json:"height,omitempty"`       // m, ellipsoidal
+	Undulation   *float64   `json:"undulation,omitempty"`   // m
+	PositionECEF *XYZ       `json:"positionEcef,omitempty"` // m
+	Velocity     *ENU       `json:"velocity,omitempty"`     // m/s
+	VelocityECEF *XYZ       `json:"velocityEcef,omitempty"` // m/s
+	COG          *float64   `json:"cog,omitempty"`          // deg
+	RxClkBias    *float64   `json:"rxClkBias,omitempty"`    // ms
+	RxClkDrift   *float64   `json:"rxClkDrift,omitempty"`   // ppm
+	MeanCorrAge  *float64   `json:"meanCorrAge,omitempty"`  // s
+	HAccuracy    *float64   `json:"hAccuracy,omitempty"`    // m
+	VAccuracy    *float64   `json:"vAccuracy,omitempty"`    // m
+	Latency      *float64   `json:"latency,omitempty"`      // s
+}
+
+// ExtEventINSNavigation is the INS/GNSS solution at the instant of an event
+type ExtEventINSNavigation struct {
+	BlockTime
+	Event          *EventTime      `json:"event,omitempty"`
+	Aiding         string          `json:"aiding"`
+	GNSSMode       string          `json:"gnssMode"`
+	Error          string          `json:"error,omitempty"`
+	GNSSAge        *float64        `json:"gnssAge,omitempty"` // s
+	Datum          uint8           `json:"datum"`
+	Latitude       *float64        `json:"latitude,omitempty"`   // deg
+	Longitude      *float64        `json:"longitude,omitempty"`  // deg
+	Height         *float64        `json:"height,omitempty"`     // m, ellipsoidal
+	Undulation     *float64        `json:"undulation,omitempty"` // m
+	Velocity       *ENU            `json:"velocity,omitempty"`   // m/s
+	Attitude       *Euler          `json:"attitude,omitempty"`   // deg
+	PositionStdDev *GeodeticStdDev `json:"positionStdDev,omitempty"`
+	VelocityStdDev *ENU            `json:"velocityStdDev,omitempty"`
+	AttitudeStdDev *Euler          `json:"attitudeStdDev,omitempty"`
+	Accuracy       *float64        `json:"accuracy,omitempty"` // m
+}
+
+// ExtEventBaseVectors are the vectors to the base stations at the instant of an event
+type ExtEventBaseVectors struct {
+	BlockTime
+	Event   *EventTime        `json:"event,omitempty"`
+	Vectors []ExtEventBaseVec `json:"vectors"`
+}
+
+// ExtEventBaseVec is the ENU vector from one base station to the rover
+type ExtEventBaseVec struct {
+	ReferenceID uint16   `json:"referenceId"`
+	Mode        string   `json:"mode"`
+	Error       string   `json:"error,omitempty"`
+	NrSV        *int     `json:"nrSV,omitempty"`
+	Delta       ENU      `json:"delta"`               // m
+	DeltaV      ENU      `json:"deltaV"`              // m/s
+	Azimuth     *float64 `json:"azimuth,omitempty"`   // deg
+	Elevation   *float64 `json:"elevation,omitempty"` // deg
+	CorrAge     *float64 `json:"corrAge,omitempty"`   // s
+}
+
+// ExtEventAttitude is the GNSS attitude at the instant of an event
+type ExtEventAttitude struct {
+	BlockTime
+	Event        *EventTime `json:"event,omitempty"`
+	Mode         string     `json:"mode"`
+	Error        string     `json:"error,omitempty"`
+	NrSV         *int       `json:"nrSV,omitempty"`
+	Attitude     Euler      `json:"attitude"`     // deg
+	AttitudeRate Euler      `json:"attitudeRate"` // deg/s
+}
+
+var extEventSources = map[uint8]string{
+	1: "EventA",
+	2: "EventB",
+}
+
+var attitudeModes = map[uint16]string{
+	0: "noAttitude",
+	1: "headingPitchFloat",
+	2: "headingPitchFixed",
+	3: "headingPitchRollFloat",
+	4: "headingPitchRollFixed",
+}
+
+var attitudeErrors = map[uint8]string{
+	SBF_ATTERR_NONE:                    "",
+	SBF_ATTERR_NOTENOUGHMEAS:           "not enough measurements",
+	SBF_ATTERR_ANTENNASALIGNED:         "antennas are aligned",
+	SBF_ATTERR_INCONSISTENCYWITHANTPOS: "inconsistency with manual antenna position",
+}
+
+/* Last ExtEvent of each source, used to match the ExtEvent* blocks that follow it */
+type extEventTimeTag struct {
+	tow   uint32
+	wnc   uint16
+	event EventTime
+}
+
+func extEventSourceString(source uint8) string {
+	if name, ok := extEventSources[source&0x1F]; ok {
+		return name
+	}
+	return "Event" + strconv.Itoa(int(source&0x1F))
+}
+
+func attitudeModeString(mode uint16) string {
+	if name, ok := attitudeModes[mode]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// attitudeErrorString describes an AttEuler error. Bits 0-1 hold the error of
+// the main/aux1 baseline and bits 2-3 the error of the main/aux2 baseline.
+func attitudeErrorString(err uint8) string {
+	if err&SBF_ATTERR_NO_ATTITUDE_REQUESTED != 0 {
+		return "attitude not requested"
+	}
+	if description := attitudeErrors[err&0x03]; description != "" {
+		return "aux1: " + description
+	}
+	if description := attitudeErrors[(err>>2)&0x03]; description != "" {
+		return "aux2: " + description
+	}
+	return ""
+}
+
+// eventFor returns the ExtEvent with the same time tag as an ExtEvent* block
+func eventFor(tow uint32, wnc uint16) *EventTime {
+	for _, timeTag := range state.extEvents {
+		if timeTag.tow == tow && timeTag.wnc == wnc {
+			event := timeTag.event
+			return &event
+		}
+	}
+	return nil
+}
+
+func handleExtEvent(buffer []byte) []interface{} {
+	block := ExtEvent_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtEvent - Error decoding ExtEvent block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	timer := block.TimerData
+
+	event := ExtEvent{
+		BlockTime: newBlockTime(timer.TOW, timer.WNc),
+		RxClkBias: optDouble(timer.RxClkBias),
+	}
+	if offset := optFloat(timer.Offset); offset != nil {
+		event.Offset = *offset
+	}
+	if block.Header.ID&0xE000 != 0 {
+		event.PVTAge = optScaled(int64(timer.PVTAge), 65535, 0.01)
+	}
+	event.Source = extEventSourceString(timer.Source)
+	event.Polarity = "rising"
+	if timer.Polarity != 0 {
+		event.Polarity = "falling"
+	}
+
+	if timer.TOW == towDoNotUse || timer.WNc == wncDoNotUse {
+		log.Printf("[ERROR] handleExtEvent - %s received before the receiver time was set\n", event.Source)
+		return []interface{}{Payload{Topic: extEventTimeTopic, Data: event}}
+	}
+
+	// The receiver time of the event is TOW + Offset, the receiver clock bias
+	// converts it to GNSS system time
+	seconds := float64(timer.TOW)/1000 + event.Offset
+	if event.RxClkBias != nil {
+		seconds -= *event.RxClkBias / 1000
+	}
+	week := int(timer.WNc)
+	for seconds < 0 {
+		seconds += secondsPerWeek
+		week--
+	}
+	for seconds >= secondsPerWeek {
+		seconds -= secondsPerWeek
+		week++
+	}
+	event.GPSWeek = uint16(week)
+	event.GPSTOW = seconds
+	whole, fraction := math.Modf(seconds)
+	event.UTC = gpsEpoch.Add(time.Duration(week)*7*24*time.Hour +
+		time.Duration(whole)*time.Second + time.Duration(math.Round(fraction*1e9)) -
+		time.Duration(gpsUtcLeapSeconds)*time.Second)
+
+	state.extEvents[event.Source] = extEventTimeTag{tow: timer.TOW, wnc: timer.WNc, event: event.EventTime}
+
+	return []interface{}{Payload{Topic: extEventTimeTopic, Data: event}}
+}
+
+// newExtEventPVT fills the fields ExtEventPVTGeodetic and ExtEventPVTCartesian
+// have in common. Revision 0 lacks NrBases and PPPInfo and revision 2 adds
+// Latency, HAccuracy, VAccuracy and Misc, so the revision 1 struct is used for
+// all of them and the revision 2 fields are read from the buffer.
+func newExtEventPVT(name string, buffer []byte, block *ExtEventPVTGeodetic_1_1_t) ExtEventPVT {
+	pvt := ExtEventPVT{
+		BlockTime:   newBlockTime(block.TOW, block.WNc),
+		Block:       name,
+		Event:       eventFor(block.TOW, block.WNc),
+		Mode:        pvtModeString(block.Mode),
+		Error:       pvtErrorString(block.Error),
+		NrSV:        optNrSV(block.NrSV),
+		Datum:       block.Datum,
+		Undulation:  optFloat(block.Undulation),
+		COG:         optFloat(block.COG),
+		RxClkBias:   optDouble(block.RxClkBias),
+		RxClkDrift:  optFloat(block.RxClkDrift),
+		MeanCorrAge: optScaled(int64(block.MeanCorrAge), 65535, 0.01),
+	}
+	if block.Header.ID>>13 >= 2 && len(buffer) >= extEventPVTRev2Offset+6 {
+		pvt.Latency = optScaled(int64(binary.LittleEndian.Uint16(buffer[extEventPVTRev2Offset:])), 65535, 0.0001)
+		pvt.HAccuracy = optScaled(int64(binary.LittleEndian.Uint16(buffer[extEventPVTRev2Offset+2:])), 65535, 0.01)
+		pvt.VAccuracy = optScaled(int64(binary.LittleEndian.Uint16(buffer[extEventPVTRev2Offset+4:])), 65535, 0.01)
+	}
+	return pvt
+}
+
+func handleExtEventPVTGeodetic(buffer []byte) []interface{} {
+	block := ExtEventPVTGeodetic_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtEventPVTGeodetic - Error decoding ExtEventPVTGeodetic block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	pvt := newExtEventPVT("ExtEventPVTGeodetic", buffer, &block)
+	pvt.Latitude = radToDeg(optDouble(block.Lat))
+	pvt.Longitude = radToDeg(optDouble(block.Lon))
+	pvt.Height = optDouble(block.Alt)
+	pvt.Velocity = &ENU{optFloat(block.Ve), optFloat(block.Vn), optFloat(block.Vu)}
+
+	if !state.geotagFromINS && pvt.Event != nil {
+		var attitude *Euler
+		if state.extEventAttitude != nil && state.extEventAttitude.TOW == pvt.TOW && state.extEventAttitude.WNc == pvt.WNc {
+			attitude = &state.extEventAttitude.Attitude
+		}
+		writeGeotag(pvt.Event, pvt.Latitude, pvt.Longitude, pvt.Height, attitude)
+	}
+
+	return []interface{}{Payload{Topic: extEventPVTTopic, Data: pvt}}
+}
+
+func handleExtEventPVTCartesian(buffer []byte) []interface{} {
+	block := ExtEventPVTCartesian_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtEventPVTCartesian - Error decoding ExtEventPVTCartesian block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	// Both blocks share the same layout, only the meaning of the position and
+	// velocity fields differs
+	common := ExtEventPVTGeodetic_1_1_t{}
+	decodeBlock(buffer, &common)
+	pvt := newExtEventPVT("ExtEventPVTCartesian", buffer, &common)
+	pvt.PositionECEF = &XYZ{optDouble(block.X), optDouble(block.Y), optDouble(block.Z)}
+	pvt.VelocityECEF = &XYZ{optFloat(block.Vx), optFloat(block.Vy), optFloat(block.Vz)}
+
+	return []interface{}{Payload{Topic: extEventPVTTopic, Data: pvt}}
+}
+
+func handleExtEventINSNavGeod(buffer []byte) []interface{} {
+	block := ExtEventINSNavGeod_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtEventINSNavGeod - Error decoding ExtEventINSNavGeod block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := ExtEventINSNavigation{
+		BlockTime:  newBlockTime(block.TOW, block.WNc),
+		Event:      eventFor(block.TOW, block.WNc),
+		Aiding:     insNavAiding(block.Error, block.Info),
+		GNSSMode:   pvtModeString(block.GNSSMode),
+		Error:      pvtErrorString(block.Error),
+		GNSSAge:    optScaled(int64(block.GNSSAge), 65535, 0.01),
+		Datum:      block.Datum,
+		Latitude:   radToDeg(optDouble(block.Latitude)),
+		Longitude:  radToDeg(optDouble(block.Longitude)),
+		Height:     optDouble(block.Height),
+		Undulation: optFloat(block.Undulation),
+		Accuracy:   optScaled(int64(block.Accuracy), 65535, 0.01),
+	}
+
+	// The sub-blocks selected by SBList follow the header in bit order
+	offset := extEventINSNavGeodSubBlockOffset
+	for bit := uint16(INSNAV_SBLIST_POSITION_STTDEV); bit <= INSNAV_SBLIST_VELOCITY_STTDEV; bit <<= 1 {
+		if block.SBList&bit == 0 {
+			continue
+		}
+		ok := true
+		switch bit {
+		case INSNAV_SBLIST_POSITION_STTDEV:
+			sb := ExtEventINSNavGeodPosStdDev_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.PositionStdDev = &GeodeticStdDev{optFloat(sb.LatitudeStdDev), optFloat(sb.LongitudeStdDev), optFloat(sb.HeightStdDev)}
+			}
+		case INSNAV_SBLIST_ATTITDE:
+			sb := ExtEventINSNavGeodAtt_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.Attitude = &Euler{optFloat(sb.Heading), optFloat(sb.Pitch), optFloat(sb.Roll)}
+			}
+		case INSNAV_SBLIST_ATTITDE_STTDEV:
+			sb := ExtEventINSNavGeodAttStdDev_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.AttitudeStdDev = &Euler{optFloat(sb.HeadingStdDev), optFloat(sb.PitchStdDev), optFloat(sb.RollStdDev)}
+			}
+		case INSNAV_SBLIST_VELOCITY:
+			sb := ExtEventINSNavGeodVel_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.Velocity = &ENU{optFloat(sb.Ve), optFloat(sb.Vn), optFloat(sb.Vu)}
+			}
+		case INSNAV_SBLIST_VELOCITY_STTDEV:
+			sb := ExtEventINSNavGeodVelStdDev_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.VelocityStdDev = &ENU{optFloat(sb.VeStdDev), optFloat(sb.VnStdDev), optFloat(sb.VuStdDev)}
+			}
+		}
+		if !ok {
+			log.Printf("[ERROR] handleExtEventINSNavGeod - ExtEventINSNavGeod block too short for sub-block list 0x%04x\n", block.SBList)
+			break
+		}
+		offset += binary.Size(ExtEventINSNavGeodAtt_1_0_t{})
+	}
+
+	// Once the receiver outputs INS positions at events they are preferred
+	// over the GNSS-only ExtEventPVTGeodetic for the geotags
+	state.geotagFromINS = true
+	if nav.Event != nil {
+		writeGeotag(nav.Event, nav.Latitude, nav.Longitude, nav.Height, nav.Attitude)
+	}
+
+	return []interface{}{Payload{Topic: extEventINSTopic, Data: nav}}
+}
+
+func handleExtEventBaseVectGeod(buffer []byte) []interface{} {
+	block := ExtEventBaseVectGeod_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtEventBaseVectGeod - Error decoding ExtEventBaseVectGeod block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	vectors := ExtEventBaseVectors{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Event:     eventFor(block.TOW, block.WNc),
+		Vectors:   []ExtEventBaseVec{},
+	}
+	for i := 0; i < int(block.N); i++ {
+		sb := ExtEventVectorInfoGeod_1_0_t{}
+		if !decodeSubBlock(buffer, extEventBaseVectGeodSubBlockOffset+i*int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleExtEventBaseVectGeod - ExtEventBaseVectGeod block too short for %d vectors\n", block.N)
+			break
+		}
+		vectors.Vectors = append(vectors.Vectors, ExtEventBaseVec{
+			ReferenceID: sb.ReferenceID,
+			Mode:        pvtModeString(sb.Mode),
+			Error:       pvtErrorString(sb.Error),
+			NrSV:        optNrSV(sb.NrSV),
+			Delta:       ENU{optDouble(sb.DeltaEast), optDouble(sb.DeltaNorth), optDouble(sb.DeltaUp)},
+			DeltaV:      ENU{optFloat(sb.DeltaVe), optFloat(sb.DeltaVn), optFloat(sb.DeltaVu)},
+			Azimuth:     optScaled(int64(sb.Azimuth), 65535, 0.01),
+			Elevation:   optScaled(int64(sb.Elevation), -32768, 0.01),
+			CorrAge:     optScaled(int64(sb.CorrAge), 65535, 0.01),
+		})
+	}
+
+	return []interface{}{Payload{Topic: extEventBaseVectorTopic, Data: vectors}}
+}
+
+func handleExtEventAttEuler(buffer []byte) []interface{} {
+	block := ExtEventAttEuler_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtEventAttEuler - Error decoding ExtEventAttEuler block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	attitude := ExtEventAttitude{
+		BlockTime:    newBlockTime(block.TOW, block.WNc),
+		Event:        eventFor(block.TOW, block.WNc),
+		Mode:         attitudeModeString(block.Mode),
+		Error:        attitudeErrorString(block.Error),
+		NrSV:         optNrSV(block.NrSV),
+		Attitude:     Euler{optFloat(block.Heading), optFloat(block.Pitch), optFloat(block.Roll)},
+		AttitudeRate: Euler{optFloat(block.HeadingDot), optFloat(block.PitchDot), optFloat(block.RollDot)},
+	}
+	state.extEventAttitude = &attitude
+
+	return []interface{}{Payload{Topic: extEventAttitudeTopic, Data: attitude}}
+}
+
+// writeGeotag appends an event to the geotag file of the session. The file is
+// a CSV with one row per event, labelled with the event source and a counter
+// so the rows can be matched to the images in the order they were taken.
+func writeGeotag(event *EventTime, latitude, longitude, height *float64, attitude *Euler) {
+	if settings.GeotagDirectory == "" || latitude == nil || longitude == nil {
+		return
+	}
+
+	if state.geotagFile == nil {
+		directory := receiverDirectory(settings.GeotagDirectory)
+		if err := os.MkdirAll(directory, 0755); err != nil {
+			log.Printf("[ERROR] writeGeotag - Error creating geotag directory %s: %s\n", directory, err.Error())
+			return
+		}
+		name := filepath.Join(directory, "geotags_"+time.Now().UTC().Format("20060102_150405")+".csv")
+		file, err := os.Create(name)
+		if err != nil {
+			log.Printf("[ERROR] writeGeotag - Error creating geotag file %s: %s\n", name, err.Error())
+			return
+		}
+		if _, err := file.WriteString("label,utc,gpsWeek,gpsTow,latitude,longitude,height,heading,pitch,roll\n"); err != nil {
+			log.Printf("[ERROR] writeGeotag - Error writing geotag file %s: %s\n", name, err.Error())
+		}
+		log.Printf("[INFO] writeGeotag - Writing event geotags to %s\n", name)
+		state.geotagFile = file
+	}
+
+	state.geotagCounts[event.Source]++
+	var heading, pitch, roll *float64
+	if attitude != nil {
+		heading, pitch, roll = attitude.Heading, attitude.Pitch, attitude.Roll
+	}
+
+	writer := bufio.NewWriter(state.geotagFile)
+	fmt.Fprintf(writer, "%s_%04d,%s,%d,%.7f,%s,%s,%s,%s,%s,%s\n",
+		event.Source, state.geotagCounts[event.Source], event.UTC.Format("2006-01-02T15:04:05.000000000Z"),
+		event.GPSWeek, event.GPSTOW,
+		csvFloat(latitude, 9), csvFloat(longitude, 9), csvFloat(height, 3),
+		csvFloat(heading, 3), csvFloat(pitch, 3), csvFloat(roll, 3))
+	if err := writer.Flush(); err != nil {
+		log.Printf("[ERROR] writeGeotag - Error writing geotag file %s: %s\n", state.geotagFile.Name(), err.Error())
+	}
+}
+
+// csvFloat formats an optional value for a CSV cell, leaving it empty when unset
+func csvFloat(value *float64, precision int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', precision, 64)
+}
@@ -0,0 +1,362 @@
+package sbf
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+)
+
+/**
+ * Decoding of the external sensor blocks. ExtSensorMeas is output at IMU
+ * rates, so it is only published every settings.ExtSensorDecimation blocks.
+ */
+
+const extSensorMeasurementsTopic = "extsensor/measurements"
+const extSensorStatusTopic = "extsensor/status"
+const extSensorSetupTopic = "extsensor/setup"
+const extSensorInfoTopic = "extsensor/info"
+
+/* Offset of the first sub-block in ExtSensorMeas and ExtSensorSetup */
+const extSensorSubBlockOffset = 16
+
+/* Offset of the data following Source and SensorModel in ExtSensorStatus_2 and ExtSensorInfo */
+const extSensorDataOffset = 16
+
+// ExtSensorMeasurements is one epoch of external sensor measurements
+type ExtSensorMeasurements struct {
+	BlockTime
+	Measurements []ExtSensorMeasurement `json:"measurements"`
+}
+
+// ExtSensorMeasurement is a single measurement of an external sensor. Only the
+// fields matching Type are set.
+type ExtSensorMeasurement struct {
+	Source         string   `json:"source"`
+	SensorModel    string   `json:"sensorModel"`
+	Type           string   `json:"type"`
+	ObsInfo        uint8    `json:"obsInfo"`
+	Acceleration   *XYZ     `json:"acceleration,omitempty"`   // m/s²
+	AngularRate    *XYZ     `json:"angularRate,omitempty"`    // deg/s
+	Temperature    *float64 `json:"temperature,omitempty"`    // °C
+	Velocity       *XYZ     `json:"velocity,omitempty"`       // m/s
+	VelocityStdDev *XYZ     `json:"velocityStdDev,omitempty"` // m/s
+	ZeroVelocity   *bool    `json:"zeroVelocity,omitempty"`
+}
+
+// ExtSensorStatus is the sensor specific status reported by an external sensor
+type ExtSensorStatus struct {
+	BlockTime
+	Source      string          `json:"source"`
+	SensorModel string          `json:"sensorModel"`
+	StatusType  string          `json:"statusType,omitempty"`
+	StatusBits  string          `json:"statusBits"`       // Hex encoded
+	Status      map[string]bool `json:"status,omitempty"` // Decoded status word of the Xsens MTi sensors
+}
+
+// ExtSensorSetup lists the external sensors the receiver is configured with
+type ExtSensorSetup struct {
+	BlockTime
+	Sensors []ExtSensor `json:"sensors"`
+}
+
+// ExtSensor describes one configured external sensor
+type ExtSensor struct {
+	Source         string   `json:"source"`
+	SensorModel    string   `json:"sensorModel"`
+	MeasTypes      []string `json:"measTypes"`
+	LeverArmSource string   `json:"leverArmSource,omitempty"`
+	LeverArm       *XYZ     `json:"leverArm,omitempty"`       // m
+	LeverArmStdDev *XYZ     `json:"leverArmStdDev,omitempty"` // m
+}
+
+// ExtSensorInfo identifies an external sensor
+type ExtSensorInfo struct {
+	BlockTime
+	Source          string `json:"source"`
+	SensorModel     string `json:"sensorModel"`
+	SerialNumber    string `json:"serialNumber"`
+	HardwareVersion string `json:"hardwareVersion"`
+	FirmwareVersion string `json:"firmwareVersion"`
+}
+
+var extSensorModels = map[uint8]string{
+	EXTSENSORSBF_SENSORMODEL_MMQ50:     "MMQ50",
+	EXTSENSORSBF_SENSORMODEL_MTI:       "MTi",
+	EXTSENSORSBF_SENSORMODEL_ELLIPSE:   "Ellipse",
+	EXTSENSORSBF_SENSORMODEL_MTI10:     "MTi-10",
+	EXTSENSORSBF_SENSORMODEL_SIMSENSOR: "SimSensor",
+	EXTSENSORSBF_SENSORMODEL_ELLIPSE2:  "Ellipse2",
+	EXTSENSORSBF_SENSORMODEL_EKINOX2:   "Ekinox2",
+	EXTSENSORSBF_SENSORMODEL_VN100:     "VN-100",
+	EXTSENSORSBF_SENSORMODEL_HGUIDE:    "HGuide",
+	EXTSENSORSBF_SENSORMODEL_RIVIANIMU: "RivianIMU",
+	EXTSENSORSBF_SENSORMODEL_ADIS:      "ADIS",
+	EXTSENSORSBF_SENSORMODEL_ZUPT:      "ZUPT",
+	EXTSENSORSBF_SENSORMODEL_VSM1:      "VSM1",
+	EXTSENSORSBF_SENSORMODEL_VSM2:      "VSM2",
+	EXTSENSORSBF_SENSORMODEL_VSM3:      "VSM3",
+	EXTSENSORSBF_SENSORMODEL_VSM4:      "VSM4",
+}
+
+var extSensorMeasTypes = map[uint8]string{
+	EXTSENSORSBF_MEASTYPE_ACC:           "acceleration",
+	EXTSENSORSBF_MEASTYPE_RATE:          "angularRate",
+	EXTSENSORSBF_MEASTYPE_MAGNETICFIELD: "magneticField",
+	EXTSENSORSBF_MEASTYPE_INFO:          "info",
+	EXTSENSORSBF_MEASTYPE_VELOCITY:      "velocity",
+	EXTSENSORSBF_MEASTYPE_ZUPT:          "zeroVelocity",
+}
+
+var extSensorSetupMeasTypes = []struct {
+	bit  uint16
+	name string
+}{
+	{EXTSENSORSETUP_MEASTYPE_ACCELERATIONS, "acceleration"},
+	{EXTSENSORSETUP_MEASTYPE_ANGULAR_RATES, "angularRate"},
+	{EXTSENSORSETUP_MEASTYPE_MAGNETICFIELD, "magneticField"},
+	{EXTSENSORSETUP_MEASTYPE_PRESSURE, "pressure"},
+	{EXTSENSORSETUP_MEASTYPE_TEMPERATURE, "temperature"},
+}
+
+var extSensorLeverArmSources = map[uint8]string{
+	EXTSENSORSETUP_LEVERARMSOURCE_NVRAM:       "nvram",
+	EXTSENSORSETUP_LEVERARMSOURCE_MANUAL:      "manual",
+	EXTSENSORSETUP_LEVERARMSOURCE_CALIBRATION: "calibration",
+}
+
+/* Bits of the status word of the Xsens MTi sensors, the first 4 status bytes */
+var xsensStatusBits = map[uint]string{
+	0:  "selfTest",
+	1:  "filterValid",
+	2:  "gnssFix",
+	5:  "representativeMotion",
+	6:  "clockSync",
+	8:  "clipAccX",
+	9:  "clipAccY",
+	10: "clipAccZ",
+	11: "clipGyrX",
+	12: "clipGyrY",
+	13: "clipGyrZ",
+	14: "clipMagX",
+	15: "clipMagY",
+	16: "clipMagZ",
+	19: "clipping",
+	21: "syncIn",
+	22: "syncOut",
+	26: "gnssTimePulse",
+}
+
+// extSensorStatusFlags decodes the status bits of the sensors whose status
+// word is known, nil for the other sensors
+func extSensorStatusFlags(model uint8, bits []byte) map[string]bool {
+	if (model != EXTSENSORSBF_SENSORMODEL_MTI && model != EXTSENSORSBF_SENSORMODEL_MTI10) || len(bits) < 4 {
+		return nil
+	}
+	return bitFlags(xsensStatusBits, binary.LittleEndian.Uint32(bits))
+}
+
+func extSensorModelString(model uint8) string {
+	if name, ok := extSensorModels[model]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func handleExtSensorMeas(buffer []byte) []interface{} {
+	state.extSensorMeasCount++
+	if settings.ExtSensorDecimation > 1 && (state.extSensorMeasCount-1)%settings.ExtSensorDecimation != 0 {
+		return []interface{}{}
+	}
+
+	block := ExtSensorMeas_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtSensorMeas - Error decoding ExtSensorMeas block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	measurements := ExtSensorMeasurements{
+		BlockTime:    newBlockTime(block.TOW, block.WNc),
+		Measurements: []ExtSensorMeasurement{},
+	}
+
+	for i := 0; i < int(block.N); i++ {
+		offset := extSensorSubBlockOffset + i*int(block.SBSize)
+		if offset+4 > len(buffer) {
+			log.Printf("[ERROR] handleExtSensorMeas - ExtSensorMeas block too short for %d sub-blocks\n", block.N)
+			break
+		}
+		set := ExtSensorMeasSet_1_0_t{
+			Source:      buffer[offset],
+			SensorModel: buffer[offset+1],
+			Type:        buffer[offset+2],
+			ObsInfo:     buffer[offset+3],
+		}
+
+		measurement := ExtSensorMeasurement{
+			Source:      connectionDescriptorString(set.Source),
+			SensorModel: extSensorModelString(set.SensorModel),
+			Type:        extSensorMeasTypes[set.Type],
+			ObsInfo:     set.ObsInfo,
+		}
+		if measurement.Type == "" {
+			measurement.Type = "unknown"
+		}
+
+		// The measurement data is a union selected by Type
+		data := &set.ExtSensorMeasData
+		dataOffset := offset + 4
+		ok := true
+		switch set.Type {
+		case EXTSENSORSBF_MEASTYPE_ACC:
+			if ok = decodeSubBlock(buffer, dataOffset, &data.Acceleration); ok {
+				measurement.Acceleration = &XYZ{optDouble(data.Acceleration.AccelerationX), optDouble(data.Acceleration.AccelerationY), optDouble(data.Acceleration.AccelerationZ)}
+			}
+		case EXTSENSORSBF_MEASTYPE_RATE:
+			if ok = decodeSubBlock(buffer, dataOffset, &data.AngularRate); ok {
+				measurement.AngularRate = &XYZ{optDouble(data.AngularRate.AngularRateX), optDouble(data.AngularRate.AngularRateY), optDouble(data.AngularRate.AngularRateZ)}
+			}
+		case EXTSENSORSBF_MEASTYPE_INFO:
+			if ok = decodeSubBlock(buffer, dataOffset, &data.Info); ok {
+				measurement.Temperature = optScaled(int64(data.Info.SensorTemperature), -32768, 0.01)
+			}
+		case EXTSENSORSBF_MEASTYPE_VELOCITY:
+			if ok = decodeSubBlock(buffer, dataOffset, &data.Velocity); ok {
+				measurement.Velocity = &XYZ{optFloat(data.Velocity.VelocityX), optFloat(data.Velocity.VelocityY), optFloat(data.Velocity.VelocityZ)}
+				measurement.VelocityStdDev = &XYZ{optFloat(data.Velocity.StdDevX), optFloat(data.Velocity.StdDevY), optFloat(data.Velocity.StdDevZ)}
+			}
+		case EXTSENSORSBF_MEASTYPE_ZUPT:
+			if ok = decodeSubBlock(buffer, dataOffset, &data.ZeroVelocityFlag); ok {
+				zeroVelocity := data.ZeroVelocityFlag.ZeroVelocityFlag != 0
+				measurement.ZeroVelocity = &zeroVelocity
+			}
+		}
+		if !ok {
+			log.Printf("[ERROR] handleExtSensorMeas - ExtSensorMeas sub-block %d too short for type %d\n", i, set.Type)
+			break
+		}
+		measurements.Measurements = append(measurements.Measurements, measurement)
+	}
+
+	return []interface{}{Payload{Topic: extSensorMeasurementsTopic, Data: measurements}}
+}
+
+func handleExtSensorStatus(buffer []byte) []interface{} {
+	block := ExtSensorStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtSensorStatus - Error decoding ExtSensorStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := ExtSensorStatus{
+		BlockTime:   newBlockTime(block.TOW, block.WNc),
+		Source:      connectionDescriptorString(block.Source),
+		SensorModel: extSensorModelString(block.SensorModel),
+		StatusType:  "status",
+		StatusBits:  hex.EncodeToString(block.StatusBits[:]),
+	}
+	if block.StatusType == EXTSENSORSTATUS_STATUSTYPE_CONFIG {
+		status.StatusType = "config"
+	} else {
+		status.Status = extSensorStatusFlags(block.SensorModel, block.StatusBits[:])
+	}
+
+	return []interface{}{Payload{Topic: extSensorStatusTopic, Data: status}}
+}
+
+func handleExtSensorStatus2(buffer []byte) []interface{} {
+	block := ExtSensorStatus_2_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtSensorStatus2 - Error decoding ExtSensorStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := ExtSensorStatus{
+		BlockTime:   newBlockTime(block.TOW, block.WNc),
+		Source:      connectionDescriptorString(block.Source),
+		SensorModel: extSensorModelString(block.SensorModel),
+	}
+	if len(buffer) > extSensorDataOffset {
+		status.StatusBits = hex.EncodeToString(buffer[extSensorDataOffset:])
+		status.Status = extSensorStatusFlags(block.SensorModel, buffer[extSensorDataOffset:])
+	}
+
+	return []interface{}{Payload{Topic: extSensorStatusTopic, Data: status}}
+}
+
+func handleExtSensorSetup(buffer []byte) []interface{} {
+	block := ExtSensorSetup_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtSensorSetup - Error decoding ExtSensorSetup block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	revision := SBF_ID_TO_REV(block.Header.ID)
+
+	setup := ExtSensorSetup{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Sensors:   []ExtSensor{},
+	}
+
+	for i := 0; i < int(block.N); i++ {
+		offset := extSensorSubBlockOffset + i*int(block.SBLength)
+		if offset+int(block.SBLength) > len(buffer) {
+			log.Printf("[ERROR] handleExtSensorSetup - ExtSensorSetup block too short for %d sub-blocks\n", block.N)
+			break
+		}
+
+		// Older revisions have shorter sub-blocks, the missing fields decode as zero
+		sb := OneSensor_1_2_t{}
+		if err := decodeBlock(buffer[offset:offset+int(block.SBLength)], &sb); err != nil {
+			log.Printf("[ERROR] handleExtSensorSetup - Error decoding ExtSensorSetup sub-block: %s\n", err.Error())
+			break
+		}
+
+		sensor := ExtSensor{
+			Source:      connectionDescriptorString(sb.Source),
+			SensorModel: extSensorModelString(sb.SensorModel),
+			MeasTypes:   []string{},
+		}
+		for _, measType := range extSensorSetupMeasTypes {
+			if sb.MeasTypes&measType.bit != 0 {
+				sensor.MeasTypes = append(sensor.MeasTypes, measType.name)
+			}
+		}
+		if revision >= 1 {
+			sensor.LeverArmSource = extSensorLeverArmSources[sb.LeverArmSource]
+		}
+		if revision >= 2 && int(block.SBLength) >= binary.Size(sb) {
+			sensor.LeverArm = &XYZ{
+				optScaled(int64(sb.LeverArmX), -32768, 0.001),
+				optScaled(int64(sb.LeverArmY), -32768, 0.001),
+				optScaled(int64(sb.LeverArmZ), -32768, 0.001),
+			}
+			sensor.LeverArmStdDev = &XYZ{
+				optScaled(int64(sb.LeverArmStdX), 65535, 0.001),
+				optScaled(int64(sb.LeverArmStdY), 65535, 0.001),
+				optScaled(int64(sb.LeverArmStdZ), 65535, 0.001),
+			}
+		}
+		setup.Sensors = append(setup.Sensors, sensor)
+	}
+
+	return []interface{}{Payload{Topic: extSensorSetupTopic, Data: setup}}
+}
+
+func handleExtSensorInfo(buffer []byte) []interface{} {
+	block := ExtSensorInfo_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleExtSensorInfo - Error decoding ExtSensorInfo block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	// Data holds the serial number followed by the hardware and firmware versions
+	info := ExtSensorInfo{
+		BlockTime:       newBlockTime(block.TOW, block.WNc),
+		Source:          connectionDescriptorString(block.Source),
+		SensorModel:     extSensorModelString(block.SensorModel),
+		SerialNumber:    cString(block.Data[:EXTSENSORSERIALNBR_LENGTH]),
+		HardwareVersion: cString(block.Data[EXTSENSORSERIALNBR_LENGTH : EXTSENSORSERIALNBR_LENGTH+EXTSENSORVERSION_LENGTH]),
+		FirmwareVersion: cString(block.Data[EXTSENSORSERIALNBR_LENGTH+EXTSENSORVERSION_LENGTH:]),
+	}
+
+	return []interface{}{Payload{Topic: extSensorInfoTopic, Data: info}}
+}
@@ -0,0 +1,233 @@
+package sbf
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+/**
+ * Decoding of ReceiverStatus into a receiver health object. Every RxState,
+ * RxError and ExtError bit is expanded into a named flag and the AGC gain of
+ * every frontend is tracked over the last blocks, as a sudden gain drop is an
+ * early sign of RF interference.
+ */
+
+const healthTopic = "health"
+const healthAlarmTopic = "health/alarm"
+
+/* Offset of the first AGCState sub-block in ReceiverStatus_2 */
+const agcStateSubBlockOffset = 32
+
+/* Number of ReceiverStatus blocks the AGC gain statistics are computed over */
+const agcHistoryLength = 60
+
+// ReceiverHealth is the overall status of the receiver
+type ReceiverHealth struct {
+	BlockTime
+	CPULoad      *uint8          `json:"cpuLoad,omitempty"`     // %
+	UpTime       uint32          `json:"upTime"`                // s
+	Temperature  *float64        `json:"temperature,omitempty"` // °C
+	CmdCount     uint8           `json:"cmdCount"`
+	RxState      map[string]bool `json:"rxState"`
+	RxError      map[string]bool `json:"rxError"`
+	ExtError     map[string]bool `json:"extError"`
+	AntennaOpen  bool            `json:"antennaOpen"`  // No current drawn by the active antenna
+	AntennaShort bool            `json:"antennaShort"` // Antenna overcurrent
+	Errors       []string        `json:"errors"`       // Names of the RxError and ExtError bits that are set
+	AGC          []FrontendAGC   `json:"agc"`
+}
+
+// FrontendAGC is the AGC state of one frontend, with its gain statistics over
+// the last agcHistoryLength blocks
+type FrontendAGC struct {
+	Frontend     string   `json:"frontend"`
+	Antenna      uint8    `json:"antenna"`
+	Gain         *float64 `json:"gain,omitempty"` // dB
+	SampleVar    uint8    `json:"sampleVar"`
+	BlankingStat uint8    `json:"blankingStat"`       // %
+	MeanGain     *float64 `json:"meanGain,omitempty"` // dB
+	MinGain      *float64 `json:"minGain,omitempty"`  // dB
+	MaxGain      *float64 `json:"maxGain,omitempty"`  // dB
+	GainDrop     *float64 `json:"gainDrop,omitempty"` // dB, mean gain before this block minus the current gain
+}
+
+var rxStateBits = map[uint]string{
+	1:  "activeAntenna",
+	2:  "extFreq",
+	3:  "extTime",
+	4:  "wnSet",
+	5:  "towSet",
+	6:  "fineTime",
+	7:  "internalDiskActivity",
+	8:  "internalDiskFull",
+	9:  "internalDiskMounted",
+	10: "intAnt",
+	11: "refOutLocked",
+	13: "externalDiskActivity",
+	14: "externalDiskFull",
+	15: "externalDiskMounted",
+	16: "ppsInCal",
+	17: "diffCorrIn",
+	18: "internet",
+}
+
+var rxErrorBits = map[uint]string{
+	3:  "software",
+	4:  "watchdog",
+	5:  "antenna",
+	6:  "congestion",
+	8:  "missedEvent",
+	9:  "cpuOverload",
+	10: "invalidConfig",
+	11: "outOfGeofence",
+}
+
+var extErrorBits = map[uint]string{
+	0: "sisError",
+	1: "diffCorrError",
+	2: "extSensorError",
+	3: "setupError",
+}
+
+var frontends = map[uint8]string{
+	FRONTENDID_GPSGALL1:    "GPSL1/E1",
+	FRONTENDID_GLOL1:       "GLOL1",
+	FRONTENDID_GALE6:       "E6",
+	FRONTENDID_GPSL2:       "GPSL2",
+	FRONTENDID_GLOL2:       "GLOL2",
+	FRONTENDID_GPSGALL5E5a: "L5/E5a",
+	FRONTENDID_GALE5b:      "E5b",
+	FRONTENDID_GALE5ab:     "E5(a+b)",
+	FRONTENDID_GPSGLOGALL1: "GPS/GLO/GAL L1",
+	FRONTENDID_GPSGLOL2:    "GPS/GLO L2",
+	FRONTENDID_LBAND:       "L-band",
+	FRONTENDID_CMPB1:       "B1",
+	FRONTENDID_CMPB3:       "B3",
+	FRONTENDID_SBAND:       "S-band",
+}
+
+// bitFlags expands the named bits of a bitfield into flags
+func bitFlags(bits map[uint]string, value uint32) map[string]bool {
+	flags := map[string]bool{}
+	for bit, name := range bits {
+		flags[name] = value&(1<<bit) != 0
+	}
+	return flags
+}
+
+func handleReceiverStatus2(buffer []byte) []interface{} {
+	block := ReceiverStatus_2_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleReceiverStatus2 - Error decoding ReceiverStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	revision := block.Header.ID >> 13
+
+	health := ReceiverHealth{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		UpTime:    block.UpTime,
+		CmdCount:  block.CmdCount,
+		RxState:   bitFlags(rxStateBits, block.RxStatus),
+		RxError:   bitFlags(rxErrorBits, block.RxError),
+		ExtError:  bitFlags(extErrorBits, uint32(block.ExtError)),
+		Errors:    []string{},
+		AGC:       []FrontendAGC{},
+	}
+	if health.Time != nil {
+		boot := health.Time.Add(-time.Duration(block.UpTime) * time.Second)
+		state.receiverBootTime = &boot
+	}
+	if block.CPULoad != 255 {
+		cpuLoad := block.CPULoad
+		health.CPULoad = &cpuLoad
+	}
+	if revision >= 1 && block.Temperature != 0 {
+		temperature := float64(block.Temperature) - 100
+		health.Temperature = &temperature
+	}
+	health.AntennaOpen = !health.RxState["activeAntenna"]
+	health.AntennaShort = health.RxError["antenna"]
+	for bit := uint(0); bit < 32; bit++ {
+		if block.RxError&(1<<bit) == 0 {
+			continue
+		}
+		if name, ok := rxErrorBits[bit]; ok {
+			health.Errors = append(health.Errors, name)
+		} else {
+			health.Errors = append(health.Errors, fmt.Sprintf("rxErrorBit%d", bit))
+		}
+	}
+	for bit := uint(0); bit < 8; bit++ {
+		if name, ok := extErrorBits[bit]; ok && block.ExtError&(1<<bit) != 0 {
+			health.Errors = append(health.Errors, name)
+		}
+	}
+
+	payloads := []interface{}{}
+	for i := 0; i < int(block.N); i++ {
+		sb := AGCState_2_1_t{}
+		if !decodeSubBlock(buffer, agcStateSubBlockOffset+i*int(block.SBSize), &sb) {
+			log.Printf("[ERROR] handleReceiverStatus2 - ReceiverStatus block too short for %d AGC states\n", block.N)
+			break
+		}
+		agc := FrontendAGC{
+			Frontend:     mapString(frontends, sb.FrontendID&0x1F),
+			Antenna:      sb.FrontendID >> 5,
+			Gain:         optScaled(int64(sb.Gain), -128, 1),
+			SampleVar:    sb.SampleVar,
+			BlankingStat: sb.BlankingStat,
+		}
+		if agc.Gain != nil {
+			payloads = append(payloads, updateAGCHistory(&agc, health.BlockTime)...)
+		}
+		health.AGC = append(health.AGC, agc)
+	}
+
+	return append([]interface{}{Payload{Topic: healthTopic, Data: health}}, payloads...)
+}
+
+// updateAGCHistory adds the gain of a frontend to its history, fills in the
+// gain statistics and checks the gain drop against settings.AGCDropLimit
+func updateAGCHistory(agc *FrontendAGC, blockTime BlockTime) []interface{} {
+	key := fmt.Sprintf("%s/%d", agc.Frontend, agc.Antenna)
+	history := state.agcHistory[key]
+
+	if len(history) > 0 {
+		mean := 0.0
+		for _, gain := range history {
+			mean += gain
+		}
+		mean /= float64(len(history))
+		drop := mean - *agc.Gain
+		agc.GainDrop = &drop
+	}
+
+	history = append(history, *agc.Gain)
+	if len(history) > agcHistoryLength {
+		history = history[len(history)-agcHistoryLength:]
+	}
+	state.agcHistory[key] = history
+
+	mean, min, max := 0.0, math.Inf(1), math.Inf(-1)
+	for _, gain := range history {
+		mean += gain
+		min = math.Min(min, gain)
+		max = math.Max(max, gain)
+	}
+	mean /= float64(len(history))
+	agc.MeanGain, agc.MinGain, agc.MaxGain = &mean, &min, &max
+
+	if settings.AGCDropLimit <= 0 || agc.GainDrop == nil {
+		return []interface{}{}
+	}
+	return updateAlarm(healthAlarmTopic, Alarm{
+		BlockTime: blockTime,
+		Alarm:     "agcDrop/" + key,
+		Active:    *agc.GainDrop >= settings.AGCDropLimit,
+		Message:   fmt.Sprintf("%s antenna %d AGC gain %.0f dB, %.1f dB below its mean", agc.Frontend, agc.Antenna, *agc.Gain, *agc.GainDrop),
+		Value:     agc.GainDrop,
+		Limit:     floatPtr(settings.AGCDropLimit),
+	})
+}
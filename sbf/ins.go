@@ -0,0 +1,573 @@
+package sbf
+
+import (
+	"encoding/binary"
+	"log"
+)
+
+/**
+ * Decoding of the INS/GNSS integrated blocks. All navigation blocks are
+ * published in the same INSNavigation shape so consumers get a single fused
+ * navigation stream, whichever blocks the receiver is configured to output.
+ */
+
+const insNavigationTopic = "ins/navigation"
+const insCovarianceTopic = "ins/covariance"
+const insBiasTopic = "ins/bias"
+const insSetupTopic = "ins/setup"
+
+/* Values for INSNavigation.Aiding */
+const insAidingNone = "none"
+const insAidingINSOnly = "insOnly"
+const insAidingGNSSAided = "gnssAided"
+const insAidingGNSSOnly = "gnssOnly"
+
+/* INSNavCart/INSNavGeod Info bits not defined in constants.go */
+const insNavInfoGNSSPosUsed = 0x0100 /* Bit 8 */
+const insNavInfoGNSSVelUsed = 0x0200 /* Bit 9 */
+const insNavInfoGNSSAttUsed = 0x0400 /* Bit 10 */
+
+/* Offset of the first sub-block in INSNavCart and INSNavGeod */
+const insNavCartSubBlockOffset = 52
+const insNavGeodSubBlockOffset = 56
+
+// ENU is a vector in the local East/North/Up frame
+type ENU struct {
+	East  *float64 `json:"east,omitempty"`
+	North *float64 `json:"north,omitempty"`
+	Up    *float64 `json:"up,omitempty"`
+}
+
+// XYZ is a vector in the ECEF or vehicle frame
+type XYZ struct {
+	X *float64 `json:"x,omitempty"`
+	Y *float64 `json:"y,omitempty"`
+	Z *float64 `json:"z,omitempty"`
+}
+
+// Euler holds heading, pitch and roll, in degrees or degrees/s
+type Euler struct {
+	Heading *float64 `json:"heading,omitempty"`
+	Pitch   *float64 `json:"pitch,omitempty"`
+	Roll    *float64 `json:"roll,omitempty"`
+}
+
+// GeodeticStdDev holds position standard deviations in meters
+type GeodeticStdDev struct {
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Height    *float64 `json:"height,omitempty"`
+}
+
+// INSAlignment reports the progress of the INS alignment and calibration
+type INSAlignment struct {
+	State   string   `json:"state"`             // aligned, staticAlignment, inMotionAlignment, calibration, waiting or notAvailable
+	Elapsed *float64 `json:"elapsed,omitempty"` // Seconds spent in the current state while not aligned
+}
+
+// INSNavigation is the fused INS/GNSS navigation solution
+type INSNavigation struct {
+	BlockTime
+	Block     string       `json:"block"`
+	Aiding    string       `json:"aiding"`
+	Mode      string       `json:"mode,omitempty"` // Integration mode, only reported by the Int* blocks
+	GNSSMode  string       `json:"gnssMode"`
+	Error     string       `json:"error,omitempty"`
+	Alignment INSAlignment `json:"alignment"`
+	GNSSAge   *float64     `json:"gnssAge,omitempty"` // s
+	NrSV      *int         `json:"nrSV,omitempty"`
+	Datum     uint8        `json:"datum"`
+
+	Latitude     *float64 `json:"latitude,omitempty"`     // deg
+	Longitude    *float64 `json:"longitude,omitempty"`    // deg
+	Height       *float64 `json:"height,omitempty"`       // m, ellipsoidal
+	Undulation   *float64 `json:"undulation,omitempty"`   // m
+	PositionECEF *XYZ     `json:"positionEcef,omitempty"` // m
+	Velocity     *ENU     `json:"velocity,omitempty"`     // m/s
+	VelocityECEF *XYZ     `json:"velocityEcef,omitempty"` // m/s
+	Acceleration *XYZ     `json:"acceleration,omitempty"` // m/s², vehicle frame
+	COG          *float64 `json:"cog,omitempty"`          // deg
+	Attitude     *Euler   `json:"attitude,omitempty"`     // deg
+	AttitudeRate *Euler   `json:"attitudeRate,omitempty"` // deg/s
+
+	PositionStdDev     *GeodeticStdDev    `json:"positionStdDev,omitempty"`
+	PositionStdDevECEF *XYZ               `json:"positionStdDevEcef,omitempty"`
+	VelocityStdDev     *ENU               `json:"velocityStdDev,omitempty"`
+	VelocityStdDevECEF *XYZ               `json:"velocityStdDevEcef,omitempty"`
+	AttitudeStdDev     *Euler             `json:"attitudeStdDev,omitempty"`
+	Covariance         map[string]float64 `json:"covariance,omitempty"`
+
+	Accuracy *float64 `json:"accuracy,omitempty"` // m
+	Latency  *float64 `json:"latency,omitempty"`  // s
+}
+
+// INSCovariance is the integrated position covariance from IntPosCovGeod, in m²
+type INSCovariance struct {
+	BlockTime
+	Mode       string             `json:"mode"`
+	Error      string             `json:"error,omitempty"`
+	Covariance map[string]float64 `json:"covariance,omitempty"`
+}
+
+// IMUBias holds the IMU biases estimated by the integration filter
+type IMUBias struct {
+	BlockTime
+	Error                   string   `json:"error,omitempty"`
+	GNSSAge                 *float64 `json:"gnssAge,omitempty"` // s
+	AccelerometerBias       XYZ      `json:"accelerometerBias"` // m/s²
+	GyroscopeBias           XYZ      `json:"gyroscopeBias"`     // deg/s
+	AccelerometerBiasStdDev XYZ      `json:"accelerometerBiasStdDev"`
+	GyroscopeBiasStdDev     XYZ      `json:"gyroscopeBiasStdDev"`
+}
+
+// IMUSetup describes how the IMU is mounted
+type IMUSetup struct {
+	BlockTime
+	SerialPort      uint8 `json:"serialPort"`
+	AntennaLeverArm XYZ   `json:"antennaLeverArm"` // m
+	Orientation     XYZ   `json:"orientation"`     // deg
+}
+
+var intPVAModes = map[uint8]string{
+	MODEINTPVA_NO_PVA_AVAILABLE:      "noPVA",
+	MODEINTPVA_EXTRAP_EXTSENSORS:     "extrapolatedExtSensors",
+	MODEINTPVA_LOOSELY_INTEGRATED:    "looselyIntegrated",
+	MODEINTPVA_TIGHTLY_INTEGRATED:    "tightlyIntegrated",
+	MODEINTPVA_GNSSONLY_EXTRAPOLATED: "gnssOnlyExtrapolated",
+}
+
+// insAlignment maps the PVT error of an integrated block to an alignment state
+// and tracks how long the receiver has been in that state
+func insAlignment(tow uint32, wnc uint16, pvtError uint8) INSAlignment {
+	alignment := INSAlignment{}
+	switch pvtError {
+	case SBF_PVTERR_NONE:
+		alignment.State = "aligned"
+	case SBF_PVTERR_ALIGNMENTNOTREADY:
+		alignment.State = "staticAlignment"
+	case SBF_PVTERR_INMOTIONALIGNMENTNOTREADY:
+		alignment.State = "inMotionAlignment"
+	case SBF_PVTERR_CALIBRATIONNOTREADY:
+		alignment.State = "calibration"
+	case SBF_PVTERR_WAITINGFORGNSSPVT, SBF_PVTERR_WAITINGFORGNSSHEAD, SBF_PVTERR_WAITINGFORPPSSYNC, SBF_PVTERR_NOTENOUGHEXTSENSORMEAS:
+		alignment.State = "waiting"
+	default:
+		alignment.State = "notAvailable"
+	}
+
+	now, ok := gpsTime(tow, wnc)
+	if !ok {
+		return alignment
+	}
+	if alignment.State != state.insAlignmentState {
+		state.insAlignmentState = alignment.State
+		state.insAlignmentSince = now
+	}
+	if alignment.State != "aligned" {
+		elapsed := now.Sub(state.insAlignmentSince).Seconds()
+		alignment.Elapsed = &elapsed
+	}
+	return alignment
+}
+
+func newINSNavigation(block string, tow uint32, wnc uint16, pvtError uint8) INSNavigation {
+	return INSNavigation{
+		BlockTime: newBlockTime(tow, wnc),
+		Block:     block,
+		Error:     pvtErrorString(pvtError),
+		Alignment: insAlignment(tow, wnc, pvtError),
+	}
+}
+
+func insNavAiding(pvtError uint8, info uint16) string {
+	if pvtError != SBF_PVTERR_NONE {
+		return insAidingNone
+	}
+	if info&(insNavInfoGNSSPosUsed|insNavInfoGNSSVelUsed|insNavInfoGNSSAttUsed) != 0 {
+		return insAidingGNSSAided
+	}
+	return insAidingINSOnly
+}
+
+func intPVAAiding(mode uint8, info uint16) string {
+	switch mode {
+	case MODEINTPVA_NO_PVA_AVAILABLE:
+		return insAidingNone
+	case MODEINTPVA_GNSSONLY_EXTRAPOLATED:
+		return insAidingGNSSOnly
+	case MODEINTPVA_EXTRAP_EXTSENSORS:
+		return insAidingINSOnly
+	}
+	if info&(INTPVA_INFO_GNSSPOSUSED|INTPVA_INFO_GNSSVELUSED|INTPVA_INFO_GNSSATTUSED) != 0 {
+		return insAidingGNSSAided
+	}
+	return insAidingINSOnly
+}
+
+func intPVAModeString(mode uint8) string {
+	if name, ok := intPVAModes[mode]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func optNrSV(nrSV uint8) *int {
+	if nrSV == 255 {
+		return nil
+	}
+	n := int(nrSV)
+	return &n
+}
+
+func handleINSNavGeod(buffer []byte) []interface{} {
+	block := INSNavGeod_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleINSNavGeod - Error decoding INSNavGeod block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := newINSNavigation("INSNavGeod", block.TOW, block.WNc, block.Error)
+	nav.Aiding = insNavAiding(block.Error, block.Info)
+	nav.GNSSMode = pvtModeString(block.GNSSMode)
+	nav.GNSSAge = optScaled(int64(block.GNSSAge), 65535, 0.01)
+	nav.Datum = block.Datum
+	nav.Latitude = radToDeg(optDouble(block.Latitude))
+	nav.Longitude = radToDeg(optDouble(block.Longitude))
+	nav.Height = optDouble(block.Height)
+	nav.Undulation = optFloat(block.Undulation)
+	nav.Accuracy = optScaled(int64(block.Accuracy), 65535, 0.01)
+	nav.Latency = optScaled(int64(block.Latency), 65535, 0.0001)
+
+	// The sub-blocks selected by SBList follow the header in bit order
+	offset := insNavGeodSubBlockOffset
+	for bit := uint16(INSNAV_SBLIST_POSITION_STTDEV); bit <= INSNAV_SBLIST_VELOCITY_COV; bit <<= 1 {
+		if block.SBList&bit == 0 {
+			continue
+		}
+		ok := true
+		switch bit {
+		case INSNAV_SBLIST_POSITION_STTDEV:
+			sb := INSNavGeodPosStdDev_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.PositionStdDev = &GeodeticStdDev{optFloat(sb.LatitudeStdDev), optFloat(sb.LongitudeStdDev), optFloat(sb.HeightStdDev)}
+			}
+		case INSNAV_SBLIST_ATTITDE:
+			sb := INSNavGeodAtt_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.Attitude = &Euler{optFloat(sb.Heading), optFloat(sb.Pitch), optFloat(sb.Roll)}
+			}
+		case INSNAV_SBLIST_ATTITDE_STTDEV:
+			sb := INSNavGeodAttStdDev_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.AttitudeStdDev = &Euler{optFloat(sb.HeadingStdDev), optFloat(sb.PitchStdDev), optFloat(sb.RollStdDev)}
+			}
+		case INSNAV_SBLIST_VELOCITY:
+			sb := INSNavGeodVel_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.Velocity = &ENU{optFloat(sb.Ve), optFloat(sb.Vn), optFloat(sb.Vu)}
+			}
+		case INSNAV_SBLIST_VELOCITY_STTDEV:
+			sb := INSNavGeodVelStdDev_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.VelocityStdDev = &ENU{optFloat(sb.VeStdDev), optFloat(sb.VnStdDev), optFloat(sb.VuStdDev)}
+			}
+		case INSNAV_SBLIST_POSITION_COV:
+			sb := INSNavGeodPosCov_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				addCovariance(&nav.Covariance, map[string]float32{
+					"latitudeLongitude": sb.LatitudeLongitudeCov,
+					"latitudeHeight":    sb.LatitudeHeightCov,
+					"longitudeHeight":   sb.LongitudeHeightCov,
+				})
+			}
+		case INSNAV_SBLIST_ATTITDE_COV:
+			sb := INSNavGeodAttCov_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				addCovariance(&nav.Covariance, map[string]float32{
+					"headingPitch": sb.HeadingPitchCov,
+					"headingRoll":  sb.HeadingRollCov,
+					"pitchRoll":    sb.PitchRollCov,
+				})
+			}
+		case INSNAV_SBLIST_VELOCITY_COV:
+			sb := INSNavGeodVelCov_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				addCovariance(&nav.Covariance, map[string]float32{
+					"veVn": sb.VeVnCov,
+					"veVu": sb.VeVuCov,
+					"vnVu": sb.VnVuCov,
+				})
+			}
+		}
+		if !ok {
+			log.Printf("[ERROR] handleINSNavGeod - INSNavGeod block too short for sub-block list 0x%04x\n", block.SBList)
+			break
+		}
+		offset += binary.Size(INSNavGeodAtt_1_0_t{})
+	}
+
+	return []interface{}{Payload{Topic: insNavigationTopic, Data: nav}}
+}
+
+func handleINSNavCart(buffer []byte) []interface{} {
+	block := INSNavCart_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleINSNavCart - Error decoding INSNavCart block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := newINSNavigation("INSNavCart", block.TOW, block.WNc, block.Error)
+	nav.Aiding = insNavAiding(block.Error, block.Info)
+	nav.GNSSMode = pvtModeString(block.GNSSMode)
+	nav.GNSSAge = optScaled(int64(block.GNSSAge), 65535, 0.01)
+	nav.Datum = block.Datum
+	nav.PositionECEF = &XYZ{optDouble(block.X), optDouble(block.Y), optDouble(block.Z)}
+	nav.Accuracy = optScaled(int64(block.Accuracy), 65535, 0.01)
+	nav.Latency = optScaled(int64(block.Latency), 65535, 0.0001)
+
+	// The sub-blocks selected by SBList follow the header in bit order
+	offset := insNavCartSubBlockOffset
+	for bit := uint16(INSNAV_SBLIST_POSITION_STTDEV); bit <= INSNAV_SBLIST_VELOCITY_COV; bit <<= 1 {
+		if block.SBList&bit == 0 {
+			continue
+		}
+		ok := true
+		switch bit {
+		case INSNAV_SBLIST_POSITION_STTDEV:
+			sb := INSNavCartPosStdDev_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.PositionStdDevECEF = &XYZ{optFloat(sb.XStdDev), optFloat(sb.YStdDev), optFloat(sb.ZStdDev)}
+			}
+		case INSNAV_SBLIST_ATTITDE:
+			sb := INSNavCartAtt_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.Attitude = &Euler{optFloat(sb.Heading), optFloat(sb.Pitch), optFloat(sb.Roll)}
+			}
+		case INSNAV_SBLIST_ATTITDE_STTDEV:
+			sb := INSNavCartAttStdDev_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.AttitudeStdDev = &Euler{optFloat(sb.HeadingStdDev), optFloat(sb.PitchStdDev), optFloat(sb.RollStdDev)}
+			}
+		case INSNAV_SBLIST_VELOCITY:
+			sb := INSNavCartVel_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.VelocityECEF = &XYZ{optFloat(sb.Vx), optFloat(sb.Vy), optFloat(sb.Vz)}
+			}
+		case INSNAV_SBLIST_VELOCITY_STTDEV:
+			sb := INSNavCartVelStdDev_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				nav.VelocityStdDevECEF = &XYZ{optFloat(sb.VxStdDev), optFloat(sb.VyStdDev), optFloat(sb.VzStdDev)}
+			}
+		case INSNAV_SBLIST_POSITION_COV:
+			sb := INSNavCartPosCov_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				addCovariance(&nav.Covariance, map[string]float32{
+					"xy": sb.XYCov,
+					"xz": sb.XZCov,
+					"yz": sb.YZCov,
+				})
+			}
+		case INSNAV_SBLIST_ATTITDE_COV:
+			sb := INSNavCartAttCov_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				addCovariance(&nav.Covariance, map[string]float32{
+					"headingPitch": sb.HeadingPitchCov,
+					"headingRoll":  sb.HeadingRollCov,
+					"pitchRoll":    sb.PitchRollCov,
+				})
+			}
+		case INSNAV_SBLIST_VELOCITY_COV:
+			sb := INSNavCartVelCov_1_0_t{}
+			if ok = decodeSubBlock(buffer, offset, &sb); ok {
+				addCovariance(&nav.Covariance, map[string]float32{
+					"vxVy": sb.VxVyCov,
+					"vxVz": sb.VxVzCov,
+					"vyVz": sb.VyVzCov,
+				})
+			}
+		}
+		if !ok {
+			log.Printf("[ERROR] handleINSNavCart - INSNavCart block too short for sub-block list 0x%04x\n", block.SBList)
+			break
+		}
+		offset += binary.Size(INSNavCartAtt_1_0_t{})
+	}
+
+	return []interface{}{Payload{Topic: insNavigationTopic, Data: nav}}
+}
+
+func handleIntPVCart(buffer []byte) []interface{} {
+	block := IntPVCart_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleIntPVCart - Error decoding IntPVCart block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := newINSNavigation("IntPVCart", block.TOW, block.WNc, block.Error)
+	nav.Aiding = intPVAAiding(block.Mode, block.Info)
+	nav.Mode = intPVAModeString(block.Mode)
+	nav.GNSSMode = pvtModeString(block.GNSSPVTMode)
+	nav.GNSSAge = optScaled(int64(block.GNSSage), 65535, 0.01)
+	nav.NrSV = optNrSV(block.NrSV)
+	nav.Datum = block.Datum
+	nav.PositionECEF = &XYZ{optDouble(block.X), optDouble(block.Y), optDouble(block.Z)}
+	nav.VelocityECEF = &XYZ{optFloat(block.Vx), optFloat(block.Vy), optFloat(block.Vz)}
+	nav.COG = optFloat(block.COG)
+
+	return []interface{}{Payload{Topic: insNavigationTopic, Data: nav}}
+}
+
+func handleIntPVGeod(buffer []byte) []interface{} {
+	block := IntPVGeod_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleIntPVGeod - Error decoding IntPVGeod block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := newINSNavigation("IntPVGeod", block.TOW, block.WNc, block.Error)
+	nav.Aiding = intPVAAiding(block.Mode, block.Info)
+	nav.Mode = intPVAModeString(block.Mode)
+	nav.GNSSMode = pvtModeString(block.GNSSPVTMode)
+	nav.GNSSAge = optScaled(int64(block.GNSSage), 65535, 0.01)
+	nav.NrSV = optNrSV(block.NrSV)
+	nav.Datum = block.Datum
+	nav.Latitude = radToDeg(optDouble(block.Lat))
+	nav.Longitude = radToDeg(optDouble(block.Lon))
+	nav.Height = optDouble(block.Alt)
+	nav.Velocity = &ENU{optFloat(block.Ve), optFloat(block.Vn), optFloat(block.Vu)}
+	nav.COG = optFloat(block.COG)
+
+	return []interface{}{Payload{Topic: insNavigationTopic, Data: nav}}
+}
+
+func handleIntPVAAGeod(buffer []byte) []interface{} {
+	block := IntPVAAGeod_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleIntPVAAGeod - Error decoding IntPVAAGeod block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := newINSNavigation("IntPVAAGeod", block.TOW, block.WNc, block.Error)
+	nav.Aiding = intPVAAiding(block.Mode, block.Info)
+	nav.Mode = intPVAModeString(block.Mode)
+	nav.GNSSMode = pvtModeString(block.GNSSPVTMode)
+	nav.GNSSAge = optScaled(int64(block.GNSSage), 255, 1)
+	nav.NrSV = optNrSV(block.NrSVAnt & 0x3F)
+	nav.Datum = block.Datum
+	nav.Latitude = optScaled(int64(block.Lat), -2147483648, 1e-7)
+	nav.Longitude = optScaled(int64(block.Lon), -2147483648, 1e-7)
+	nav.Height = optScaled(int64(block.Alt), -2147483648, 0.001)
+	nav.Velocity = &ENU{
+		optScaled(int64(block.Ve), -2147483648, 0.001),
+		optScaled(int64(block.Vn), -2147483648, 0.001),
+		optScaled(int64(block.Vu), -2147483648, 0.001),
+	}
+	nav.Acceleration = &XYZ{
+		optScaled(int64(block.Ax), -32768, 0.01),
+		optScaled(int64(block.Ay), -32768, 0.01),
+		optScaled(int64(block.Az), -32768, 0.01),
+	}
+	nav.Attitude = &Euler{
+		optScaled(int64(block.Heading), 65535, 0.01),
+		optScaled(int64(block.Pitch), -32768, 0.01),
+		optScaled(int64(block.Roll), -32768, 0.01),
+	}
+
+	return []interface{}{Payload{Topic: insNavigationTopic, Data: nav}}
+}
+
+func handleIntAttEuler(buffer []byte) []interface{} {
+	// Revision 0 has a reserved byte where revision 1 reports the GNSS PVT mode
+	block := IntAttEuler_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleIntAttEuler - Error decoding IntAttEuler block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := newINSNavigation("IntAttEuler", block.TOW, block.WNc, block.Error)
+	nav.Aiding = intPVAAiding(block.Mode, block.Info)
+	nav.Mode = intPVAModeString(block.Mode)
+	if SBF_ID_TO_REV(block.Header.ID) >= 1 {
+		nav.GNSSMode = pvtModeString(block.GNSSPVTMode)
+	}
+	nav.GNSSAge = optScaled(int64(block.GNSSage), 65535, 0.01)
+	nav.NrSV = optNrSV(block.NrSV)
+	nav.Datum = block.Datum
+	nav.Attitude = &Euler{optFloat(block.Heading), optFloat(block.Pitch), optFloat(block.Roll)}
+	nav.AttitudeRate = &Euler{optFloat(block.HeadingDot), optFloat(block.PitchDot), optFloat(block.RollDot)}
+
+	return []interface{}{Payload{Topic: insNavigationTopic, Data: nav}}
+}
+
+func handleIntPosCovGeod(buffer []byte) []interface{} {
+	block := IntPosCovGeod_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleIntPosCovGeod - Error decoding IntPosCovGeod block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	cov := INSCovariance{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Mode:      intPVAModeString(block.Mode),
+		Error:     pvtErrorString(block.Error),
+	}
+	addCovariance(&cov.Covariance, map[string]float32{
+		"latLat": block.Cov_LatLat,
+		"lonLon": block.Cov_LonLon,
+		"altAlt": block.Cov_AltAlt,
+		"latLon": block.Cov_LatLon,
+		"latAlt": block.Cov_LatAlt,
+		"lonAlt": block.Cov_LonAlt,
+	})
+
+	return []interface{}{Payload{Topic: insCovarianceTopic, Data: cov}}
+}
+
+func handleIMUBias(buffer []byte) []interface{} {
+	block := IMUBias_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleIMUBias - Error decoding IMUBias block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	bias := IMUBias{
+		BlockTime:               newBlockTime(block.TOW, block.WNc),
+		Error:                   pvtErrorString(block.Error),
+		GNSSAge:                 optScaled(int64(block.GNSSAge), 65535, 0.01),
+		AccelerometerBias:       XYZ{optFloat(block.XAccBias), optFloat(block.YAccBias), optFloat(block.ZAccBias)},
+		GyroscopeBias:           XYZ{optFloat(block.XGyroBias), optFloat(block.YGyroBias), optFloat(block.ZGyroBias)},
+		AccelerometerBiasStdDev: XYZ{optFloat(block.StdXAccBias), optFloat(block.StdYAccBias), optFloat(block.StdZAccBias)},
+		GyroscopeBiasStdDev:     XYZ{optFloat(block.StdXGyroBias), optFloat(block.StdYGyroBias), optFloat(block.StdZGyroBias)},
+	}
+
+	return []interface{}{Payload{Topic: insBiasTopic, Data: bias}}
+}
+
+func handleIMUSetup(buffer []byte) []interface{} {
+	block := IMUSetup_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleIMUSetup - Error decoding IMUSetup block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	setup := IMUSetup{
+		BlockTime:       newBlockTime(block.TOW, block.WNc),
+		SerialPort:      block.SerialPort,
+		AntennaLeverArm: XYZ{optFloat(block.AntLeverArmX), optFloat(block.AntLeverArmY), optFloat(block.AntLeverArmZ)},
+		Orientation:     XYZ{optFloat(block.ThetaX), optFloat(block.ThetaY), optFloat(block.ThetaZ)},
+	}
+
+	return []interface{}{Payload{Topic: insSetupTopic, Data: setup}}
+}
+
+// addCovariance adds the valid covariance terms to a covariance map
+func addCovariance(covariance *map[string]float64, terms map[string]float32) {
+	for name, value := range terms {
+		if v := optFloat(value); v != nil {
+			if *covariance == nil {
+				*covariance = map[string]float64{}
+			}
+			(*covariance)[name] = *v
+		}
+	}
+}
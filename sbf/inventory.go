@@ -0,0 +1,213 @@
+package sbf
+
+import (
+	"log"
+	"net"
+	"reflect"
+)
+
+/**
+ * Receiver inventory built from ReceiverSetup and RxComponents: marker,
+ * receiver and antenna identification, firmware versions and the hardware
+ * components. The inventory is kept for the whole session and only published
+ * when a block changes it, so the first ReceiverSetup after connecting and
+ * every later configuration change are published once. RxMessage blocks are
+ * published as they come.
+ */
+
+const inventoryTopic = "inventory"
+const inventoryMessagesTopic = "inventory/messages"
+
+/* Offsets of the RxComponents sub-blocks and of the RxMessage text */
+const rxComponentsSubBlockOffset = 20
+const rxMessageTextOffset = 24
+
+// ReceiverInventory identifies the receiver, its antenna and the site
+type ReceiverInventory struct {
+	BlockTime
+	MarkerName          string              `json:"markerName"`
+	MarkerNumber        string              `json:"markerNumber"`
+	MarkerType          string              `json:"markerType,omitempty"`
+	Observer            string              `json:"observer"`
+	Agency              string              `json:"agency"`
+	StationCode         string              `json:"stationCode,omitempty"`
+	CountryCode         string              `json:"countryCode,omitempty"`
+	MonumentIdx         *uint8              `json:"monumentIdx,omitempty"`
+	ReceiverIdx         *uint8              `json:"receiverIdx,omitempty"`
+	ReceiverSerial      string              `json:"receiverSerial"`
+	ReceiverName        string              `json:"receiverName"`
+	ReceiverVersion     string              `json:"receiverVersion"`
+	GNSSFirmwareVersion string              `json:"gnssFirmwareVersion,omitempty"`
+	ProductName         string              `json:"productName,omitempty"`
+	AntennaSerial       string              `json:"antennaSerial"`
+	AntennaType         string              `json:"antennaType"`
+	AntennaOffset       *AntennaOffset      `json:"antennaOffset,omitempty"`
+	ApproxPosition      *ApproxPosition     `json:"approxPosition,omitempty"`
+	Components          []ReceiverComponent `json:"components"`
+}
+
+// AntennaOffset is the offset of the antenna reference point from the marker
+type AntennaOffset struct {
+	DeltaH *float64 `json:"deltaH,omitempty"` // m
+	DeltaE *float64 `json:"deltaE,omitempty"` // m
+	DeltaN *float64 `json:"deltaN,omitempty"` // m
+}
+
+// ApproxPosition is the approximate position of the marker
+type ApproxPosition struct {
+	Latitude  *float64 `json:"latitude,omitempty"`  // °
+	Longitude *float64 `json:"longitude,omitempty"` // °
+	Height    *float64 `json:"height,omitempty"`    // m, ellipsoidal
+}
+
+// ReceiverComponent is a hardware component of the receiver
+type ReceiverComponent struct {
+	Type            uint8  `json:"type"`
+	Name            string `json:"name"`
+	SerialNumber    string `json:"serialNumber"`
+	FirmwareVersion string `json:"firmwareVersion"`
+	MACAddress      string `json:"macAddress,omitempty"`
+	CPULoad         *uint8 `json:"cpuLoad,omitempty"` // %
+}
+
+// ReceiverMessage is a message logged by the receiver
+type ReceiverMessage struct {
+	BlockTime
+	Type      uint8  `json:"type"`
+	Severity  string `json:"severity"`
+	MessageID uint32 `json:"messageId"`
+	Message   string `json:"message"`
+}
+
+var rxMessageSeverities = map[uint8]string{
+	1: "error",
+	2: "warning",
+	3: "info",
+}
+
+func handleReceiverSetup(buffer []byte) []interface{} {
+	block := ReceiverSetup_1_4_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleReceiverSetup - Error decoding ReceiverSetup block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	revision := block.Header.ID >> 13
+	state.receiverSetupSeen = true
+
+	// The fields of later revisions are zero padded, cString leaves them empty
+	state.inventory.MarkerName = cString(block.MarkerName[:])
+	state.inventory.MarkerNumber = cString(block.MarkerNumber[:])
+	state.inventory.MarkerType = cString(block.MarkerType[:])
+	state.inventory.Observer = cString(block.Observer[:])
+	state.inventory.Agency = cString(block.Agency[:])
+	state.inventory.ReceiverSerial = cString(block.RxSerialNbr[:])
+	state.inventory.ReceiverName = cString(block.RxName[:])
+	state.inventory.ReceiverVersion = cString(block.RxVersion[:])
+	state.inventory.GNSSFirmwareVersion = cString(block.GNSSFWVersion[:])
+	state.inventory.ProductName = cString(block.ProductName[:])
+	state.inventory.AntennaSerial = cString(block.AntSerialNbr[:])
+	state.inventory.AntennaType = cString(block.AntType[:])
+	state.inventory.AntennaOffset = &AntennaOffset{
+		DeltaH: optFloat(block.DeltaH),
+		DeltaE: optFloat(block.DeltaE),
+		DeltaN: optFloat(block.DeltaN),
+	}
+	// The position, station and country codes and indices only exist from
+	// revision 4
+	state.inventory.ApproxPosition = nil
+	state.inventory.StationCode, state.inventory.CountryCode = "", ""
+	state.inventory.MonumentIdx, state.inventory.ReceiverIdx = nil, nil
+	if revision >= 4 {
+		state.inventory.ApproxPosition = &ApproxPosition{
+			Latitude:  radToDeg(optDouble(block.Latitude)),
+			Longitude: radToDeg(optDouble(block.Longitude)),
+			Height:    optFloat(block.Height),
+		}
+		state.inventory.StationCode = cString(block.StationCode[:])
+		state.inventory.CountryCode = cString(block.CountryCode[:])
+		state.inventory.MonumentIdx = &block.MonumentIdx
+		state.inventory.ReceiverIdx = &block.ReceiverIdx
+	}
+
+	return updateInventory(newBlockTime(block.TOW, block.WNc))
+}
+
+func handleRxComponents(buffer []byte) []interface{} {
+	block := RxComponents_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleRxComponents - Error decoding RxComponents block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	components := []ReceiverComponent{}
+	for i := 0; i < int(block.N); i++ {
+		sb := Component_1_0_t{}
+		if !decodeSizedSubBlock(buffer, rxComponentsSubBlockOffset+i*int(block.SBLength), int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleRxComponents - RxComponents block too short for %d components\n", block.N)
+			break
+		}
+		component := ReceiverComponent{
+			Type:            sb.Type,
+			Name:            cString(sb.Name[:]),
+			SerialNumber:    cString(sb.SerialNumber[:]),
+			FirmwareVersion: cString(sb.FWVersion[:]),
+		}
+		if sb.MACAddress != [SBF_COMPONENT_1_0_MACADDRESS_LENGTH]uint8{} {
+			component.MACAddress = net.HardwareAddr(sb.MACAddress[:]).String()
+		}
+		if sb.CPULoad != 255 {
+			component.CPULoad = &sb.CPULoad
+		}
+		components = append(components, component)
+	}
+	state.inventory.Components = components
+
+	return updateInventory(newBlockTime(block.TOW, block.WNc))
+}
+
+// updateInventory publishes the inventory when it differs from the one last
+// published. Components decoded before the first ReceiverSetup are only
+// published with it. The block time and the CPU loads of the components change with
+// every block and are left out of the comparison.
+func updateInventory(blockTime BlockTime) []interface{} {
+	if !state.receiverSetupSeen {
+		return []interface{}{}
+	}
+	current := state.inventory
+	current.BlockTime = BlockTime{}
+	current.Components = make([]ReceiverComponent, len(state.inventory.Components))
+	for i, component := range state.inventory.Components {
+		component.CPULoad = nil
+		current.Components[i] = component
+	}
+	if state.publishedInventory != nil && reflect.DeepEqual(current, *state.publishedInventory) {
+		return []interface{}{}
+	}
+	state.publishedInventory = &current
+
+	log.Printf("[INFO] updateInventory - Receiver %s (%s %s), antenna %s\n", state.inventory.ReceiverSerial, state.inventory.ReceiverName, state.inventory.ReceiverVersion, state.inventory.AntennaType)
+	state.inventory.BlockTime = blockTime
+	return []interface{}{Payload{Topic: inventoryTopic, Data: state.inventory}}
+}
+
+func handleRxMessage(buffer []byte) []interface{} {
+	block := RxMessage_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleRxMessage - Error decoding RxMessage block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	length := int(block.StringLn)
+	if rxMessageTextOffset+length > len(buffer) {
+		log.Printf("[ERROR] handleRxMessage - RxMessage block too short for a %d character message\n", block.StringLn)
+		length = len(buffer) - rxMessageTextOffset
+	}
+
+	return []interface{}{Payload{Topic: inventoryMessagesTopic, Data: ReceiverMessage{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Type:      block.Type,
+		Severity:  mapString(rxMessageSeverities, block.Severity),
+		MessageID: block.MessageID,
+		Message:   cString(buffer[rxMessageTextOffset : rxMessageTextOffset+length]),
+	}}}
+}
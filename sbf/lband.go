@@ -0,0 +1,392 @@
+package sbf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+/**
+ * Decoding of the L-band blocks: the state of the L-band trackers, the beams
+ * they can track and the status of the LBAS1 and Fugro PPP correction
+ * services. An alarm is raised settings.SubscriptionWarningDays before a
+ * correction subscription expires.
+ */
+
+const lbandReceiverStatusTopic = "lband/receiverstatus"
+const lbandTrackerTopic = "lband/tracker"
+const lbandBeamsTopic = "lband/beams"
+const lbandRawTopic = "lband/raw"
+const lbandLBAS1StatusTopic = "lband/lbas1/status"
+const lbandLBAS1MessagesTopic = "lband/lbas1/messages"
+const lbandFugroStatusTopic = "lband/fugro/status"
+const lbandAlarmTopic = "lband/alarm"
+
+/* Offset of the first sub-block in LBandTrackerStatus and LBandBeams */
+const lbandSubBlockOffset = 16
+
+/* Offset of the data in LBandRaw */
+const lbandRawDataOffset = 20
+
+// LBandReceiverStatus is the status of the L-band module
+type LBandReceiverStatus struct {
+	BlockTime
+	CPULoad  uint8  `json:"cpuLoad"` // %
+	UpTime   uint32 `json:"upTime"`  // s
+	RxStatus string `json:"rxStatus"`
+	RxError  string `json:"rxError"`
+}
+
+// LBandTrackerStatus lists the L-band trackers
+type LBandTrackerStatus struct {
+	BlockTime
+	Trackers []LBandTracker `json:"trackers"`
+}
+
+// LBandTracker is the state of one L-band tracker
+type LBandTracker struct {
+	State      string   `json:"state"`
+	Beam       string   `json:"beam,omitempty"` // Name of the satellite from LBandBeams
+	SVID       *uint8   `json:"svid,omitempty"`
+	Frequency  uint32   `json:"frequency"` // Hz
+	BaudRate   uint16   `json:"baudRate"`
+	ServiceID  string   `json:"serviceId"`
+	FreqOffset *float64 `json:"freqOffset,omitempty"` // Hz
+	CN0        *float64 `json:"cn0,omitempty"`        // dB-Hz
+	AvgPower   *float64 `json:"avgPower,omitempty"`   // dB
+	AGCGain    *float64 `json:"agcGain,omitempty"`    // dB
+	Mode       string   `json:"mode"`
+	LockTime   *float64 `json:"lockTime,omitempty"` // s
+	Source     *uint8   `json:"source,omitempty"`
+}
+
+// LBandBeams lists the L-band satellites and beams known to the receiver
+type LBandBeams struct {
+	BlockTime
+	Beams []LBandBeam `json:"beams"`
+}
+
+// LBandBeam is one L-band satellite beam
+type LBandBeam struct {
+	SVID      uint8    `json:"svid"`
+	Name      string   `json:"name"`
+	Longitude *float64 `json:"longitude,omitempty"` // deg
+	Frequency uint32   `json:"frequency"`           // Hz
+}
+
+// LBandRaw is raw user data received on an L-band channel
+type LBandRaw struct {
+	BlockTime
+	Frequency uint32 `json:"frequency"` // Hz
+	Data      string `json:"data"`      // Hex encoded
+}
+
+// LBAS1Status is the status of the LBAS1 correction service
+type LBAS1Status struct {
+	BlockTime
+	Status            string     `json:"status"`
+	Access            string     `json:"access"`
+	GeoGatingMode     uint8      `json:"geoGatingMode"`
+	GeoGatingStatus   uint8      `json:"geoGatingStatus"`
+	Event             string     `json:"event"`
+	LeaseTime         *uint32    `json:"leaseTime,omitempty"`      // s
+	LeaseRemaining    *uint32    `json:"leaseRemaining,omitempty"` // s
+	LocalAreaStatus   *uint8     `json:"localAreaStatus,omitempty"`
+	SubscriptionEnd   *time.Time `json:"subscriptionEnd,omitempty"`
+	PAC               string     `json:"pac,omitempty"`
+	VelocityLimit     *uint8     `json:"velocityLimit,omitempty"`
+	SpeedGatingStatus *uint8     `json:"speedGatingStatus,omitempty"`
+}
+
+// LBAS1Message is an over-the-air message broadcast by the LBAS1 service
+type LBAS1Message struct {
+	BlockTime
+	Message string `json:"message"`
+}
+
+// FugroStatus is the status of the Fugro correction service
+type FugroStatus struct {
+	BlockTime
+	Status            string     `json:"status"`
+	SubscriptionStart *time.Time `json:"subscriptionStart,omitempty"`
+	SubscriptionEnd   *time.Time `json:"subscriptionEnd,omitempty"`
+	HourGlass         *int32     `json:"hourGlass,omitempty"` // s
+	SubscribedMode    string     `json:"subscribedMode"`
+	CurrentMode       string     `json:"currentMode"`
+	LinkVector        string     `json:"linkVector"`
+	CRCGoodCount      uint32     `json:"crcGoodCount"`
+	CRCBadCount       uint32     `json:"crcBadCount"`
+	CRCErrorRate      *float64   `json:"crcErrorRate,omitempty"`
+}
+
+var lbandTrackerStates = map[uint8]string{
+	0: "idle",
+	1: "search",
+	2: "sync",
+	3: "locked",
+}
+
+var lbandTrackerModes = map[uint8]string{
+	0: "disabled",
+	1: "auto",
+	2: "manual",
+}
+
+var lbas1Statuses = map[uint8]string{
+	0: "disabled",
+	1: "searching",
+	2: "locked",
+}
+
+var lbas1Access = map[uint8]string{
+	0: "denied",
+	1: "enabled",
+}
+
+func handleLBandReceiverStatus(buffer []byte) []interface{} {
+	block := LBandReceiverStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleLBandReceiverStatus - Error decoding LBandReceiverStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := LBandReceiverStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		CPULoad:   block.CPULoad,
+		UpTime:    block.UpTime,
+		RxStatus:  fmt.Sprintf("0x%08x", block.RxStatus),
+		RxError:   fmt.Sprintf("0x%08x", block.RxError),
+	}
+	return []interface{}{Payload{Topic: lbandReceiverStatusTopic, Data: status}}
+}
+
+func handleLBandTrackerStatus(buffer []byte) []interface{} {
+	block := LBandTrackerStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleLBandTrackerStatus - Error decoding LBandTrackerStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	revision := block.Header.ID >> 13
+
+	status := LBandTrackerStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Trackers:  []LBandTracker{},
+	}
+	for i := 0; i < int(block.N); i++ {
+		sb := TrackData_1_3_t{}
+		if !decodeSizedSubBlock(buffer, lbandSubBlockOffset+i*int(block.SBSize), int(block.SBSize), &sb) {
+			log.Printf("[ERROR] handleLBandTrackerStatus - LBandTrackerStatus block too short for %d trackers\n", block.N)
+			break
+		}
+		tracker := LBandTracker{
+			State:      mapString(lbandTrackerStates, sb.Status),
+			Frequency:  sb.Frequency,
+			BaudRate:   sb.BaudRate,
+			ServiceID:  fmt.Sprintf("0x%04x", sb.ServiceID),
+			FreqOffset: optFloat(sb.FreqOffset),
+			CN0:        optScaled(int64(sb.CN0), 0, 0.01),
+			AvgPower:   optScaled(int64(sb.AvgPower), -32768, 0.01),
+			AGCGain:    optScaled(int64(sb.AGCGain), -128, 1),
+			Mode:       mapString(lbandTrackerModes, sb.Mode),
+		}
+		if revision >= 1 {
+			tracker.LockTime = optScaled(int64(sb.LockTime), 65535, 1)
+		}
+		if revision >= 2 && sb.SVID != 0 {
+			svid := sb.SVID
+			tracker.SVID = &svid
+			tracker.Beam = state.lbandBeams[svid]
+		}
+		if revision >= 3 {
+			source := sb.Source
+			tracker.Source = &source
+		}
+		status.Trackers = append(status.Trackers, tracker)
+	}
+
+	return []interface{}{Payload{Topic: lbandTrackerTopic, Data: status}}
+}
+
+func handleLBandBeams(buffer []byte) []interface{} {
+	block := LBandBeams_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleLBandBeams - Error decoding LBandBeams block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	beams := LBandBeams{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Beams:     []LBandBeam{},
+	}
+	names := map[uint8]string{}
+	for i := 0; i < int(block.N); i++ {
+		sb := BeamInfo_1_0_t{}
+		if !decodeSubBlock(buffer, lbandSubBlockOffset+i*int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleLBandBeams - LBandBeams block too short for %d beams\n", block.N)
+			break
+		}
+		beam := LBandBeam{
+			SVID:      sb.SVID,
+			Name:      cString(sb.SatName[:]),
+			Longitude: optScaled(int64(sb.SatLongitude), -32768, 0.01),
+			Frequency: sb.BeamFreq,
+		}
+		names[beam.SVID] = beam.Name
+		beams.Beams = append(beams.Beams, beam)
+	}
+	state.lbandBeams = names
+
+	return []interface{}{Payload{Topic: lbandBeamsTopic, Data: beams}}
+}
+
+func handleLBandRaw(buffer []byte) []interface{} {
+	block := LBandRaw_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleLBandRaw - Error decoding LBandRaw block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	length := int(block.N)
+	if lbandRawDataOffset+length > len(buffer) {
+		log.Printf("[ERROR] handleLBandRaw - LBandRaw block too short for %d bytes of data\n", block.N)
+		length = len(buffer) - lbandRawDataOffset
+	}
+	raw := LBandRaw{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Frequency: block.Frequency,
+		Data:      hex.EncodeToString(buffer[lbandRawDataOffset : lbandRawDataOffset+length]),
+	}
+	return []interface{}{Payload{Topic: lbandRawTopic, Data: raw}}
+}
+
+func handleLBAS1DecoderStatus(buffer []byte) []interface{} {
+	block := LBAS1DecoderStatus_1_2_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleLBAS1DecoderStatus - Error decoding LBAS1DecoderStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	revision := block.Header.ID >> 13
+
+	status := LBAS1Status{
+		BlockTime:       newBlockTime(block.TOW, block.WNc),
+		Status:          mapString(lbas1Statuses, block.Status),
+		Access:          mapString(lbas1Access, block.Access),
+		GeoGatingMode:   block.GeoGatingMode,
+		GeoGatingStatus: block.GeoGatingStatus,
+		Event:           fmt.Sprintf("0x%08x", block.Event),
+	}
+	if revision >= 1 {
+		leaseTime, leaseRemaining, localAreaStatus := block.LeaseTime, block.LeaseRemaining, block.LocalAreaStatus
+		status.LeaseTime = &leaseTime
+		status.LeaseRemaining = &leaseRemaining
+		status.LocalAreaStatus = &localAreaStatus
+		status.PAC = cString(block.PAC[:])
+		if block.SubscrEndYear != -128 && block.SubscrEndMonth > 0 && block.SubscrEndDay > 0 && block.SubscrEndHour >= 0 {
+			end := time.Date(2000+int(block.SubscrEndYear), time.Month(block.SubscrEndMonth), int(block.SubscrEndDay),
+				int(block.SubscrEndHour), 0, 0, 0, time.UTC)
+			status.SubscriptionEnd = &end
+		}
+	}
+	if revision >= 2 {
+		velocityLimit, speedGatingStatus := block.VelocityLimit, block.SpeedGatingStatus
+		status.VelocityLimit = &velocityLimit
+		status.SpeedGatingStatus = &speedGatingStatus
+	}
+
+	payloads := []interface{}{Payload{Topic: lbandLBAS1StatusTopic, Data: status}}
+	if status.SubscriptionEnd != nil {
+		payloads = append(payloads, subscriptionAlarm("lbas1Subscription", status.BlockTime, *status.SubscriptionEnd)...)
+	}
+	return payloads
+}
+
+func handleLBAS1Messages(buffer []byte) []interface{} {
+	block := LBAS1Messages_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleLBAS1Messages - Error decoding LBAS1Messages block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	length := int(block.MessageLen)
+	if length > len(block.Message) {
+		length = len(block.Message)
+	}
+	message := LBAS1Message{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Message:   cString(block.Message[:length]),
+	}
+	return []interface{}{Payload{Topic: lbandLBAS1MessagesTopic, Data: message}}
+}
+
+func handleFugroStatus(buffer []byte) []interface{} {
+	block := FugroStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleFugroStatus - Error decoding FugroStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := FugroStatus{
+		BlockTime:      newBlockTime(block.TOW, block.WNc),
+		Status:         fmt.Sprintf("0x%08x", block.Status),
+		SubscribedMode: fmt.Sprintf("0x%08x", block.SubscribedMode),
+		CurrentMode:    fmt.Sprintf("0x%08x", block.SubCurrentMode),
+		LinkVector:     fmt.Sprintf("0x%08x", block.SubLinkVector),
+		CRCGoodCount:   block.CRCGoodCount,
+		CRCBadCount:    block.CRCBadCount,
+	}
+	// Subscription times are in seconds since the Unix epoch
+	if block.SubStartingTime > 0 {
+		start := time.Unix(int64(block.SubStartingTime), 0).UTC()
+		status.SubscriptionStart = &start
+	}
+	if block.SubExpirationTime > 0 {
+		end := time.Unix(int64(block.SubExpirationTime), 0).UTC()
+		status.SubscriptionEnd = &end
+	}
+	if block.SubHourGlass != math.MinInt32 {
+		hourGlass := block.SubHourGlass
+		status.HourGlass = &hourGlass
+	}
+
+	// The receiver does not report a bit error rate for the L-band signal, the
+	// share of messages failing their CRC since the previous block is used instead
+	if state.fugroCRCCountsValid && block.CRCGoodCount >= state.fugroCRCGoodCount && block.CRCBadCount >= state.fugroCRCBadCount {
+		good, bad := block.CRCGoodCount-state.fugroCRCGoodCount, block.CRCBadCount-state.fugroCRCBadCount
+		if good+bad > 0 {
+			rate := float64(bad) / float64(good+bad)
+			status.CRCErrorRate = &rate
+		}
+	}
+	state.fugroCRCGoodCount, state.fugroCRCBadCount, state.fugroCRCCountsValid = block.CRCGoodCount, block.CRCBadCount, true
+
+	payloads := []interface{}{Payload{Topic: lbandFugroStatusTopic, Data: status}}
+	if status.SubscriptionEnd != nil {
+		payloads = append(payloads, subscriptionAlarm("fugroSubscription", status.BlockTime, *status.SubscriptionEnd)...)
+	}
+	return payloads
+}
+
+// subscriptionAlarm raises an alarm once a subscription expires within
+// settings.SubscriptionWarningDays, measured against the receiver time
+func subscriptionAlarm(name string, blockTime BlockTime, end time.Time) []interface{} {
+	now := currentTime().UTC()
+	if blockTime.Time != nil {
+		now = *blockTime.Time
+	}
+	remaining := end.Sub(now).Hours() / 24
+
+	message := fmt.Sprintf("subscription expires on %s, in %.1f days", end.Format("2006-01-02 15:04"), remaining)
+	if remaining <= 0 {
+		message = fmt.Sprintf("subscription expired on %s", end.Format("2006-01-02 15:04"))
+	}
+	return updateAlarm(lbandAlarmTopic, Alarm{
+		BlockTime: blockTime,
+		Alarm:     name,
+		Active:    remaining <= float64(settings.SubscriptionWarningDays),
+		Message:   message,
+		Value:     floatPtr(math.Round(remaining*10) / 10),
+		Limit:     floatPtr(float64(settings.SubscriptionWarningDays)),
+	})
+}
@@ -0,0 +1,207 @@
+package sbf
+
+import (
+	"log"
+	"strconv"
+)
+
+/**
+ * Decoding of MeasEpoch, the code, carrier, Doppler and C/N0 measurements of
+ * every tracked signal, into the observations of observations.go. Only the
+ * measurements of the main antenna are kept, those of the auxiliary antennas
+ * of attitude receivers are not published.
+ */
+
+/* Offset of the first Type1 sub-block */
+const measEpochSubBlockOffset = 20
+
+/* RINEX observation code of the SBF signal numbers */
+var measEpochSignals = map[int]string{
+	0:  "1C", // GPS L1C/A
+	1:  "1W", // GPS L1P
+	2:  "2W", // GPS L2P
+	3:  "2L", // GPS L2C
+	4:  "5Q", // GPS L5
+	5:  "1L", // GPS L1C
+	6:  "1C", // QZSS L1C/A
+	7:  "2L", // QZSS L2C
+	8:  "1C", // GLONASS L1C/A
+	9:  "1P", // GLONASS L1P
+	10: "2P", // GLONASS L2P
+	11: "2C", // GLONASS L2C/A
+	12: "3Q", // GLONASS L3
+	13: "1P", // BeiDou B1C
+	14: "5P", // BeiDou B2a
+	15: "5A", // NavIC L5
+	17: "1C", // Galileo E1 (L1BC)
+	19: "6C", // Galileo E6 (E6BC)
+	20: "5Q", // Galileo E5a
+	21: "7Q", // Galileo E5b
+	22: "8Q", // Galileo E5 AltBOC
+	24: "1C", // SBAS L1C/A
+	25: "5I", // SBAS L5
+	26: "5Q", // QZSS L5
+	27: "6L", // QZSS L6
+	28: "2I", // BeiDou B1I
+	29: "7I", // BeiDou B2I
+	30: "6I", // BeiDou B3I
+	32: "1L", // QZSS L1C
+	33: "1Z", // QZSS L1S
+	34: "7D", // BeiDou B2b
+	37: "1E", // QZSS L1C/B
+	38: "5Z", // QZSS L5S
+}
+
+/* MeasEpoch do-not-use values */
+const measDopplerDoNotUse = -2147483648
+const measCN0DoNotUse = 255
+
+func handleMeasEpoch(buffer []byte) []interface{} {
+	block := MeasEpoch_2_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleMeasEpoch - Error decoding MeasEpoch block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	observations := Observations{
+		BlockTime:  newBlockTime(block.TOW, block.WNc),
+		Source:     "sbf",
+		Satellites: []SatelliteObservations{},
+	}
+	offset := measEpochSubBlockOffset
+	for i := 0; i < int(block.N); i++ {
+		type1 := MeasEpochChannelType1_2_1_t{}
+		if !decodeSizedSubBlock(buffer, offset, int(block.SB1Size), &type1) {
+			log.Printf("[ERROR] handleMeasEpoch - MeasEpoch block too short for %d channels\n", block.N)
+			break
+		}
+		offset += int(block.SB1Size)
+
+		system, number := satelliteSystem(type1.SVID)
+		satellite := SatelliteObservations{
+			Satellite: satelliteName(type1.SVID),
+			System:    system,
+			Signals:   []SignalObservation{},
+		}
+		if system == "GLONASS" {
+			frequencyNumber := int(type1.ObsInfo>>3) - 8
+			satellite.FrequencyNumber = &frequencyNumber
+			if number > 0 {
+				state.glonassFrequencyNumbers[number] = frequencyNumber
+			}
+		}
+
+		// Type1 holds the reference signal, the Type2 sub-blocks that follow
+		// it the other signals of the satellite as offsets to it
+		signal, frequency, mainAntenna := measType1Signal(type1, satellite)
+		for j := 0; j < int(type1.N_Type2); j++ {
+			type2 := MeasEpochChannelType2_2_1_t{}
+			if !decodeSizedSubBlock(buffer, offset, int(block.SB2Size), &type2) {
+				log.Printf("[ERROR] handleMeasEpoch - MeasEpoch block too short for %d signals of %s\n", type1.N_Type2, satellite.Satellite)
+				break
+			}
+			offset += int(block.SB2Size)
+			if type2.Type>>5 != 0 {
+				continue
+			}
+			satellite.Signals = append(satellite.Signals, measType2Signal(type2, signal, frequency, satellite))
+		}
+		if mainAntenna {
+			satellite.Signals = append([]SignalObservation{signal}, satellite.Signals...)
+		}
+		if len(satellite.Signals) > 0 {
+			observations.Satellites = append(observations.Satellites, satellite)
+		}
+	}
+
+	writeRinexObservations(observations)
+	updateNMEASignalStrengths(observations)
+
+	payloads := []interface{}{Payload{Topic: observationsTopic, Data: observations}}
+	return append(payloads, generateCorrections(observations)...)
+}
+
+// measSignalCode returns the RINEX code of the signal of a sub-block. Signal
+// numbers above 31 are stored in the ObsInfo field.
+func measSignalCode(signalType uint8, obsInfo uint8) string {
+	number := int(signalType & 0x1F)
+	if number == 31 {
+		number = int(obsInfo>>3) + 32
+	}
+	if code, ok := measEpochSignals[number]; ok {
+		return code
+	}
+	return "unknown(" + strconv.Itoa(number) + ")"
+}
+
+// measCN0 converts a MeasEpoch C/N0 to dB-Hz. The GPS P(Y) signals have no
+// offset, all other signals 10 dB-Hz.
+func measCN0(cn0 uint8, signalType uint8) *float64 {
+	if cn0 == measCN0DoNotUse {
+		return nil
+	}
+	value := float64(cn0) * 0.25
+	if number := signalType & 0x1F; number != 1 && number != 2 {
+		value += 10
+	}
+	return &value
+}
+
+// measType1Signal decodes the reference signal of a satellite. It returns its
+// carrier frequency, needed to decode the Type2 signals, and whether it was
+// measured on the main antenna.
+func measType1Signal(type1 MeasEpochChannelType1_2_1_t, satellite SatelliteObservations) (SignalObservation, float64, bool) {
+	signal := SignalObservation{
+		Signal:             measSignalCode(type1.Type, type1.ObsInfo),
+		CN0:                measCN0(type1.CN0, type1.Type),
+		LockTime:           floatPtr(float64(type1.LockTime)),
+		HalfCycleAmbiguity: type1.ObsInfo&0x04 != 0,
+	}
+	frequency := carrierFrequency(satellite.System, signal.Signal, satellite.FrequencyNumber)
+
+	codeMSB := type1.Misc & 0x0F
+	if codeMSB != 0 || type1.CodeLSB != 0 {
+		signal.Pseudorange = floatPtr((float64(codeMSB)*4294967296 + float64(type1.CodeLSB)) * 0.001)
+	}
+	if type1.Doppler != measDopplerDoNotUse {
+		signal.Doppler = floatPtr(float64(type1.Doppler) * 0.0001)
+	}
+	if signal.Pseudorange != nil && frequency != 0 && !(type1.CarrierMSB == -128 && type1.CarrierLSB == 0) {
+		carrier := *signal.Pseudorange*frequency/speedOfLight + (float64(type1.CarrierMSB)*65536+float64(type1.CarrierLSB))*0.001
+		signal.CarrierPhase = &carrier
+	}
+	return signal, frequency, type1.Type>>5 == 0
+}
+
+// measType2Signal decodes a signal stored as offsets to the reference signal
+func measType2Signal(type2 MeasEpochChannelType2_2_1_t, reference SignalObservation, referenceFrequency float64, satellite SatelliteObservations) SignalObservation {
+	signal := SignalObservation{
+		Signal:             measSignalCode(type2.Type, type2.ObsInfo),
+		CN0:                measCN0(type2.CN0, type2.Type),
+		LockTime:           floatPtr(float64(type2.LockTime)),
+		HalfCycleAmbiguity: type2.ObsInfo&0x04 != 0,
+	}
+	frequency := carrierFrequency(satellite.System, signal.Signal, satellite.FrequencyNumber)
+
+	// The offsets MSBs are signed 3 and 5 bit fields
+	codeOffsetMSB := int64(type2.OffsetsMSB & 0x07)
+	if codeOffsetMSB > 3 {
+		codeOffsetMSB -= 8
+	}
+	dopplerOffsetMSB := int64(type2.OffsetsMSB >> 3)
+	if dopplerOffsetMSB > 15 {
+		dopplerOffsetMSB -= 32
+	}
+
+	if reference.Pseudorange != nil && !(codeOffsetMSB == -4 && type2.CodeOffsetLSB == 0) {
+		signal.Pseudorange = floatPtr(*reference.Pseudorange + float64(codeOffsetMSB*65536+int64(type2.CodeOffsetLSB))*0.001)
+	}
+	if signal.Pseudorange != nil && frequency != 0 && !(type2.CarrierMSB == -128 && type2.CarrierLSB == 0) {
+		carrier := *signal.Pseudorange*frequency/speedOfLight + (float64(type2.CarrierMSB)*65536+float64(type2.CarrierLSB))*0.001
+		signal.CarrierPhase = &carrier
+	}
+	if reference.Doppler != nil && frequency != 0 && referenceFrequency != 0 && !(dopplerOffsetMSB == -16 && type2.DopplerOffsetLSB == 0) {
+		signal.Doppler = floatPtr(*reference.Doppler*frequency/referenceFrequency + float64(dopplerOffsetMSB*65536+int64(type2.DopplerOffsetLSB))*0.0001)
+	}
+	return signal
+}
@@ -0,0 +1,448 @@
+package sbf
+
+import (
+	"log"
+	"math"
+	"time"
+)
+
+/**
+ * Decoding of the GPS, Galileo, GLONASS, BeiDou, QZSS and SBAS navigation
+ * blocks into the ephemeris shapes of observations.go, published on the same
+ * topic as the RTCM3 ephemerides, and of the GPS and Galileo ionosphere and
+ * UTC parameters. Every record is also handed to the RINEX navigation writer.
+ */
+
+const ionosphereTopic = "ephemeris/ionosphere"
+const utcTopic = "ephemeris/utc"
+
+/* GALNav Source values */
+const galileoSourceINAV = 2
+const galileoSourceFNAV = 16
+
+/* GPS weeks at which the Galileo and BeiDou weeks start */
+const galileoWeekOffset = 1024
+const beidouWeekOffset = 1356
+
+// SBASEphemeris is the broadcast ephemeris of an SBAS satellite, message type 9
+type SBASEphemeris struct {
+	BlockTime
+	Source        string     `json:"source"`
+	Satellite     string     `json:"satellite"`
+	System        string     `json:"system"`
+	IODN          uint16     `json:"iodn"`
+	Accuracy      *float64   `json:"accuracy,omitempty"` // m, URA
+	T0            uint32     `json:"t0"`                 // s of the day, GPS time
+	Toe           *time.Time `json:"toe,omitempty"`
+	X             float64    `json:"x"`             // km, WGS84
+	Y             float64    `json:"y"`             // km
+	Z             float64    `json:"z"`             // km
+	VelocityX     float64    `json:"velocityX"`     // km/s
+	VelocityY     float64    `json:"velocityY"`     // km/s
+	VelocityZ     float64    `json:"velocityZ"`     // km/s
+	AccelerationX float64    `json:"accelerationX"` // km/s²
+	AccelerationY float64    `json:"accelerationY"` // km/s²
+	AccelerationZ float64    `json:"accelerationZ"` // km/s²
+	Af0           float64    `json:"af0"`           // s
+	Af1           float64    `json:"af1"`           // s/s
+}
+
+// IonosphereParameters are the broadcast ionosphere model parameters, Klobuchar
+// for GPS and NeQuick for Galileo
+type IonosphereParameters struct {
+	BlockTime
+	Satellite           string    `json:"satellite"`
+	System              string    `json:"system"`
+	Alpha               []float64 `json:"alpha,omitempty"`               // GPS, s, s/semicircle, s/semicircle², s/semicircle³
+	Beta                []float64 `json:"beta,omitempty"`                // GPS, s, s/semicircle, s/semicircle², s/semicircle³
+	EffectiveIonisation []float64 `json:"effectiveIonisation,omitempty"` // Galileo ai0-ai2, sfu, sfu/°, sfu/°²
+	StormFlags          *uint8    `json:"stormFlags,omitempty"`          // Galileo, one bit per region
+}
+
+// UTCParameters relate the time of a constellation to UTC
+type UTCParameters struct {
+	BlockTime
+	Satellite         string  `json:"satellite"`
+	System            string  `json:"system"`
+	A0                float64 `json:"a0"`  // s
+	A1                float64 `json:"a1"`  // s/s
+	Tot               uint32  `json:"tot"` // s of week
+	Week              uint16  `json:"week"`
+	LeapSeconds       int8    `json:"leapSeconds"`
+	LeapSecondsFuture int8    `json:"leapSecondsFuture"`
+	LeapSecondsWeek   uint16  `json:"leapSecondsWeek"`
+	LeapSecondsDay    uint8   `json:"leapSecondsDay"`
+}
+
+func handleGPSNav(buffer []byte) []interface{} {
+	block := GPSNav_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleGPSNav - Error decoding GPSNav block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	eph := gpsEphemeris(block.Eph, "GPS")
+	writeRinexEphemeris(eph)
+	return []interface{}{Payload{Topic: ephemerisTopic, Data: eph}}
+}
+
+func handleQZSNav(buffer []byte) []interface{} {
+	// QZSS broadcasts the GPS navigation message, QZSNav has the layout of GPSNav
+	block := GPSNav_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleQZSNav - Error decoding QZSNav block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	eph := gpsEphemeris(block.Eph, "QZSS")
+	writeRinexEphemeris(eph)
+	return []interface{}{Payload{Topic: ephemerisTopic, Data: eph}}
+}
+
+// gpsEphemeris converts a GPS or QZSS ephemeris, whose SBF layouts are the same
+func gpsEphemeris(block gpEph_1_0_t, system string) Ephemeris {
+	eph := Ephemeris{
+		BlockTime:    newBlockTime(block.TOW, block.WNc),
+		Source:       "sbf",
+		Satellite:    satelliteName(block.PRN),
+		System:       system,
+		Week:         uint16(fullWeek(int(block.WNt_oe), 10, int(block.WNc))),
+		IODE:         uint16(block.IODE2),
+		Toe:          float64(block.T_oe),
+		Toc:          float64(block.T_oc),
+		SqrtA:        float64(block.SQRT_A),
+		Eccentricity: float64(block.E),
+		I0:           float64(block.I_0) * math.Pi,
+		Omega0:       float64(block.OMEGA_0) * math.Pi,
+		Omega:        float64(block.Omega) * math.Pi,
+		M0:           float64(block.M_0) * math.Pi,
+		DeltaN:       float64(block.DEL_N) * math.Pi,
+		OmegaDot:     float64(block.OMEGADOT) * math.Pi,
+		IDot:         float64(block.IDOT) * math.Pi,
+		Cuc:          float64(block.C_uc),
+		Cus:          float64(block.C_us),
+		Crc:          float64(block.C_rc),
+		Crs:          float64(block.C_rs),
+		Cic:          float64(block.C_ic),
+		Cis:          float64(block.C_is),
+		Af0:          float64(block.A_f0),
+		Af1:          float64(block.A_f1),
+		Af2:          float64(block.A_f2),
+		TGD:          optFloat(block.T_gd),
+		Accuracy:     floatPtr(gpsURA[block.URA&0x0F]),
+		Health:       uint16(block.Health),
+	}
+	iodc := block.IODC
+	eph.IODC = &iodc
+	codes := block.CAorPonL2
+	eph.CodesOnL2 = &codes
+	l2p := block.L2DataFlag == 1
+	eph.L2PDataFlag = &l2p
+
+	// The fit interval flag means more than 4 hours for GPS, 2 for QZSS
+	switch {
+	case block.FitIntFlg != 0:
+		eph.FitInterval = floatPtr(6)
+	case system == "QZSS":
+		eph.FitInterval = floatPtr(2)
+	default:
+		eph.FitInterval = floatPtr(4)
+	}
+	return eph
+}
+
+func handleGALNav(buffer []byte) []interface{} {
+	block := GALNav_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleGALNav - Error decoding GALNav block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := block.Eph
+	eph := Ephemeris{
+		BlockTime:    newBlockTime(nav.TOW, nav.WNc),
+		Source:       "sbf",
+		Satellite:    satelliteName(nav.SVID),
+		System:       "Galileo",
+		Week:         uint16(fullWeek(int(nav.WNt_oe)+galileoWeekOffset, 12, int(nav.WNc))),
+		IODE:         nav.IODnav,
+		Toe:          float64(nav.T_oe),
+		Toc:          float64(nav.T_oc),
+		SqrtA:        float64(nav.SQRT_A),
+		Eccentricity: float64(nav.E),
+		I0:           float64(nav.I_0) * math.Pi,
+		Omega0:       float64(nav.OMEGA_0) * math.Pi,
+		Omega:        float64(nav.Omega) * math.Pi,
+		M0:           float64(nav.M_0) * math.Pi,
+		DeltaN:       float64(nav.DEL_N) * math.Pi,
+		OmegaDot:     float64(nav.OMEGADOT) * math.Pi,
+		IDot:         float64(nav.IDOT) * math.Pi,
+		Cuc:          float64(nav.C_uc),
+		Cus:          float64(nav.C_us),
+		Crc:          float64(nav.C_rc),
+		Crs:          float64(nav.C_rs),
+		Cic:          float64(nav.C_ic),
+		Cis:          float64(nav.C_is),
+		Af0:          float64(nav.A_f0),
+		Af1:          float64(nav.A_f1),
+		Af2:          float64(nav.A_f2),
+		TGD:          optFloat(nav.BGD_L1E5a),
+		TGD2:         optFloat(nav.BGD_L1E5b),
+	}
+
+	// The SBF health holds a valid flag, the HS and the DVS per signal, E1-B
+	// in bits 0-3, E5b in bits 4-7 and E5a in bits 8-11. RINEX has the DVS
+	// and HS of E1-B in bits 0-2, E5a in bits 3-5 and E5b in bits 6-8.
+	health := nav.Health_OSSOL
+	for _, signal := range []struct{ sbf, rinex uint }{{0, 0}, {8, 3}, {4, 6}} {
+		if health>>signal.sbf&0x01 != 0 {
+			eph.Health |= (health>>(signal.sbf+3)&0x01 | health>>(signal.sbf+1)&0x03<<1) << signal.rinex
+		}
+	}
+
+	// Data sources as in RINEX: I/NAV with the clock for E5b/E1 or F/NAV with
+	// the clock for E5a/E1
+	sources := uint16(0x205)
+	eph.Accuracy = galileoSISA(nav.SISA_L1E5b)
+	if nav.Source == galileoSourceFNAV {
+		sources = 0x102
+		eph.Accuracy = galileoSISA(nav.SISA_L1E5a)
+	}
+	eph.DataSources = &sources
+
+	writeRinexEphemeris(eph)
+	return []interface{}{Payload{Topic: ephemerisTopic, Data: eph}}
+}
+
+func handleBDSNav(buffer []byte) []interface{} {
+	block := BDSNav_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleBDSNav - Error decoding BDSNav block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := block.Eph
+	eph := Ephemeris{
+		BlockTime:    newBlockTime(nav.TOW, nav.WNc),
+		Source:       "sbf",
+		Satellite:    satelliteName(nav.PRN),
+		System:       "BeiDou",
+		Week:         uint16(fullWeek(int(nav.WNt_oe), 13, int(nav.WNc)-beidouWeekOffset)),
+		IODE:         uint16(nav.IODE),
+		Toe:          float64(nav.T_oe),
+		Toc:          float64(nav.T_oc),
+		SqrtA:        float64(nav.SQRT_A),
+		Eccentricity: float64(nav.E),
+		I0:           float64(nav.I_0) * math.Pi,
+		Omega0:       float64(nav.OMEGA_0) * math.Pi,
+		Omega:        float64(nav.Omega) * math.Pi,
+		M0:           float64(nav.M_0) * math.Pi,
+		DeltaN:       float64(nav.DEL_N) * math.Pi,
+		OmegaDot:     float64(nav.OMEGADOT) * math.Pi,
+		IDot:         float64(nav.IDOT) * math.Pi,
+		Cuc:          float64(nav.C_uc),
+		Cus:          float64(nav.C_us),
+		Crc:          float64(nav.C_rc),
+		Crs:          float64(nav.C_rs),
+		Cic:          float64(nav.C_ic),
+		Cis:          float64(nav.C_is),
+		Af0:          float64(nav.A_f0),
+		Af1:          float64(nav.A_f1),
+		Af2:          float64(nav.A_f2),
+		TGD:          optFloat(nav.T_GD1),
+		TGD2:         optFloat(nav.T_GD2),
+		Accuracy:     floatPtr(gpsURA[nav.URA&0x0F]),
+		Health:       uint16(nav.SatH1),
+	}
+	aodc := uint16(nav.IODC)
+	eph.IODC = &aodc
+
+	writeRinexEphemeris(eph)
+	return []interface{}{Payload{Topic: ephemerisTopic, Data: eph}}
+}
+
+func handleGLONav(buffer []byte) []interface{} {
+	block := GLONav_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleGLONav - Error decoding GLONav block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := block.Eph
+	number := int(nav.SVID) - 37
+	eph := GLONASSEphemeris{
+		BlockTime:       newBlockTime(nav.TOW, nav.WNc),
+		Source:          "sbf",
+		Satellite:       rinexSatellite("GLONASS", number),
+		System:          "GLONASS",
+		FrequencyNumber: int(nav.FreqNr) - 8,
+		Tb:              nav.Tb,
+		X:               float64(nav.X),
+		Y:               float64(nav.Y),
+		Z:               float64(nav.Z),
+		VelocityX:       float64(nav.Dx),
+		VelocityY:       float64(nav.Dy),
+		VelocityZ:       float64(nav.Dz),
+		AccelerationX:   float64(nav.Ddx),
+		AccelerationY:   float64(nav.Ddy),
+		AccelerationZ:   float64(nav.Ddz),
+		TauN:            float64(nav.Tau),
+		GammaN:          float64(nav.Gamma),
+		DeltaTauN:       float64(nav.Dtau),
+		Health:          nav.B >> 2 & 0x01,
+		Age:             nav.E,
+	}
+
+	// The reference time is given in GPS time, the frame time is approximated
+	// by the reception time, both are converted to UTC as GLONASS uses it
+	leapSeconds := time.Duration(gpsUtcLeapSeconds) * time.Second
+	if toe, ok := gpsTime(nav.T_oe*1000, nav.WNt_oe); ok {
+		toe = toe.Add(-leapSeconds)
+		eph.Toe = &toe
+	}
+	if nav.TOW != towDoNotUse {
+		eph.Tk = uint32((int64(nav.TOW)/1000 - int64(gpsUtcLeapSeconds) + 3*3600 + 604800) % 86400)
+	}
+
+	if number > 0 {
+		state.glonassFrequencyNumbers[number] = eph.FrequencyNumber
+	}
+	writeRinexGLONASSEphemeris(eph, nav.F_T)
+	return []interface{}{Payload{Topic: ephemerisTopic, Data: eph}}
+}
+
+func handleGEONav(buffer []byte) []interface{} {
+	block := GEONav_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleGEONav - Error decoding GEONav block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	nav := block.Eph
+	eph := SBASEphemeris{
+		BlockTime:     newBlockTime(nav.TOW, nav.WNc),
+		Source:        "sbf",
+		Satellite:     satelliteName(nav.PRN),
+		System:        "SBAS",
+		IODN:          nav.IODN,
+		T0:            nav.T0,
+		X:             float64(nav.Xg) / 1000,
+		Y:             float64(nav.Yg) / 1000,
+		Z:             float64(nav.Zg) / 1000,
+		VelocityX:     float64(nav.Xgd) / 1000,
+		VelocityY:     float64(nav.Ygd) / 1000,
+		VelocityZ:     float64(nav.Zgd) / 1000,
+		AccelerationX: float64(nav.Xgdd) / 1000,
+		AccelerationY: float64(nav.Ygdd) / 1000,
+		AccelerationZ: float64(nav.Zgdd) / 1000,
+		Af0:           float64(nav.AGf0),
+		Af1:           float64(nav.AGf1),
+	}
+	if nav.URA < 15 {
+		eph.Accuracy = floatPtr(gpsURA[nav.URA])
+	}
+
+	// t0 is a time of day, of the day of reception or the one next to it
+	if reception, ok := gpsTime(nav.TOW, nav.WNc); ok {
+		toe := reception.Truncate(24 * time.Hour).Add(time.Duration(nav.T0) * time.Second)
+		if toe.Sub(reception) > 12*time.Hour {
+			toe = toe.Add(-24 * time.Hour)
+		} else if reception.Sub(toe) > 12*time.Hour {
+			toe = toe.Add(24 * time.Hour)
+		}
+		eph.Toe = &toe
+	}
+
+	writeRinexSBASEphemeris(eph)
+	return []interface{}{Payload{Topic: ephemerisTopic, Data: eph}}
+}
+
+func handleGPSIon(buffer []byte) []interface{} {
+	block := GPSIon_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleGPSIon - Error decoding GPSIon block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	ion := block.Ion
+	parameters := IonosphereParameters{
+		BlockTime: newBlockTime(ion.TOW, ion.WNc),
+		Satellite: satelliteName(ion.PRN),
+		System:    "GPS",
+		Alpha:     []float64{float64(ion.Alpha_0), float64(ion.Alpha_1), float64(ion.Alpha_2), float64(ion.Alpha_3)},
+		Beta:      []float64{float64(ion.Beta_0), float64(ion.Beta_1), float64(ion.Beta_2), float64(ion.Beta_3)},
+	}
+	writeRinexIonosphere(parameters)
+	return []interface{}{Payload{Topic: ionosphereTopic, Data: parameters}}
+}
+
+func handleGALIon(buffer []byte) []interface{} {
+	block := GALIon_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleGALIon - Error decoding GALIon block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	ion := block.Ion
+	stormFlags := ion.StormFlags
+	parameters := IonosphereParameters{
+		BlockTime:           newBlockTime(ion.TOW, ion.WNc),
+		Satellite:           satelliteName(ion.SVID),
+		System:              "Galileo",
+		EffectiveIonisation: []float64{float64(ion.A_i0), float64(ion.A_i1), float64(ion.A_i2)},
+		StormFlags:          &stormFlags,
+	}
+	writeRinexIonosphere(parameters)
+	return []interface{}{Payload{Topic: ionosphereTopic, Data: parameters}}
+}
+
+func handleGPSUtc(buffer []byte) []interface{} {
+	block := GPSUtc_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleGPSUtc - Error decoding GPSUtc block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	utc := block.Utc
+	parameters := UTCParameters{
+		BlockTime:         newBlockTime(utc.TOW, utc.WNc),
+		Satellite:         satelliteName(utc.PRN),
+		System:            "GPS",
+		A0:                float64(utc.A_0),
+		A1:                float64(utc.A_1),
+		Tot:               utc.T_ot,
+		Week:              uint16(fullWeek(int(utc.WN_t), 8, int(utc.WNc))),
+		LeapSeconds:       utc.DEL_t_LS,
+		LeapSecondsFuture: utc.DEL_t_LSF,
+		LeapSecondsWeek:   uint16(fullWeek(int(utc.WN_LSF), 8, int(utc.WNc))),
+		LeapSecondsDay:    utc.DN,
+	}
+	writeRinexUTC(parameters)
+	return []interface{}{Payload{Topic: utcTopic, Data: parameters}}
+}
+
+func handleGALUtc(buffer []byte) []interface{} {
+	block := GALUtc_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleGALUtc - Error decoding GALUtc block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	// Galileo weeks are converted to GPS weeks as RINEX uses them
+	utc := block.Utc
+	parameters := UTCParameters{
+		BlockTime:         newBlockTime(utc.TOW, utc.WNc),
+		Satellite:         satelliteName(utc.SVID),
+		System:            "Galileo",
+		A0:                float64(utc.A_0),
+		A1:                float64(utc.A_1),
+		Tot:               utc.T_ot,
+		Week:              uint16(fullWeek(int(utc.WN_ot)+galileoWeekOffset, 8, int(utc.WNc))),
+		LeapSeconds:       utc.DEL_t_LS,
+		LeapSecondsFuture: utc.DEL_t_LSF,
+		LeapSecondsWeek:   uint16(fullWeek(int(utc.WN_LSF)+galileoWeekOffset, 8, int(utc.WNc))),
+		LeapSecondsDay:    utc.DN,
+	}
+	writeRinexUTC(parameters)
+	return []interface{}{Payload{Topic: utcTopic, Data: parameters}}
+}
@@ -0,0 +1,461 @@
+package sbf
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Decoding of the NMEA 0183 sentences the receiver outputs alongside or
+ * instead of SBF: GGA, RMC, GSA, GSV, GST, VTG, ZDA and HDT of any talker and
+ * the Septentrio proprietary $PSSN sentences. Every sentence type is published
+ * on its own topic, nmea/gga, nmea/rmc, ..., nmea/pssn/hrp. Sentences of other
+ * types are published on nmea/other with their fields unparsed.
+ */
+
+const nmeaTopic = "nmea"
+
+// NMEASentence identifies the sentence a record was decoded from
+type NMEASentence struct {
+	Talker   string `json:"talker"`   // e.g. GP, GN or P for proprietary sentences
+	Sentence string `json:"sentence"` // e.g. GGA, or SSN,HRP for proprietary sentences
+}
+
+// NMEAFix is a GGA position fix
+type NMEAFix struct {
+	NMEASentence
+	UTCTime         string   `json:"utcTime,omitempty"` // hh:mm:ss.ss
+	Latitude        *float64 `json:"latitude,omitempty"`
+	Longitude       *float64 `json:"longitude,omitempty"`
+	Quality         string   `json:"quality"`
+	NrSV            *int     `json:"nrSV,omitempty"`
+	HDOP            *float64 `json:"hdop,omitempty"`
+	Altitude        *float64 `json:"altitude,omitempty"`        // m above mean sea level
+	GeoidSeparation *float64 `json:"geoidSeparation,omitempty"` // m
+	DifferentialAge *float64 `json:"differentialAge,omitempty"` // s
+	StationID       string   `json:"stationId,omitempty"`
+}
+
+// NMEARecommendedMinimum is an RMC position, velocity and time
+type NMEARecommendedMinimum struct {
+	NMEASentence
+	Time              *time.Time `json:"time,omitempty"`
+	Valid             bool       `json:"valid"`
+	Latitude          *float64   `json:"latitude,omitempty"`
+	Longitude         *float64   `json:"longitude,omitempty"`
+	SpeedOverGround   *float64   `json:"speedOverGround,omitempty"`   // knots
+	CourseOverGround  *float64   `json:"courseOverGround,omitempty"`  // ° true
+	MagneticVariation *float64   `json:"magneticVariation,omitempty"` // °, east positive
+	Mode              string     `json:"mode,omitempty"`
+}
+
+// NMEADOP is a GSA list of the satellites used and the dilutions of precision
+type NMEADOP struct {
+	NMEASentence
+	Mode       string   `json:"mode"` // M manual, A automatic
+	FixType    string   `json:"fixType"`
+	Satellites []int    `json:"satellites"`
+	PDOP       *float64 `json:"pdop,omitempty"`
+	HDOP       *float64 `json:"hdop,omitempty"`
+	VDOP       *float64 `json:"vdop,omitempty"`
+	SystemID   *int     `json:"systemId,omitempty"` // NMEA 4.10 and later
+}
+
+// NMEASatellitesInView is one GSV sentence of a sequence
+type NMEASatellitesInView struct {
+	NMEASentence
+	NrMessages       int                 `json:"nrMessages"`
+	MessageNr        int                 `json:"messageNr"`
+	SatellitesInView int                 `json:"satellitesInView"`
+	Satellites       []NMEASatelliteView `json:"satellites"`
+	SignalID         *int                `json:"signalId,omitempty"` // NMEA 4.10 and later
+}
+
+// NMEASatelliteView is a satellite of a GSV sentence
+type NMEASatelliteView struct {
+	PRN       int      `json:"prn"`
+	Elevation *float64 `json:"elevation,omitempty"` // °
+	Azimuth   *float64 `json:"azimuth,omitempty"`   // °
+	SNR       *float64 `json:"snr,omitempty"`       // dB-Hz
+}
+
+// NMEAPseudorangeNoise is a GST error estimate of the position
+type NMEAPseudorangeNoise struct {
+	NMEASentence
+	UTCTime         string   `json:"utcTime,omitempty"`
+	RMS             *float64 `json:"rms,omitempty"`             // m
+	SemiMajor       *float64 `json:"semiMajor,omitempty"`       // m
+	SemiMinor       *float64 `json:"semiMinor,omitempty"`       // m
+	Orientation     *float64 `json:"orientation,omitempty"`     // ° from true north
+	LatitudeStdDev  *float64 `json:"latitudeStdDev,omitempty"`  // m
+	LongitudeStdDev *float64 `json:"longitudeStdDev,omitempty"` // m
+	AltitudeStdDev  *float64 `json:"altitudeStdDev,omitempty"`  // m
+}
+
+// NMEACourseOverGround is a VTG course and speed
+type NMEACourseOverGround struct {
+	NMEASentence
+	CourseTrue     *float64 `json:"courseTrue,omitempty"`     // °
+	CourseMagnetic *float64 `json:"courseMagnetic,omitempty"` // °
+	SpeedKnots     *float64 `json:"speedKnots,omitempty"`
+	SpeedKmh       *float64 `json:"speedKmh,omitempty"`
+	Mode           string   `json:"mode,omitempty"`
+}
+
+// NMEATimeDate is a ZDA time and date
+type NMEATimeDate struct {
+	NMEASentence
+	Time             *time.Time `json:"time,omitempty"`
+	LocalZoneHours   *int       `json:"localZoneHours,omitempty"`
+	LocalZoneMinutes *int       `json:"localZoneMinutes,omitempty"`
+}
+
+// NMEAHeading is an HDT true heading
+type NMEAHeading struct {
+	NMEASentence
+	Heading *float64 `json:"heading,omitempty"` // ° true
+}
+
+// NMEAAttitude is a $PSSN,HRP heading, roll and pitch
+type NMEAAttitude struct {
+	NMEASentence
+	Time              *time.Time `json:"time,omitempty"`
+	Heading           *float64   `json:"heading,omitempty"`       // °
+	Roll              *float64   `json:"roll,omitempty"`          // °
+	Pitch             *float64   `json:"pitch,omitempty"`         // °
+	HeadingStdDev     *float64   `json:"headingStdDev,omitempty"` // °
+	RollStdDev        *float64   `json:"rollStdDev,omitempty"`    // °
+	PitchStdDev       *float64   `json:"pitchStdDev,omitempty"`   // °
+	NrSV              *int       `json:"nrSV,omitempty"`
+	Mode              *int       `json:"mode,omitempty"`              // Attitude mode as defined by the receiver reference guide
+	MagneticVariation *float64   `json:"magneticVariation,omitempty"` // °, east positive
+}
+
+// NMEAOther is a sentence without a dedicated decoder
+type NMEAOther struct {
+	NMEASentence
+	Fields []string `json:"fields"`
+}
+
+var ggaQualities = map[uint8]string{
+	0: "invalid",
+	1: "gps",
+	2: "differential",
+	3: "pps",
+	4: "rtkFixed",
+	5: "rtkFloat",
+	6: "deadReckoning",
+	7: "manual",
+	8: "simulation",
+}
+
+var gsaFixTypes = map[uint8]string{
+	1: "noFix",
+	2: "2D",
+	3: "3D",
+}
+
+// nmeaChecksum validates the checksum of a sentence and returns the data
+// between the $ and the *, e.g. GPGGA,...
+func nmeaChecksum(sentence string) (string, error) {
+	sentence = strings.TrimRight(sentence, "\r\n")
+	if len(sentence) < 1 || (sentence[0] != '$' && sentence[0] != '!') {
+		return "", fmt.Errorf("sentence does not start with $")
+	}
+	star := strings.LastIndexByte(sentence, '*')
+	if star < 0 || len(sentence)-star != 3 {
+		return "", fmt.Errorf("sentence has no checksum")
+	}
+	expected, err := strconv.ParseUint(sentence[star+1:], 16, 8)
+	if err != nil {
+		return "", fmt.Errorf("invalid checksum %s", sentence[star+1:])
+	}
+
+	// The checksum is the XOR of all characters between the $ and the *
+	var actual uint8
+	for i := 1; i < star; i++ {
+		actual ^= sentence[i]
+	}
+	if uint64(actual) != expected {
+		return "", fmt.Errorf("checksum error. Expected: %02X, calculated: %02X", expected, actual)
+	}
+	return sentence[1:star], nil
+}
+
+// handleNMEASentence decodes a complete sentence, including its checksum
+func handleNMEASentence(sentence string) []interface{} {
+	data, err := nmeaChecksum(sentence)
+	if err != nil {
+		log.Printf("[ERROR] handleNMEASentence - Invalid NMEA sentence %q: %s\n", strings.TrimRight(sentence, "\r\n"), err.Error())
+		return []interface{}{}
+	}
+	fields := strings.Split(data, ",")
+	if len(fields[0]) < 3 {
+		return []interface{}{}
+	}
+
+	// Proprietary sentences have a P talker followed by the manufacturer
+	// code, the Septentrio sentences are identified by their second field
+	id := NMEASentence{Talker: fields[0][:2], Sentence: fields[0][2:]}
+	if fields[0][0] == 'P' {
+		id.Talker = "P"
+		id.Sentence = fields[0][1:]
+		if id.Sentence == "SSN" && len(fields) > 1 {
+			id.Sentence += "," + fields[1]
+			return []interface{}{Payload{Topic: nmeaTopic + "/pssn/" + strings.ToLower(fields[1]), Data: decodePSSN(id, fields)}}
+		}
+	}
+
+	var record interface{}
+	switch id.Sentence {
+	case "GGA":
+		record = decodeGGA(id, fields)
+	case "RMC":
+		record = decodeRMC(id, fields)
+	case "GSA":
+		record = decodeGSA(id, fields)
+	case "GSV":
+		record = decodeGSV(id, fields)
+	case "GST":
+		record = decodeGST(id, fields)
+	case "VTG":
+		record = decodeVTG(id, fields)
+	case "ZDA":
+		record = decodeZDA(id, fields)
+	case "HDT":
+		record = NMEAHeading{NMEASentence: id, Heading: nmeaFloat(fields, 1)}
+	default:
+		return []interface{}{Payload{Topic: nmeaTopic + "/other", Data: NMEAOther{NMEASentence: id, Fields: fields[1:]}}}
+	}
+	return []interface{}{Payload{Topic: nmeaTopic + "/" + strings.ToLower(id.Sentence), Data: record}}
+}
+
+func decodeGGA(id NMEASentence, fields []string) NMEAFix {
+	fix := NMEAFix{
+		NMEASentence:    id,
+		UTCTime:         nmeaTimeOfDay(nmeaField(fields, 1)),
+		Latitude:        nmeaCoordinate(nmeaField(fields, 2), nmeaField(fields, 3)),
+		Longitude:       nmeaCoordinate(nmeaField(fields, 4), nmeaField(fields, 5)),
+		Quality:         "invalid",
+		NrSV:            nmeaInt(fields, 7),
+		HDOP:            nmeaFloat(fields, 8),
+		Altitude:        nmeaFloat(fields, 9),
+		GeoidSeparation: nmeaFloat(fields, 11),
+		DifferentialAge: nmeaFloat(fields, 13),
+		StationID:       nmeaField(fields, 14),
+	}
+	if quality := nmeaInt(fields, 6); quality != nil {
+		fix.Quality = mapString(ggaQualities, uint8(*quality))
+	}
+	return fix
+}
+
+func decodeRMC(id NMEASentence, fields []string) NMEARecommendedMinimum {
+	rmc := NMEARecommendedMinimum{
+		NMEASentence:     id,
+		Time:             nmeaDateTime(nmeaField(fields, 9), nmeaField(fields, 1)),
+		Valid:            nmeaField(fields, 2) == "A",
+		Latitude:         nmeaCoordinate(nmeaField(fields, 3), nmeaField(fields, 4)),
+		Longitude:        nmeaCoordinate(nmeaField(fields, 5), nmeaField(fields, 6)),
+		SpeedOverGround:  nmeaFloat(fields, 7),
+		CourseOverGround: nmeaFloat(fields, 8),
+		Mode:             nmeaField(fields, 12),
+	}
+	if variation := nmeaFloat(fields, 10); variation != nil {
+		if nmeaField(fields, 11) == "W" {
+			*variation = -*variation
+		}
+		rmc.MagneticVariation = variation
+	}
+	return rmc
+}
+
+func decodeGSA(id NMEASentence, fields []string) NMEADOP {
+	dop := NMEADOP{
+		NMEASentence: id,
+		Mode:         nmeaField(fields, 1),
+		FixType:      "noFix",
+		Satellites:   []int{},
+		PDOP:         nmeaFloat(fields, 15),
+		HDOP:         nmeaFloat(fields, 16),
+		VDOP:         nmeaFloat(fields, 17),
+		SystemID:     nmeaInt(fields, 18),
+	}
+	if fixType := nmeaInt(fields, 2); fixType != nil {
+		dop.FixType = mapString(gsaFixTypes, uint8(*fixType))
+	}
+	for i := 3; i <= 14; i++ {
+		if prn := nmeaInt(fields, i); prn != nil {
+			dop.Satellites = append(dop.Satellites, *prn)
+		}
+	}
+	return dop
+}
+
+func decodeGSV(id NMEASentence, fields []string) NMEASatellitesInView {
+	gsv := NMEASatellitesInView{
+		NMEASentence: id,
+		Satellites:   []NMEASatelliteView{},
+	}
+	if value := nmeaInt(fields, 1); value != nil {
+		gsv.NrMessages = *value
+	}
+	if value := nmeaInt(fields, 2); value != nil {
+		gsv.MessageNr = *value
+	}
+	if value := nmeaInt(fields, 3); value != nil {
+		gsv.SatellitesInView = *value
+	}
+
+	// Up to four satellites of four fields, followed by the signal ID since NMEA 4.10
+	i := 4
+	for ; i+4 <= len(fields); i += 4 {
+		prn := nmeaInt(fields, i)
+		if prn == nil {
+			continue
+		}
+		gsv.Satellites = append(gsv.Satellites, NMEASatelliteView{
+			PRN:       *prn,
+			Elevation: nmeaFloat(fields, i+1),
+			Azimuth:   nmeaFloat(fields, i+2),
+			SNR:       nmeaFloat(fields, i+3),
+		})
+	}
+	if i < len(fields) {
+		gsv.SignalID = nmeaInt(fields, i)
+	}
+	return gsv
+}
+
+func decodeGST(id NMEASentence, fields []string) NMEAPseudorangeNoise {
+	return NMEAPseudorangeNoise{
+		NMEASentence:    id,
+		UTCTime:         nmeaTimeOfDay(nmeaField(fields, 1)),
+		RMS:             nmeaFloat(fields, 2),
+		SemiMajor:       nmeaFloat(fields, 3),
+		SemiMinor:       nmeaFloat(fields, 4),
+		Orientation:     nmeaFloat(fields, 5),
+		LatitudeStdDev:  nmeaFloat(fields, 6),
+		LongitudeStdDev: nmeaFloat(fields, 7),
+		AltitudeStdDev:  nmeaFloat(fields, 8),
+	}
+}
+
+func decodeVTG(id NMEASentence, fields []string) NMEACourseOverGround {
+	return NMEACourseOverGround{
+		NMEASentence:   id,
+		CourseTrue:     nmeaFloat(fields, 1),
+		CourseMagnetic: nmeaFloat(fields, 3),
+		SpeedKnots:     nmeaFloat(fields, 5),
+		SpeedKmh:       nmeaFloat(fields, 7),
+		Mode:           nmeaField(fields, 9),
+	}
+}
+
+func decodeZDA(id NMEASentence, fields []string) NMEATimeDate {
+	zda := NMEATimeDate{
+		NMEASentence:     id,
+		LocalZoneHours:   nmeaInt(fields, 5),
+		LocalZoneMinutes: nmeaInt(fields, 6),
+	}
+	day, month, year := nmeaInt(fields, 2), nmeaInt(fields, 3), nmeaInt(fields, 4)
+	if day != nil && month != nil && year != nil {
+		zda.Time = nmeaDateTime(fmt.Sprintf("%02d%02d%02d", *day, *month, *year%100), nmeaField(fields, 1))
+	}
+	return zda
+}
+
+// decodePSSN decodes the Septentrio proprietary sentences. Only HRP has a
+// dedicated record, the others are published with their fields unparsed.
+func decodePSSN(id NMEASentence, fields []string) interface{} {
+	if fields[1] != "HRP" {
+		return NMEAOther{NMEASentence: id, Fields: fields[2:]}
+	}
+
+	hrp := NMEAAttitude{
+		NMEASentence:  id,
+		Time:          nmeaDateTime(nmeaField(fields, 3), nmeaField(fields, 2)),
+		Heading:       nmeaFloat(fields, 4),
+		Roll:          nmeaFloat(fields, 5),
+		Pitch:         nmeaFloat(fields, 6),
+		HeadingStdDev: nmeaFloat(fields, 7),
+		RollStdDev:    nmeaFloat(fields, 8),
+		PitchStdDev:   nmeaFloat(fields, 9),
+		NrSV:          nmeaInt(fields, 10),
+		Mode:          nmeaInt(fields, 11),
+	}
+	if variation := nmeaFloat(fields, 12); variation != nil {
+		if nmeaField(fields, 13) == "W" {
+			*variation = -*variation
+		}
+		hrp.MagneticVariation = variation
+	}
+	return hrp
+}
+
+// nmeaField returns field i of a sentence, or an empty string when the
+// sentence is too short
+func nmeaField(fields []string, i int) string {
+	if i >= len(fields) {
+		return ""
+	}
+	return fields[i]
+}
+
+// nmeaFloat returns nil for empty and invalid fields
+func nmeaFloat(fields []string, i int) *float64 {
+	value, err := strconv.ParseFloat(nmeaField(fields, i), 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+func nmeaInt(fields []string, i int) *int {
+	value, err := strconv.Atoi(nmeaField(fields, i))
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// nmeaCoordinate converts a (d)ddmm.mmmm field and its hemisphere to degrees
+func nmeaCoordinate(field string, hemisphere string) *float64 {
+	value, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return nil
+	}
+	degrees := float64(int(value/100)) + (value-float64(int(value/100))*100)/60
+	if hemisphere == "S" || hemisphere == "W" {
+		degrees = -degrees
+	}
+	return &degrees
+}
+
+// nmeaTimeOfDay formats an hhmmss.ss field as hh:mm:ss.ss
+func nmeaTimeOfDay(field string) string {
+	if len(field) < 6 {
+		return ""
+	}
+	return field[0:2] + ":" + field[2:4] + ":" + field[4:]
+}
+
+// nmeaDateTime combines a ddmmyy date and hhmmss.ss time field to a UTC time
+func nmeaDateTime(date string, timeOfDay string) *time.Time {
+	if len(date) != 6 || len(timeOfDay) < 6 {
+		return nil
+	}
+	t, err := time.Parse("020106150405", date+timeOfDay[:6])
+	if err != nil {
+		return nil
+	}
+	if len(timeOfDay) > 6 {
+		if fraction, err := strconv.ParseFloat("0"+timeOfDay[6:], 64); err == nil {
+			t = t.Add(time.Duration(fraction * float64(time.Second)))
+		}
+	}
+	return &t
+}
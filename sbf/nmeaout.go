@@ -0,0 +1,454 @@
+package sbf
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+/**
+ * NMEA 0183 output generated from SBF, for consumers that only speak NMEA.
+ * Every PVTGeodetic epoch produces GGA, RMC, GSA, GSV, GST, VTG and ZDA
+ * sentences, completed with the latest DOP, PosCovGeodetic and SatVisibility
+ * blocks, and every AttEuler block an HDT sentence. The sentences of an epoch
+ * are published together as raw text on NMEAOutputTopic, CRLF terminated, so
+ * the adapter can also serve them as they are on a TCP port.
+ */
+
+// NMEAOutputTopic is the topic of the generated NMEA sentences
+const NMEAOutputTopic = "nmea/output"
+
+/* Blocks older than this, in seconds, are not used to complete a PVT epoch */
+const nmeaMaxBlockAge = 2
+
+const knotsPerMeterPerSecond = 3600.0 / 1852
+const kmhPerMeterPerSecond = 3.6
+
+/* NMEA 4.10 GNSS system IDs of the GSA sentences and talkers of the GSV ones */
+var nmeaSystemIDs = map[string]int{
+	"GPS":     1,
+	"SBAS":    1,
+	"GLONASS": 2,
+	"Galileo": 3,
+	"BeiDou":  4,
+	"QZSS":    5,
+	"NavIC":   6,
+}
+
+var nmeaTalkers = map[string]string{
+	"GPS":     "GP",
+	"SBAS":    "GP",
+	"GLONASS": "GL",
+	"Galileo": "GA",
+	"BeiDou":  "GB",
+	"QZSS":    "GQ",
+	"NavIC":   "GI",
+}
+
+/* Order of the GSA and GSV sentences of an epoch */
+var nmeaSystemOrder = []int{1, 2, 3, 4, 5, 6}
+
+// nmeaSentence adds the $, checksum and CRLF to the fields of a sentence
+func nmeaSentence(fields ...string) string {
+	data := strings.Join(fields, ",")
+	var checksum uint8
+	for i := 0; i < len(data); i++ {
+		checksum ^= data[i]
+	}
+	return fmt.Sprintf("$%s*%02X\r\n", data, checksum)
+}
+
+// nmeaSatelliteID numbers a satellite the NMEA way: GPS 1-32, SBAS 33-64,
+// GLONASS 65-96 and the other constellations their PRN. It returns 0 for
+// satellites NMEA cannot identify.
+func nmeaSatelliteID(svid uint8) (string, int) {
+	system, prn := satelliteSystem(svid)
+	switch system {
+	case "SBAS":
+		// satelliteSystem numbers SBAS satellites PRN - 100, NMEA PRN - 87
+		prn += 13
+	case "GLONASS":
+		if prn == 0 {
+			return system, 0
+		}
+		prn += 64
+	case "":
+		return system, 0
+	}
+	return system, prn
+}
+
+// nmeaGGAQuality maps an SBF PVT mode to the GGA quality indicator
+func nmeaGGAQuality(mode uint8) string {
+	switch mode & 0x0F {
+	case MODE_STAND_ALONE_PVT:
+		return "1"
+	case MODE_DIFFERENTIAL_PVT, MODE_SBAS_AIDED_PVT, MODE_PPP_FLOAT_AMBIGUITIES, MODE_PPP_FIXED_AMBIGUITIES:
+		return "2"
+	case MODE_RTK_FIXED_AMBIGUITIES, MODE_MOVBASERTK_FIXED_AMBIGUITIES:
+		return "4"
+	case MODE_RTK_FLOAT_AMBIGUITIES, MODE_MOVBASERTK_FLOAT_AMBIGUITIES, MODE_RTK_FLOAT_WL_FIXED_AMBIGUITIES:
+		return "5"
+	case MODE_FIXED_LOCATION:
+		return "7"
+	}
+	return "0"
+}
+
+// nmeaModeIndicator maps an SBF PVT mode to the RMC and VTG mode indicator
+func nmeaModeIndicator(mode uint8) string {
+	switch nmeaGGAQuality(mode) {
+	case "1":
+		return "A"
+	case "2":
+		return "D"
+	case "4":
+		return "R"
+	case "5":
+		return "F"
+	case "7":
+		return "M"
+	}
+	return "N"
+}
+
+// nmeaLatitude formats a latitude in radians as ddmm.mmmmmmm,N
+func nmeaLatitude(lat float64) (string, string) {
+	hemisphere := "N"
+	if lat < 0 {
+		hemisphere = "S"
+	}
+	return nmeaDegreesMinutes(math.Abs(lat*180/math.Pi), "%02d"), hemisphere
+}
+
+// nmeaLongitude formats a longitude in radians as dddmm.mmmmmmm,E
+func nmeaLongitude(lon float64) (string, string) {
+	hemisphere := "E"
+	if lon < 0 {
+		hemisphere = "W"
+	}
+	return nmeaDegreesMinutes(math.Abs(lon*180/math.Pi), "%03d"), hemisphere
+}
+
+func nmeaDegreesMinutes(degrees float64, degreesFormat string) string {
+	d := math.Floor(degrees)
+	minutes := (degrees - d) * 60
+	// Carry minutes rounded up to 60 into the degrees
+	if minutes >= 59.99999995 {
+		d++
+		minutes = 0
+	}
+	return fmt.Sprintf(degreesFormat+"%010.7f", int(d), minutes)
+}
+
+func nmeaTimeField(t time.Time) string {
+	return fmt.Sprintf("%02d%02d%05.2f", t.Hour(), t.Minute(), float64(t.Second())+float64(t.Nanosecond())/1e9)
+}
+
+func nmeaOptional(value *float64, format string) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf(format, *value)
+}
+
+// pvtBlockCurrent tells whether a block completing the epoch at tow is recent
+// enough to be used
+func pvtBlockCurrent(blockTOW uint32, blockWNc uint16, tow uint32, wnc uint16) bool {
+	blockTime, ok := gpsTime(blockTOW, blockWNc)
+	if !ok {
+		return false
+	}
+	epochTime, ok := gpsTime(tow, wnc)
+	return ok && math.Abs(epochTime.Sub(blockTime).Seconds()) <= nmeaMaxBlockAge
+}
+
+func handleDOP(buffer []byte) []interface{} {
+	block := DOP_2_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleDOP - Error decoding block: %s\n", err.Error())
+		return nil
+	}
+	state.pvtDOP = &block
+	return nil
+}
+
+func handlePosCovGeodetic(buffer []byte) []interface{} {
+	block := PosCovGeodetic_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handlePosCovGeodetic - Error decoding block: %s\n", err.Error())
+		return nil
+	}
+	state.pvtCovariance = &block
+	return nil
+}
+
+// handlePVTGeodetic adds a PVT epoch to the track, monitors its correction age
+// and generates its NMEA sentences. Revisions 0 and 1 are decoded into the
+// revision 2 struct, their fields are a prefix of it.
+func handlePVTGeodetic(buffer []byte) []interface{} {
+	block := PVTGeodetic_2_2_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handlePVTGeodetic - Error decoding block: %s\n", err.Error())
+		return nil
+	}
+	blockTime := newBlockTime(block.TOW, block.WNc)
+	recordTrackPoint(block, blockTime)
+	payloads := updateCorrectionAge(block, blockTime)
+	if !settings.NMEAOutput || blockTime.Time == nil {
+		return payloads
+	}
+	t := *blockTime.Time
+
+	var sentences []string
+	sentences = append(sentences, nmeaGGA(block, t))
+	sentences = append(sentences, nmeaRMC(block, t))
+	sentences = append(sentences, nmeaGSA(block)...)
+	sentences = append(sentences, nmeaGSV()...)
+	if gst := nmeaGST(block, t); gst != "" {
+		sentences = append(sentences, gst)
+	}
+	sentences = append(sentences, nmeaVTG(block))
+	sentences = append(sentences, nmeaSentence("GNZDA", nmeaTimeField(t), fmt.Sprintf("%02d", t.Day()),
+		fmt.Sprintf("%02d", int(t.Month())), fmt.Sprintf("%04d", t.Year()), "00", "00"))
+
+	return append(payloads, Payload{Topic: NMEAOutputTopic, Data: []byte(strings.Join(sentences, ""))})
+}
+
+// handleAttEuler generates the HDT sentence of an attitude epoch
+func handleAttEuler(buffer []byte) []interface{} {
+	if !settings.NMEAOutput {
+		return nil
+	}
+	block := AttEuler_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleAttEuler - Error decoding block: %s\n", err.Error())
+		return nil
+	}
+	heading := optFloat(block.Heading)
+	if heading == nil || block.Mode == SBF_ATT_MODE_NOT_AVAILABLE {
+		return nil
+	}
+	hdt := nmeaSentence("GNHDT", fmt.Sprintf("%.2f", *heading), "T")
+	return []interface{}{Payload{Topic: NMEAOutputTopic, Data: []byte(hdt)}}
+}
+
+func nmeaHasFix(block PVTGeodetic_2_2_t) bool {
+	return block.Mode&0x0F != MODE_NO_PVT_AVAILABLE && optDouble(block.Lat) != nil && optDouble(block.Lon) != nil
+}
+
+func nmeaGGA(block PVTGeodetic_2_2_t, t time.Time) string {
+	fields := []string{"GNGGA", nmeaTimeField(t), "", "", "", "", nmeaGGAQuality(block.Mode), "", "", "", "M", "", "M", "", ""}
+	if !nmeaHasFix(block) {
+		fields[6] = "0"
+		return nmeaSentence(fields...)
+	}
+	fields[2], fields[3] = nmeaLatitude(float64(block.Lat))
+	fields[4], fields[5] = nmeaLongitude(float64(block.Lon))
+	if block.NrSV != 255 {
+		fields[7] = fmt.Sprintf("%02d", block.NrSV)
+	}
+	if state.pvtDOP != nil && pvtBlockCurrent(state.pvtDOP.TOW, state.pvtDOP.WNc, block.TOW, block.WNc) {
+		fields[8] = nmeaOptional(optScaled(int64(state.pvtDOP.HDOP), 0, 0.01), "%.1f")
+	}
+	// GGA gives the altitude above the geoid and the geoid separation
+	if alt := optDouble(block.Alt); alt != nil {
+		undulation := optFloat(block.Undulation)
+		if undulation == nil {
+			fields[9] = fmt.Sprintf("%.3f", *alt)
+		} else {
+			fields[9] = fmt.Sprintf("%.3f", *alt-*undulation)
+			fields[11] = fmt.Sprintf("%.3f", *undulation)
+		}
+	}
+	if quality := fields[6]; quality != "1" && quality != "7" {
+		fields[13] = nmeaOptional(optScaled(int64(block.MeanCorrAge), 65535, 0.01), "%.1f")
+		if fields[13] != "" && block.ReferenceId != 65535 {
+			fields[14] = fmt.Sprintf("%04d", block.ReferenceId)
+		}
+	}
+	return nmeaSentence(fields...)
+}
+
+// nmeaSpeed returns the horizontal speed (m/s) and course (°) of a PVT
+// epoch, or nil when the receiver has no velocity
+func nmeaSpeed(block PVTGeodetic_2_2_t) (*float64, *float64) {
+	vn, ve := optFloat(block.Vn), optFloat(block.Ve)
+	if vn == nil || ve == nil {
+		return nil, nil
+	}
+	speed := math.Hypot(*vn, *ve)
+	return &speed, optFloat(block.COG)
+}
+
+func nmeaRMC(block PVTGeodetic_2_2_t, t time.Time) string {
+	fields := []string{"GNRMC", nmeaTimeField(t), "V", "", "", "", "", "", "",
+		fmt.Sprintf("%02d%02d%02d", t.Day(), int(t.Month()), t.Year()%100), "", "", nmeaModeIndicator(block.Mode)}
+	if !nmeaHasFix(block) {
+		return nmeaSentence(fields...)
+	}
+	fields[2] = "A"
+	fields[3], fields[4] = nmeaLatitude(float64(block.Lat))
+	fields[5], fields[6] = nmeaLongitude(float64(block.Lon))
+	if speed, course := nmeaSpeed(block); speed != nil {
+		fields[7] = fmt.Sprintf("%.3f", *speed*knotsPerMeterPerSecond)
+		fields[8] = nmeaOptional(course, "%.2f")
+	}
+	return nmeaSentence(fields...)
+}
+
+func nmeaVTG(block PVTGeodetic_2_2_t) string {
+	fields := []string{"GNVTG", "", "T", "", "M", "", "N", "", "K", nmeaModeIndicator(block.Mode)}
+	if speed, course := nmeaSpeed(block); speed != nil && nmeaHasFix(block) {
+		fields[1] = nmeaOptional(course, "%.2f")
+		fields[5] = fmt.Sprintf("%.3f", *speed*knotsPerMeterPerSecond)
+		fields[7] = fmt.Sprintf("%.3f", *speed*kmhPerMeterPerSecond)
+	}
+	return nmeaSentence(fields...)
+}
+
+// nmeaGSA lists the satellites used in the PVT, one sentence per
+// constellation as NMEA 4.10 requires for GN talkers
+func nmeaGSA(block PVTGeodetic_2_2_t) []string {
+	fixType := "1"
+	if nmeaHasFix(block) {
+		fixType = "3"
+		if block.Mode&MODE_2D_PVT != 0 {
+			fixType = "2"
+		}
+	}
+
+	used := map[int][]int{}
+	for svid, tracking := range state.skyTracking {
+		system, id := nmeaSatelliteID(svid)
+		if tracking.used && id != 0 {
+			used[nmeaSystemIDs[system]] = append(used[nmeaSystemIDs[system]], id)
+		}
+	}
+
+	dops := []string{"", "", ""}
+	if state.pvtDOP != nil && pvtBlockCurrent(state.pvtDOP.TOW, state.pvtDOP.WNc, block.TOW, block.WNc) {
+		dops[0] = nmeaOptional(optScaled(int64(state.pvtDOP.PDOP), 0, 0.01), "%.1f")
+		dops[1] = nmeaOptional(optScaled(int64(state.pvtDOP.HDOP), 0, 0.01), "%.1f")
+		dops[2] = nmeaOptional(optScaled(int64(state.pvtDOP.VDOP), 0, 0.01), "%.1f")
+	}
+
+	var sentences []string
+	for _, systemID := range nmeaSystemOrder {
+		ids := used[systemID]
+		if len(ids) == 0 && (systemID != 1 || len(used) > 0) {
+			// Without satellites used a single empty GPS sentence is sent
+			continue
+		}
+		sort.Ints(ids)
+		// A GSA sentence has room for 12 satellites
+		for start := 0; start == 0 || start < len(ids); start += 12 {
+			fields := []string{"GNGSA", "A", fixType}
+			for i := start; i < start+12; i++ {
+				if i < len(ids) {
+					fields = append(fields, fmt.Sprintf("%02d", ids[i]))
+				} else {
+					fields = append(fields, "")
+				}
+			}
+			fields = append(fields, dops...)
+			fields = append(fields, fmt.Sprintf("%X", systemID))
+			sentences = append(sentences, nmeaSentence(fields...))
+		}
+	}
+	return sentences
+}
+
+// updateNMEASignalStrengths keeps the C/N0 of the satellites of a MeasEpoch
+// for the SNR fields of the GSV sentences
+func updateNMEASignalStrengths(observations Observations) {
+	strengths := map[string]float64{}
+	for _, satellite := range observations.Satellites {
+		if len(satellite.Signals) > 0 && satellite.Signals[0].CN0 != nil {
+			strengths[satellite.Satellite] = *satellite.Signals[0].CN0
+		}
+	}
+	state.nmeaSignalStrengths = strengths
+}
+
+// nmeaGSV lists the satellites in view from SatVisibility, one group of
+// sentences per constellation. The SNR fields are only filled when MeasEpoch
+// is output too.
+func nmeaGSV() []string {
+	type view struct {
+		id        int
+		name      string
+		elevation *float64
+		azimuth   *float64
+	}
+	views := map[int][]view{}
+	talkers := map[int]string{}
+	for svid, visibility := range state.skyVisibility {
+		system, id := nmeaSatelliteID(svid)
+		if id == 0 || visibility.elevation == nil || *visibility.elevation < 0 {
+			continue
+		}
+		systemID := nmeaSystemIDs[system]
+		views[systemID] = append(views[systemID], view{id, satelliteName(svid), visibility.elevation, visibility.azimuth})
+		talkers[systemID] = nmeaTalkers[system]
+	}
+
+	var sentences []string
+	for _, systemID := range nmeaSystemOrder {
+		satellites := views[systemID]
+		if len(satellites) == 0 {
+			continue
+		}
+		sort.Slice(satellites, func(i, j int) bool { return satellites[i].id < satellites[j].id })
+		count := (len(satellites) + 3) / 4
+		for n := 0; n < count; n++ {
+			fields := []string{talkers[systemID] + "GSV", fmt.Sprintf("%d", count), fmt.Sprintf("%d", n+1), fmt.Sprintf("%02d", len(satellites))}
+			for i := n * 4; i < n*4+4 && i < len(satellites); i++ {
+				s := satellites[i]
+				azimuth := ""
+				if s.azimuth != nil {
+					azimuth = fmt.Sprintf("%03.0f", math.Mod(math.Round(*s.azimuth), 360))
+				}
+				snr := ""
+				if cn0, ok := state.nmeaSignalStrengths[s.name]; ok {
+					snr = fmt.Sprintf("%02.0f", math.Round(cn0))
+				}
+				fields = append(fields, fmt.Sprintf("%02d", s.id), fmt.Sprintf("%02.0f", math.Round(*s.elevation)), azimuth, snr)
+			}
+			sentences = append(sentences, nmeaSentence(fields...))
+		}
+	}
+	return sentences
+}
+
+// nmeaGST gives the error ellipse and standard deviations of the position
+// from the PosCovGeodetic of the epoch, or "" when there is none
+func nmeaGST(block PVTGeodetic_2_2_t, t time.Time) string {
+	cov := state.pvtCovariance
+	if cov == nil || !nmeaHasFix(block) || !pvtBlockCurrent(cov.TOW, cov.WNc, block.TOW, block.WNc) {
+		return ""
+	}
+	latLat, lonLon, altAlt := optFloat(cov.Cov_LatLat), optFloat(cov.Cov_LonLon), optFloat(cov.Cov_AltAlt)
+	latLon := optFloat(cov.Cov_LatLon)
+	if latLat == nil || lonLon == nil || altAlt == nil || latLon == nil {
+		return ""
+	}
+
+	// Eigen decomposition of the horizontal covariance, the orientation is
+	// the angle of the semi-major axis from true north
+	mean := (*latLat + *lonLon) / 2
+	radius := math.Hypot((*latLat-*lonLon)/2, *latLon)
+	major := math.Sqrt(math.Max(mean+radius, 0))
+	minor := math.Sqrt(math.Max(mean-radius, 0))
+	orientation := math.Atan2(2**latLon, *latLat-*lonLon) / 2 * 180 / math.Pi
+	if orientation < 0 {
+		orientation += 180
+	}
+
+	return nmeaSentence("GNGST", nmeaTimeField(t), "", fmt.Sprintf("%.3f", major), fmt.Sprintf("%.3f", minor),
+		fmt.Sprintf("%.1f", orientation), fmt.Sprintf("%.3f", math.Sqrt(*latLat)),
+		fmt.Sprintf("%.3f", math.Sqrt(*lonLon)), fmt.Sprintf("%.3f", math.Sqrt(*altAlt)))
+}
@@ -0,0 +1,71 @@
+package sbf
+
+import "fmt"
+
+/**
+ * Receiver side of the adapter's NTRIP client: the latest position of the
+ * receiver as a GGA sentence, sent to VRS mountpoints, and the monitoring of
+ * the age of the corrections the receiver uses. The correctionAge alarm works
+ * for corrections from any source, not only the adapter's NTRIP client.
+ */
+
+const ntripAlarmTopic = "ntrip/alarm"
+
+// updateCorrectionAge keeps the GGA sentence and the mean correction age of a
+// PVT epoch and checks the correction age against its limit
+func updateCorrectionAge(block PVTGeodetic_2_2_t, blockTime BlockTime) []interface{} {
+	state.correctionAge = nil
+	if block.Mode&0x0F != MODE_NO_PVT_AVAILABLE && block.Mode&0x0F != MODE_STAND_ALONE_PVT {
+		state.correctionAge = optScaled(int64(block.MeanCorrAge), 65535, 0.01)
+	}
+	if blockTime.Time != nil && nmeaHasFix(block) {
+		state.ggaSentence = nmeaGGA(block, *blockTime.Time)
+	}
+
+	if settings.NTRIPCorrectionAgeLimit <= 0 || blockTime.Time == nil {
+		return nil
+	}
+	alarm := Alarm{
+		BlockTime: blockTime,
+		Alarm:     "correctionAge",
+		Active:    true,
+		Message:   "no corrections used",
+		Value:     state.correctionAge,
+		Limit:     floatPtr(settings.NTRIPCorrectionAgeLimit),
+	}
+	if state.correctionAge != nil {
+		alarm.Active = *state.correctionAge > settings.NTRIPCorrectionAgeLimit
+		alarm.Message = fmt.Sprintf("corrections are %.1f s old, limit is %.1f s", *state.correctionAge, settings.NTRIPCorrectionAgeLimit)
+	}
+	return updateAlarm(ntripAlarmTopic, alarm)
+}
+
+// LatestGGA returns the GGA sentence of the latest PVT epoch with a fix, or ""
+// before the receiver has a fix
+func (r *Receiver) LatestGGA() string {
+	parseMutex.Lock()
+	defer parseMutex.Unlock()
+
+	return r.state.ggaSentence
+}
+
+// CorrectionAge returns the mean age in s of the corrections used in the
+// latest PVT epoch, nil when no corrections were used
+func (r *Receiver) CorrectionAge() *float64 {
+	parseMutex.Lock()
+	defer parseMutex.Unlock()
+
+	return r.state.correctionAge
+}
+
+// LatestGGA returns the GGA sentence of the latest PVT epoch of the receiver
+// parsed by Parse
+func LatestGGA() string {
+	return defaultReceiver.LatestGGA()
+}
+
+// CorrectionAge returns the mean correction age of the latest PVT epoch of the
+// receiver parsed by Parse
+func CorrectionAge() *float64 {
+	return defaultReceiver.CorrectionAge()
+}
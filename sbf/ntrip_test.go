@@ -0,0 +1,67 @@
+package sbf
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+/**
+ * Checks the GGA sentence kept for the NTRIP client and the correctionAge
+ * alarm over a sequence of PVT epochs.
+ */
+
+func TestCorrectionAgeAlarm(t *testing.T) {
+	defer func(previousSettings Settings, previousState *decoderState) {
+		settings, state = previousSettings, previousState
+	}(settings, state)
+	settings.NTRIPCorrectionAgeLimit = 10
+	state = newDecoderState("")
+
+	epochs := []struct {
+		name        string
+		mode        uint8
+		meanCorrAge uint16 // 0.01 s
+		published   bool
+		active      bool
+		message     string
+	}{
+		{"young corrections", MODE_RTK_FIXED_AMBIGUITIES, 200, false, false, ""},
+		{"old corrections", MODE_RTK_FLOAT_AMBIGUITIES, 1500, true, true, "corrections are 15.0 s old, limit is 10.0 s"},
+		{"still old", MODE_RTK_FLOAT_AMBIGUITIES, 1200, false, true, ""},
+		{"young again", MODE_RTK_FIXED_AMBIGUITIES, 300, true, false, "corrections are 3.0 s old, limit is 10.0 s"},
+		{"stand-alone", MODE_STAND_ALONE_PVT, 65535, true, true, "no corrections used"},
+		{"differential", MODE_DIFFERENTIAL_PVT, 50, true, false, "corrections are 0.5 s old, limit is 10.0 s"},
+	}
+	for i, epoch := range epochs {
+		block := PVTGeodetic_2_2_t{
+			TOW:         uint32(100000 + i*1000),
+			WNc:         2300,
+			Mode:        epoch.mode,
+			Lat:         50.8 * math.Pi / 180,
+			Lon:         4.35 * math.Pi / 180,
+			NrSV:        12,
+			ReferenceId: 65535,
+			MeanCorrAge: epoch.meanCorrAge,
+		}
+		payloads := updateCorrectionAge(block, newBlockTime(block.TOW, block.WNc))
+		if !epoch.published {
+			if len(payloads) != 0 {
+				t.Errorf("%s: unexpected alarm %+v", epoch.name, payloads)
+			}
+			continue
+		}
+		if len(payloads) != 1 {
+			t.Fatalf("%s: expected an alarm, got %+v", epoch.name, payloads)
+		}
+		payload := payloads[0].(Payload)
+		alarm := payload.Data.(Alarm)
+		if payload.Topic != ntripAlarmTopic || alarm.Alarm != "correctionAge" || alarm.Active != epoch.active || alarm.Message != epoch.message {
+			t.Errorf("%s: alarm %s %+v, expected active %v with %q", epoch.name, payload.Topic, alarm, epoch.active, epoch.message)
+		}
+	}
+
+	if !strings.HasPrefix(state.ggaSentence, "$GNGGA,000127.00,5048.0000000,N,00421.0000000,E,2,12,") {
+		t.Errorf("GGA sentence %q", state.ggaSentence)
+	}
+}
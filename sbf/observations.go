@@ -0,0 +1,201 @@
+package sbf
+
+import (
+	"fmt"
+	"time"
+)
+
+/**
+ * Shapes of the GNSS observations and broadcast ephemerides, shared by the
+ * decoders of every input format so downstream users get the same records
+ * whether the receiver outputs SBF or RTCM3. Signals are identified by their
+ * RINEX 3 observation code, e.g. 1C for GPS L1 C/A, and satellites by their
+ * RINEX name, e.g. G05.
+ */
+
+const observationsTopic = "observations"
+const ephemerisTopic = "ephemeris"
+
+const speedOfLight = 299792458.0 // m/s
+
+// Observations are the measurements of one epoch
+type Observations struct {
+	BlockTime
+	Source     string                  `json:"source"`              // sbf or rtcm3
+	StationID  *uint16                 `json:"stationId,omitempty"` // RTCM3 reference station
+	Satellites []SatelliteObservations `json:"satellites"`
+}
+
+// SatelliteObservations are the measurements of one satellite
+type SatelliteObservations struct {
+	Satellite       string              `json:"satellite"` // e.g. G05
+	System          string              `json:"system"`
+	FrequencyNumber *int                `json:"frequencyNumber,omitempty"` // GLONASS, when known
+	Signals         []SignalObservation `json:"signals"`
+}
+
+// SignalObservation holds the measurements of one signal
+type SignalObservation struct {
+	Signal             string   `json:"signal"`                 // RINEX 3 observation code, e.g. 1C
+	Pseudorange        *float64 `json:"pseudorange,omitempty"`  // m
+	CarrierPhase       *float64 `json:"carrierPhase,omitempty"` // cycles
+	Doppler            *float64 `json:"doppler,omitempty"`      // Hz
+	CN0                *float64 `json:"cn0,omitempty"`          // dB-Hz
+	LockTime           *float64 `json:"lockTime,omitempty"`     // s
+	HalfCycleAmbiguity bool     `json:"halfCycleAmbiguity"`
+}
+
+// Ephemeris is the broadcast ephemeris and clock of a GPS, Galileo, BeiDou or
+// QZSS satellite. Weeks and times of week are in the time system of the
+// satellite as used by RINEX: GPS weeks for GPS, QZSS and Galileo, BDT weeks
+// for BeiDou.
+type Ephemeris struct {
+	BlockTime
+	Source       string   `json:"source"`
+	Satellite    string   `json:"satellite"`
+	System       string   `json:"system"`
+	Week         uint16   `json:"week"`
+	IODE         uint16   `json:"iode"`           // IODE, IODnav or AODE
+	IODC         *uint16  `json:"iodc,omitempty"` // IODC or AODC
+	Toe          float64  `json:"toe"`            // s of week
+	Toc          float64  `json:"toc"`            // s of week
+	SqrtA        float64  `json:"sqrtA"`          // m^0.5
+	Eccentricity float64  `json:"eccentricity"`
+	I0           float64  `json:"i0"`                 // rad
+	Omega0       float64  `json:"omega0"`             // rad
+	Omega        float64  `json:"omega"`              // rad
+	M0           float64  `json:"m0"`                 // rad
+	DeltaN       float64  `json:"deltaN"`             // rad/s
+	OmegaDot     float64  `json:"omegaDot"`           // rad/s
+	IDot         float64  `json:"iDot"`               // rad/s
+	Cuc          float64  `json:"cuc"`                // rad
+	Cus          float64  `json:"cus"`                // rad
+	Crc          float64  `json:"crc"`                // m
+	Crs          float64  `json:"crs"`                // m
+	Cic          float64  `json:"cic"`                // rad
+	Cis          float64  `json:"cis"`                // rad
+	Af0          float64  `json:"af0"`                // s
+	Af1          float64  `json:"af1"`                // s/s
+	Af2          float64  `json:"af2"`                // s/s²
+	TGD          *float64 `json:"tgd,omitempty"`      // s, BGD E1/E5a for Galileo, TGD1 for BeiDou
+	TGD2         *float64 `json:"tgd2,omitempty"`     // s, BGD E1/E5b for Galileo, TGD2 for BeiDou
+	Accuracy     *float64 `json:"accuracy,omitempty"` // m, URA or SISA
+	Health       uint16   `json:"health"`
+	CodesOnL2    *uint8   `json:"codesOnL2,omitempty"`
+	L2PDataFlag  *bool    `json:"l2pDataFlag,omitempty"`
+	FitInterval  *float64 `json:"fitInterval,omitempty"` // h
+	DataSources  *uint16  `json:"dataSources,omitempty"` // Galileo data sources as in RINEX
+}
+
+// GLONASSEphemeris is the broadcast ephemeris of a GLONASS satellite
+type GLONASSEphemeris struct {
+	BlockTime
+	Source          string     `json:"source"`
+	Satellite       string     `json:"satellite"`
+	System          string     `json:"system"`
+	FrequencyNumber int        `json:"frequencyNumber"`
+	Tb              uint16     `json:"tb"` // Minutes of the day, Moscow time
+	Toe             *time.Time `json:"toe,omitempty"`
+	Tk              uint32     `json:"tk"`            // s of the day, Moscow time
+	X               float64    `json:"x"`             // km, PZ-90
+	Y               float64    `json:"y"`             // km
+	Z               float64    `json:"z"`             // km
+	VelocityX       float64    `json:"velocityX"`     // km/s
+	VelocityY       float64    `json:"velocityY"`     // km/s
+	VelocityZ       float64    `json:"velocityZ"`     // km/s
+	AccelerationX   float64    `json:"accelerationX"` // km/s²
+	AccelerationY   float64    `json:"accelerationY"` // km/s²
+	AccelerationZ   float64    `json:"accelerationZ"` // km/s²
+	TauN            float64    `json:"tauN"`          // s
+	GammaN          float64    `json:"gammaN"`
+	DeltaTauN       float64    `json:"deltaTauN"` // s
+	Health          uint8      `json:"health"`    // Bn
+	Age             uint8      `json:"age"`       // En, days
+}
+
+/* Carrier frequencies (Hz) per system and RINEX band */
+var carrierFrequencies = map[string]map[byte]float64{
+	"GPS":     {'1': 1575.42e6, '2': 1227.60e6, '5': 1176.45e6},
+	"Galileo": {'1': 1575.42e6, '5': 1176.45e6, '6': 1278.75e6, '7': 1207.14e6, '8': 1191.795e6},
+	"BeiDou":  {'1': 1575.42e6, '2': 1561.098e6, '5': 1176.45e6, '6': 1268.52e6, '7': 1207.14e6, '8': 1191.795e6},
+	"QZSS":    {'1': 1575.42e6, '2': 1227.60e6, '5': 1176.45e6, '6': 1278.75e6},
+	"SBAS":    {'1': 1575.42e6, '5': 1176.45e6},
+	"NavIC":   {'5': 1176.45e6, '9': 2492.028e6},
+}
+
+/* RINEX satellite letter per system */
+var systemPrefixes = map[string]string{
+	"GPS":     "G",
+	"GLONASS": "R",
+	"Galileo": "E",
+	"BeiDou":  "C",
+	"QZSS":    "J",
+	"SBAS":    "S",
+	"NavIC":   "I",
+}
+
+// carrierFrequency returns the frequency of a signal, 0 when unknown. GLONASS
+// FDMA frequencies depend on the frequency number of the satellite.
+func carrierFrequency(system string, signal string, frequencyNumber *int) float64 {
+	if len(signal) == 0 {
+		return 0
+	}
+	if system == "GLONASS" {
+		switch signal[0] {
+		case '1':
+			if frequencyNumber != nil {
+				return 1602e6 + float64(*frequencyNumber)*0.5625e6
+			}
+		case '2':
+			if frequencyNumber != nil {
+				return 1246e6 + float64(*frequencyNumber)*0.4375e6
+			}
+		case '3':
+			return 1202.025e6
+		}
+		return 0
+	}
+	return carrierFrequencies[system][signal[0]]
+}
+
+// rinexSatellite names a satellite of a system by its number, e.g. G05
+func rinexSatellite(system string, number int) string {
+	prefix, ok := systemPrefixes[system]
+	if !ok {
+		return fmt.Sprintf("unknown(%d)", number)
+	}
+	return fmt.Sprintf("%s%02d", prefix, number)
+}
+
+// receptionBlockTime stamps data without a week number, such as RTCM3
+// messages, with the GPS week closest to the current time
+func receptionBlockTime(towMs uint32) BlockTime {
+	week, nowTow := currentGPSTime()
+	if int64(towMs)-int64(nowTow) > 302400000 {
+		week--
+	} else if int64(nowTow)-int64(towMs) > 302400000 {
+		week++
+	}
+	return newBlockTime(towMs, uint16(week))
+}
+
+// currentGPSTime returns the GPS week and time of week (ms) of the current
+// time, to stamp data and resolve the rollover of broadcast week numbers
+func currentGPSTime() (int, uint32) {
+	now := currentTime().UTC().Add(time.Duration(gpsUtcLeapSeconds) * time.Second).Sub(gpsEpoch)
+	week := 7 * 24 * time.Hour
+	return int(now / week), uint32((now % week) / time.Millisecond)
+}
+
+// fullWeek resolves a week number broadcast modulo 2^bits to the full week
+// closest to reference
+func fullWeek(week int, bits uint, reference int) int {
+	period := 1 << bits
+	full := week + (reference-week)/period*period
+	if full-reference > period/2 {
+		full -= period
+	} else if reference-full > period/2 {
+		full += period
+	}
+	return full
+}
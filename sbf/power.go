@@ -0,0 +1,128 @@
+package sbf
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+)
+
+/**
+ * Decoding of PowerStatus and BatteryStatus. A lowBattery alarm is raised
+ * while a battery's charge is below settings.LowBatteryPercent.
+ */
+
+const powerTopic = "power"
+const powerBatteryTopic = "power/battery"
+const powerAlarmTopic = "power/alarm"
+
+/* Offset of the first Battery sub-block in BatteryStatus */
+const batteryStatusSubBlockOffset = 20
+
+// PowerStatus is the power supply of the receiver
+type PowerStatus struct {
+	BlockTime
+	Source    string `json:"source"`
+	PowerInfo uint16 `json:"powerInfo"` // Raw PowerInfo bits
+}
+
+// BatteryStatus lists the receiver's batteries
+type BatteryStatus struct {
+	BlockTime
+	ExtSupply uint8     `json:"extSupply"`
+	Batteries []Battery `json:"batteries"`
+}
+
+// Battery is the state of one battery
+type Battery struct {
+	Battery       string   `json:"battery"`
+	ChargeLevel   *uint8   `json:"chargeLevel,omitempty"` // %
+	Status        string   `json:"status"`
+	RemainingTime *uint16  `json:"remainingTime,omitempty"` // min
+	Voltage       *float64 `json:"voltage,omitempty"`       // V
+	Current       *float64 `json:"current,omitempty"`       // A
+	Temperature   *int8    `json:"temperature,omitempty"`   // °C
+}
+
+var powerSources = map[uint8]string{
+	0: "unknown",
+	1: "external1",
+	2: "external2",
+	3: "battery",
+	4: "usb",
+}
+
+var batteryStates = map[uint8]string{
+	0: "notPresent",
+	1: "charging",
+	2: "discharging",
+	3: "full",
+}
+
+func handlePowerStatus(buffer []byte) []interface{} {
+	block := PowerStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handlePowerStatus - Error decoding PowerStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	// Bits 0-2 of PowerInfo hold the power source
+	return []interface{}{Payload{Topic: powerTopic, Data: PowerStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Source:    mapString(powerSources, uint8(block.PowerInfo&0x07)),
+		PowerInfo: block.PowerInfo,
+	}}}
+}
+
+func handleBatteryStatus(buffer []byte) []interface{} {
+	block := BatteryStatus_1_2_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleBatteryStatus - Error decoding BatteryStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	revision := block.Header.ID >> 13
+
+	status := BatteryStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		ExtSupply: block.ExtSupply,
+		Batteries: []Battery{},
+	}
+	payloads := []interface{}{}
+	for i := 0; i < int(block.N); i++ {
+		sb := Battery_1_2_t{}
+		if !decodeSizedSubBlock(buffer, batteryStatusSubBlockOffset+i*int(block.SBLength), int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleBatteryStatus - BatteryStatus block too short for %d batteries\n", block.N)
+			break
+		}
+		battery := Battery{
+			Battery: "battery" + strconv.Itoa(i+1),
+			Status:  mapString(batteryStates, sb.Status),
+		}
+		if sb.ChargeLevel != 255 {
+			battery.ChargeLevel = &sb.ChargeLevel
+		}
+		if sb.RemainingTime != 65535 {
+			battery.RemainingTime = &sb.RemainingTime
+		}
+		if revision >= 1 {
+			battery.Voltage = optScaled(int64(sb.Voltage), 65535, 0.001)
+			battery.Current = optScaled(int64(sb.Current), -32768, 0.001)
+		}
+		if revision >= 2 && sb.Temperature != -128 {
+			battery.Temperature = &sb.Temperature
+		}
+		status.Batteries = append(status.Batteries, battery)
+
+		if settings.LowBatteryPercent > 0 && battery.ChargeLevel != nil {
+			payloads = append(payloads, updateAlarm(powerAlarmTopic, Alarm{
+				BlockTime: status.BlockTime,
+				Alarm:     "lowBattery/" + battery.Battery,
+				Active:    float64(*battery.ChargeLevel) < settings.LowBatteryPercent,
+				Message:   fmt.Sprintf("%s charge %d%% (%s)", battery.Battery, *battery.ChargeLevel, battery.Status),
+				Value:     floatPtr(float64(*battery.ChargeLevel)),
+				Limit:     floatPtr(settings.LowBatteryPercent),
+			})...)
+		}
+	}
+
+	return append([]interface{}{Payload{Topic: powerBatteryTopic, Data: status}}, payloads...)
+}
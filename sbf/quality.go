@@ -0,0 +1,102 @@
+package sbf
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+/**
+ * Decoding of QualityInd into a scorecard. Every indicator is a 0-10 score,
+ * 10 being best. Indicators scoring below their threshold in
+ * settings.QualityThresholds raise a warning, so the scorecard gives a single
+ * healthy/unhealthy verdict for the receiver.
+ */
+
+const qualityTopic = "quality"
+const qualityAlarmTopic = "quality/alarm"
+
+// QualityScorecard holds the quality indicators the receiver reports
+type QualityScorecard struct {
+	BlockTime
+	Scores   map[string]uint8 `json:"scores"`   // 0-10, 10 being best
+	Healthy  bool             `json:"healthy"`  // No indicator scores below its threshold
+	Warnings []string         `json:"warnings"` // Indicators scoring below their threshold
+}
+
+var qualityIndicators = map[uint8]string{
+	QUALITYIND_OVERALL:        "overall",
+	QUALITYIND_MAINSIGNAL:     "mainSignals",
+	QUALITYIND_AUX1SIGNAL:     "aux1Signals",
+	QUALITYIND_MAINANTCABLING: "mainRFPower",
+	QUALITYIND_AUX1ANTCABLING: "aux1RFPower",
+	QUALITYIND_CPUHEADROOM:    "cpuHeadroom",
+	QUALITYIND_CLOCKSTABILITY: "ocxoStability",
+	QUALITYIND_BASEMEAS:       "baseMeasurements",
+	QUALITYIND_RTKPOSTPROCESS: "rtkPostProcessing",
+}
+
+// IsQualityIndicator reports whether name is the name of a QualityInd
+// indicator that can be given a threshold
+func IsQualityIndicator(name string) bool {
+	for _, indicator := range qualityIndicators {
+		if indicator == name {
+			return true
+		}
+	}
+	return false
+}
+
+func handleQualityInd(buffer []byte) []interface{} {
+	block := QualityInd_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleQualityInd - Error decoding QualityInd block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	scorecard := QualityScorecard{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Scores:    map[string]uint8{},
+		Healthy:   true,
+		Warnings:  []string{},
+	}
+	n := int(block.N)
+	if n > len(block.Indicators) {
+		log.Printf("[ERROR] handleQualityInd - Invalid number of quality indicators %d\n", block.N)
+		n = len(block.Indicators)
+	}
+	for _, indicator := range block.Indicators[:n] {
+		// Bits 0-7 hold the indicator type and bits 8-11 its value
+		scorecard.Scores[mapString(qualityIndicators, uint8(indicator))] = uint8(indicator>>8) & 0x0F
+	}
+
+	names := []string{}
+	for name := range settings.QualityThresholds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	payloads := []interface{}{}
+	for _, name := range names {
+		threshold := settings.QualityThresholds[name]
+		score, ok := scorecard.Scores[name]
+		if !ok {
+			continue
+		}
+		low := int(score) < threshold
+		if low {
+			scorecard.Healthy = false
+			scorecard.Warnings = append(scorecard.Warnings, name)
+		}
+		payloads = append(payloads, updateAlarm(qualityAlarmTopic, Alarm{
+			BlockTime: scorecard.BlockTime,
+			Alarm:     name,
+			Active:    low,
+			Message:   fmt.Sprintf("%s quality %d/10, threshold %d", name, score, threshold),
+			Value:     floatPtr(float64(score)),
+			Limit:     floatPtr(float64(threshold)),
+		})...)
+	}
+
+	return append([]interface{}{Payload{Topic: qualityTopic, Data: scorecard}}, payloads...)
+}
@@ -0,0 +1,244 @@
+package sbf
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/**
+ * Decoding state of a receiver. Every block handler reads and updates the
+ * state of the receiver whose data is parsed, so the counters, alarms, files
+ * and track of several receivers feeding one adapter stay apart. Receivers are
+ * parsed one at a time, Parse makes the receiver's state current for the
+ * handlers.
+ */
+
+// decoderState holds everything the block handlers remember between blocks
+type decoderState struct {
+	name string // Receiver name, a subdirectory of the output files when set
+
+	/* Current state of every alarm, keyed by topic and alarm name */
+	alarmStates map[string]bool
+
+	previousInputCounters  map[string]inputCounters
+	previousOutputCounters map[string]outputCounters
+	previousInputTime      *BlockTime
+	previousOutputTime     *BlockTime
+	/* Start of the receiver from the uptime of the latest ReceiverStatus, counters restart from zero then */
+	receiverBootTime *time.Time
+
+	/* Time (s since the GPS epoch) of the last corrections and station messages */
+	lastCorrectionsTime float64
+	lastStationTime     float64
+
+	extEvents map[string]extEventTimeTag
+	/* The latest ExtEventAttEuler, used for the attitude of geotags based on ExtEventPVTGeodetic */
+	extEventAttitude *ExtEventAttitude
+	/* Geotag file of the current session */
+	geotagFile    *os.File
+	geotagCounts  map[string]int
+	geotagFromINS bool
+
+	/* Number of ExtSensorMeas blocks received, used for decimation */
+	extSensorMeasCount int
+
+	/* AGC gains of the last blocks, keyed by frontend and antenna */
+	agcHistory map[string][]float64
+
+	/* Alignment state tracked over consecutive blocks to report its progress */
+	insAlignmentState string
+	insAlignmentSince time.Time
+
+	/* Inventory of the session and the version last published */
+	inventory          ReceiverInventory
+	publishedInventory *ReceiverInventory
+	receiverSetupSeen  bool
+
+	/* Whether the receiver showed a command prompt */
+	hasPrompt bool
+
+	/* GLONASS frequency numbers learned from message 1020 and MeasEpoch, for
+	 * the MSM4 carrier phases and RINEX headers */
+	glonassFrequencyNumbers map[int]int
+
+	/* Beams from the latest LBandBeams block, used to name the tracked beam */
+	lbandBeams map[uint8]string
+	/* CRC counters of the previous FugroStatus block, used for the error rate */
+	fugroCRCGoodCount   uint32
+	fugroCRCBadCount    uint32
+	fugroCRCCountsValid bool
+
+	/* Latest blocks completing the PVT epochs, also used by the track */
+	pvtDOP        *DOP_2_0_t
+	pvtCovariance *PosCovGeodetic_1_0_t
+	/* C/N0 (dB-Hz) of the first signal of each satellite in the latest MeasEpoch */
+	nmeaSignalStrengths map[string]float64
+	/* GGA sentence and mean correction age of the latest PVT epoch, for the NTRIP client */
+	ggaSentence   string
+	correctionAge *float64
+
+	jammingHysteresis  alarmHysteresis
+	spoofingHysteresis alarmHysteresis
+
+	/* Observation file and the last lock time of every signal */
+	rinexObservationFile rinexFile
+	rinexLockTimes       map[string]float64
+
+	/* Navigation file and the latest records, keyed by satellite and message */
+	rinexNavigationFile    rinexFile
+	rinexNavigationRecords map[string]rinexNavigationRecord
+	/* RINEX 3 header lines of the ionosphere and UTC parameters */
+	rinexIonosphereLines     map[string]string
+	rinexTimeCorrectionLines map[string]string
+	rinexLeapSecondsLine     string
+
+	/* Latest sky view parts, keyed by SVID */
+	skyVisibility map[uint8]satelliteVisibility
+	skyTracking   map[uint8]satelliteTracking
+
+	/* Failed transfers of every log session upload in the previous LogStatus block */
+	previousFailedTransfers map[string]uint8
+
+	/* Recent xPPSOffset values, used for the smoothed PPS offset */
+	ppsOffsets []float64
+
+	/* Kept track, oldest point first. The track is read by the MQTT requests
+	 * while the PVT epochs are added to it, so it is guarded by trackMutex. */
+	track      []trackPoint
+	trackInfo  trackDescription
+	trackMutex sync.Mutex
+	/* Points and files of the current rolling file period */
+	trackPeriod       time.Time
+	trackPeriodPoints []trackPoint
+	trackPeriodNames  map[string]string
+	trackLastWrite    time.Time
+}
+
+func newDecoderState(name string) *decoderState {
+	return &decoderState{
+		name:                     name,
+		alarmStates:              map[string]bool{},
+		previousInputCounters:    map[string]inputCounters{},
+		previousOutputCounters:   map[string]outputCounters{},
+		extEvents:                map[string]extEventTimeTag{},
+		geotagCounts:             map[string]int{},
+		agcHistory:               map[string][]float64{},
+		inventory:                ReceiverInventory{Components: []ReceiverComponent{}},
+		glonassFrequencyNumbers:  map[int]int{},
+		lbandBeams:               map[uint8]string{},
+		nmeaSignalStrengths:      map[string]float64{},
+		rinexObservationFile:     rinexFile{fileType: "MO"},
+		rinexLockTimes:           map[string]float64{},
+		rinexNavigationFile:      rinexFile{fileType: "MN"},
+		rinexNavigationRecords:   map[string]rinexNavigationRecord{},
+		rinexIonosphereLines:     map[string]string{},
+		rinexTimeCorrectionLines: map[string]string{},
+		skyVisibility:            map[uint8]satelliteVisibility{},
+		skyTracking:              map[uint8]satelliteTracking{},
+		previousFailedTransfers:  map[string]uint8{},
+		ppsOffsets:               []float64{},
+	}
+}
+
+/* State of the receiver being parsed, and the default receiver of Parse */
+var defaultReceiver = NewReceiver("")
+var state = defaultReceiver.state
+
+/* Serializes the parsing of the receivers, which share the current state */
+var parseMutex sync.Mutex
+
+// Receiver keeps the decoding state of one receiver
+type Receiver struct {
+	state *decoderState
+}
+
+// NewReceiver returns a receiver without decoding state. A named receiver
+// writes its geotag, RINEX and track files to a subdirectory of that name.
+func NewReceiver(name string) *Receiver {
+	return &Receiver{state: newDecoderState(name)}
+}
+
+// Parse decodes the complete messages in the buffer with the receiver's state
+// and removes them from it, see the package Parse
+func (r *Receiver) Parse(buffer *[]byte) []interface{} {
+	parseMutex.Lock()
+	defer parseMutex.Unlock()
+
+	state = r.state
+	return parse(buffer)
+}
+
+// Serial returns the serial number of the receiver from its ReceiverSetup
+// block, or "" until one is received
+func (r *Receiver) Serial() string {
+	parseMutex.Lock()
+	defer parseMutex.Unlock()
+
+	return r.state.inventory.ReceiverSerial
+}
+
+// SetName renames the receiver, the files opened next are written to the
+// subdirectory of the new name
+func (r *Receiver) SetName(name string) {
+	parseMutex.Lock()
+	defer parseMutex.Unlock()
+
+	r.state.name = name
+}
+
+// HandleTrackRequest answers a track request with the receiver's track, see
+// the package HandleTrackRequest
+func (r *Receiver) HandleTrackRequest(data []byte) []interface{} {
+	return handleTrackRequest(r.state, data)
+}
+
+// Close closes the RINEX and geotag files of the receiver and writes the
+// points of its track files not written yet. The data parsed afterwards opens
+// the files again.
+func (r *Receiver) Close() {
+	parseMutex.Lock()
+	defer parseMutex.Unlock()
+
+	state = r.state
+	if settings.TrackDirectory != "" && len(r.state.trackPeriodPoints) > 0 {
+		saveTrackFiles()
+	}
+
+	r.state.rinexObservationFile.close()
+	r.state.rinexNavigationFile.close()
+	if file := r.state.geotagFile; file != nil {
+		if err := file.Close(); err != nil {
+			log.Printf("[ERROR] Close - Error closing geotag file %s: %s\n", file.Name(), err.Error())
+		}
+		r.state.geotagFile = nil
+	}
+}
+
+// Parse decodes the complete messages in the buffer, of a single receiver,
+// and removes them from it
+func Parse(buffer *[]byte) []interface{} {
+	return defaultReceiver.Parse(buffer)
+}
+
+// HandleTrackRequest returns the track of the time window of a TrackRequest
+// in the requested format, for publishing on track/{format}
+func HandleTrackRequest(data []byte) []interface{} {
+	return defaultReceiver.HandleTrackRequest(data)
+}
+
+// Close closes the files of the receiver parsed by Parse
+func Close() {
+	defaultReceiver.Close()
+}
+
+// receiverDirectory returns the directory of the current receiver's files in
+// directory
+func receiverDirectory(directory string) string {
+	if state.name == "" {
+		return directory
+	}
+	return filepath.Join(directory, state.name)
+}
@@ -0,0 +1,87 @@
+package sbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/snksoft/crc"
+)
+
+/**
+ * Support for feeding recorded data to Parse as if it came live. The reader
+ * locates the SBF blocks with NextBlock to pace them by their receiver time,
+ * and sets the decoder clock to that time so the data without a time of its
+ * own, such as RTCM3 ephemerides, is dated as it was when recorded.
+ */
+
+// BlockInfo locates an SBF block in a buffer and gives its receiver time
+type BlockInfo struct {
+	Start int // Offset of the $@ of the block
+	End   int // Offset following the block
+	ID    uint16
+	TOW   uint32 // ms
+	WNc   uint16
+	Time  *time.Time // UTC, nil when the receiver had no time yet
+}
+
+/* Length of the header and time stamp at the start of every SBF block */
+const sbfTimeHeaderEnd = 14
+
+/* Clock of the decoder, the wall clock unless data is replayed */
+var clock = time.Now
+
+// SetClock replaces the clock used to date the data that has no receiver time,
+// nil restores the wall clock
+func SetClock(c func() time.Time) {
+	if c == nil {
+		c = time.Now
+	}
+	clock = c
+}
+
+// currentTime returns the time of the decoder clock
+func currentTime() time.Time {
+	return clock()
+}
+
+// NextBlock returns the first complete SBF block with a valid CRC in the
+// buffer. It returns false when the buffer holds none yet.
+func NextBlock(buffer []byte) (BlockInfo, bool) {
+	for start := 0; start+MIN_SBFSIZE <= len(buffer); start++ {
+		next := bytes.Index(buffer[start:], []byte("$@"))
+		if next < 0 {
+			break
+		}
+		start += next
+		if start+MIN_SBFSIZE > len(buffer) {
+			break
+		}
+		length := int(buffer[start+6]) | int(buffer[start+7])<<8
+		if length < MIN_SBFSIZE {
+			continue
+		}
+		if start+length > len(buffer) {
+			// The block is not complete yet
+			break
+		}
+		expectedCRC := uint64(buffer[start+2]) | uint64(buffer[start+3])<<8
+		if crc.CalculateCRC(crc.XMODEM, buffer[start+4:start+length]) != expectedCRC {
+			continue
+		}
+		block := BlockInfo{
+			Start: start,
+			End:   start + length,
+			ID:    binary.LittleEndian.Uint16(buffer[start+4:]),
+			TOW:   towDoNotUse,
+			WNc:   wncDoNotUse,
+		}
+		if length >= sbfTimeHeaderEnd {
+			block.TOW = binary.LittleEndian.Uint32(buffer[start+8:])
+			block.WNc = binary.LittleEndian.Uint16(buffer[start+12:])
+		}
+		block.Time = newBlockTime(block.TOW, block.WNc).Time
+		return block, true
+	}
+	return BlockInfo{}, false
+}
@@ -0,0 +1,145 @@
+package sbf
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+/**
+ * Decoding of RFStatus. The RF bands the receiver reports are split into the
+ * interferers it detected and the notch filters it placed to mitigate them.
+ * Interference in a GNSS band and the spoofing flags raise alarms, debounced
+ * over settings.JammingRaiseBlocks and settings.JammingClearBlocks blocks.
+ */
+
+const rfStatusTopic = "rf/status"
+const rfAlarmTopic = "rf/alarm"
+
+/* Offset of the first RFBand sub-block in RFStatus */
+const rfBandSubBlockOffset = 20
+
+/* RFBand Info bits 0-3 */
+const rfBandModeNotch = 1
+const rfBandModeDetected = 8
+
+/* RFStatus Flags bits */
+const rfFlagMisleadingSignal = 0x01
+const rfFlagInauthenticNavigation = 0x02
+
+// RFStatus is the interference mitigation state of the receiver
+type RFStatus struct {
+	BlockTime
+	Interferers           []RFBand `json:"interferers"`
+	NotchFilters          []RFBand `json:"notchFilters"`
+	MisleadingSignal      bool     `json:"misleadingSignal"`      // RF based spoofing detection
+	InauthenticNavigation bool     `json:"inauthenticNavigation"` // Navigation message authentication failure
+}
+
+// RFBand is an interferer or a notch filter
+type RFBand struct {
+	Frequency float64 `json:"frequency"` // Hz
+	Bandwidth float64 `json:"bandwidth"` // Hz
+	Antenna   uint8   `json:"antenna"`
+	GNSSBand  string  `json:"gnssBand,omitempty"` // GNSS band the RF band overlaps
+}
+
+type gnssFrequencyBand struct {
+	name string
+	low  float64 // Hz
+	high float64 // Hz
+}
+
+var gnssFrequencyBands = []gnssFrequencyBand{
+	{"L5/E5/B2", 1164e6, 1215e6},
+	{"L2/G2", 1215e6, 1256e6},
+	{"E6/B3", 1256e6, 1300e6},
+	{"L1/E1/B1/G1", 1559e6, 1610e6},
+}
+
+// gnssBandString names the GNSS bands a frequency range overlaps, or returns
+// "" when it is outside all of them
+func gnssBandString(frequency float64, bandwidth float64) string {
+	names := []string{}
+	for _, band := range gnssFrequencyBands {
+		if frequency+bandwidth/2 > band.low && frequency-bandwidth/2 < band.high {
+			names = append(names, band.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func handleRFStatus(buffer []byte) []interface{} {
+	block := RFStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleRFStatus - Error decoding RFStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := RFStatus{
+		BlockTime:             newBlockTime(block.TOW, block.WNc),
+		Interferers:           []RFBand{},
+		NotchFilters:          []RFBand{},
+		MisleadingSignal:      block.Flags&rfFlagMisleadingSignal != 0,
+		InauthenticNavigation: block.Flags&rfFlagInauthenticNavigation != 0,
+	}
+	jammedBands := []string{}
+	for i := 0; i < int(block.N); i++ {
+		sb := RFBand_1_0_t{}
+		if !decodeSizedSubBlock(buffer, rfBandSubBlockOffset+i*int(block.SBLength), int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleRFStatus - RFStatus block too short for %d RF bands\n", block.N)
+			break
+		}
+		band := RFBand{
+			Frequency: float64(sb.Frequency),
+			Bandwidth: float64(sb.Bandwidth) * 1000,
+			Antenna:   sb.Info >> 6,
+		}
+		band.GNSSBand = gnssBandString(band.Frequency, band.Bandwidth)
+
+		switch sb.Info & 0x0F {
+		case rfBandModeNotch:
+			status.NotchFilters = append(status.NotchFilters, band)
+		case rfBandModeDetected:
+			status.Interferers = append(status.Interferers, band)
+		default:
+			log.Printf("[ERROR] handleRFStatus - Unknown RF band mode %d\n", sb.Info&0x0F)
+			continue
+		}
+		if band.GNSSBand != "" {
+			jammedBands = append(jammedBands, fmt.Sprintf("%.3f MHz (%s)", band.Frequency/1e6, band.GNSSBand))
+		}
+	}
+
+	payloads := []interface{}{Payload{Topic: rfStatusTopic, Data: status}}
+
+	// Both mitigated and unmitigated interference in a GNSS band count as jamming
+	jammingMessage := "no interference in the GNSS bands"
+	if len(jammedBands) > 0 {
+		jammingMessage = "interference at " + strings.Join(jammedBands, ", ")
+	}
+	payloads = append(payloads, updateAlarm(rfAlarmTopic, Alarm{
+		BlockTime: status.BlockTime,
+		Alarm:     "jamming",
+		Active:    state.jammingHysteresis.update(len(jammedBands) > 0, settings.JammingRaiseBlocks, settings.JammingClearBlocks),
+		Message:   jammingMessage,
+		Value:     floatPtr(float64(len(jammedBands))),
+	})...)
+
+	spoofingMessage := "no spoofing detected"
+	if status.MisleadingSignal && status.InauthenticNavigation {
+		spoofingMessage = "misleading RF signal and inauthentic navigation data detected"
+	} else if status.MisleadingSignal {
+		spoofingMessage = "misleading RF signal detected"
+	} else if status.InauthenticNavigation {
+		spoofingMessage = "inauthentic navigation data detected"
+	}
+	payloads = append(payloads, updateAlarm(rfAlarmTopic, Alarm{
+		BlockTime: status.BlockTime,
+		Alarm:     "spoofing",
+		Active:    state.spoofingHysteresis.update(status.MisleadingSignal || status.InauthenticNavigation, settings.JammingRaiseBlocks, settings.JammingClearBlocks),
+		Message:   spoofingMessage,
+	})...)
+
+	return payloads
+}
@@ -0,0 +1,346 @@
+package sbf
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * RINEX 3.05 or 4.00 observation files written from the MeasEpoch
+ * observations when settings.RinexDirectory is set. The header is filled from
+ * the ReceiverSetup inventory and a file is started every hour or every day of
+ * GPS time, named the RINEX 3 way after the station, e.g.
+ * BRUX00BEL_R_20240360000_01D_00U_MO.rnx. The epochs before the first
+ * ReceiverSetup block are not written. A file of the current period that
+ * already exists, after a restart of the adapter, is appended to.
+ */
+
+const rinexProgram = "Septentrio adapter"
+
+// rinexFile is a RINEX file rotated every hour or day
+type rinexFile struct {
+	fileType string // MO for observations, MN for navigation
+	file     *os.File
+	period   time.Time // Start of the period of the file
+}
+
+/* RINEX observation codes written per system, the signals MeasEpoch can hold */
+var rinexObservationCodes = map[string][]string{
+	"GPS":     {"1C", "1W", "1L", "2W", "2L", "5Q"},
+	"GLONASS": {"1C", "1P", "2C", "2P", "3Q"},
+	"Galileo": {"1C", "5Q", "6C", "7Q", "8Q"},
+	"QZSS":    {"1C", "1L", "1Z", "2L", "5Q", "5Z", "6L"},
+	"BeiDou":  {"2I", "7I", "6I", "1P", "5P", "7D"},
+	"SBAS":    {"1C", "5I"},
+	"NavIC":   {"5A"},
+}
+
+/* Order of the systems in the header */
+var rinexSystemOrder = []string{"GPS", "GLONASS", "Galileo", "QZSS", "BeiDou", "SBAS", "NavIC"}
+
+// writeRinexObservations appends an epoch to the observation file
+func writeRinexObservations(observations Observations) {
+	if settings.RinexDirectory == "" || observations.WNc == wncDoNotUse {
+		return
+	}
+	epoch, ok := gpsTime(uint32(math.Round(observations.TOW*1000)), observations.WNc)
+	if !ok {
+		return
+	}
+
+	file := state.rinexObservationFile.open(epoch, func(writer *bufio.Writer) {
+		writeRinexObservationHeader(writer, epoch)
+	})
+	if file == nil {
+		return
+	}
+
+	records := []string{}
+	for _, satellite := range observations.Satellites {
+		codes, ok := rinexObservationCodes[satellite.System]
+		if !ok || strings.HasSuffix(satellite.Satellite, "00") {
+			continue
+		}
+		signals := map[string]SignalObservation{}
+		for _, signal := range satellite.Signals {
+			signals[signal.Signal] = signal
+		}
+
+		record := satellite.Satellite
+		written := false
+		for _, code := range codes {
+			signal, ok := signals[code]
+			if !ok {
+				record += strings.Repeat(" ", 4*16)
+				continue
+			}
+			written = true
+
+			// Loss of lock is detected by the lock time going down
+			lli := 0
+			if signal.LockTime != nil {
+				key := satellite.Satellite + code
+				if previous, ok := state.rinexLockTimes[key]; ok && *signal.LockTime < previous {
+					lli |= 0x01
+				}
+				state.rinexLockTimes[key] = *signal.LockTime
+			}
+			if signal.HalfCycleAmbiguity {
+				lli |= 0x02
+			}
+			ssi := rinexSignalStrength(signal.CN0)
+			record += rinexObservation(signal.Pseudorange, 0, 0) +
+				rinexObservation(signal.CarrierPhase, lli, ssi) +
+				rinexObservation(signal.Doppler, 0, 0) +
+				rinexObservation(signal.CN0, 0, 0)
+		}
+		if written {
+			records = append(records, strings.TrimRight(record, " "))
+		}
+	}
+
+	writer := bufio.NewWriter(file)
+	fmt.Fprintf(writer, "> %4d %02d %02d %02d %02d%11.7f  %d%3d\n",
+		epoch.Year(), epoch.Month(), epoch.Day(), epoch.Hour(), epoch.Minute(),
+		float64(epoch.Second())+float64(epoch.Nanosecond())/1e9, 0, len(records))
+	for _, record := range records {
+		fmt.Fprintln(writer, record)
+	}
+	if err := writer.Flush(); err != nil {
+		log.Printf("[ERROR] writeRinexObservations - Error writing RINEX file %s: %s\n", file.Name(), err.Error())
+	}
+}
+
+// writeRinexObservationHeader writes the header of an observation file
+// starting at the epoch
+func writeRinexObservationHeader(writer *bufio.Writer, epoch time.Time) {
+	rinexHeaderLine(writer, fmt.Sprintf("%9s%11s%-20s%-20s", settings.RinexVersion, "", "OBSERVATION DATA", "M: Mixed"), "RINEX VERSION / TYPE")
+	rinexProgramLine(writer)
+
+	markerName := state.inventory.MarkerName
+	if markerName == "" {
+		markerName = rinexStationName()[:4]
+	}
+	rinexHeaderLine(writer, markerName, "MARKER NAME")
+	if state.inventory.MarkerNumber != "" {
+		rinexHeaderLine(writer, state.inventory.MarkerNumber, "MARKER NUMBER")
+	}
+	if state.inventory.MarkerType != "" {
+		rinexHeaderLine(writer, state.inventory.MarkerType, "MARKER TYPE")
+	}
+	rinexHeaderLine(writer, fmt.Sprintf("%-20.20s%-40.40s", state.inventory.Observer, state.inventory.Agency), "OBSERVER / AGENCY")
+	rinexHeaderLine(writer, fmt.Sprintf("%-20.20s%-20.20s%-20.20s", state.inventory.ReceiverSerial, state.inventory.ReceiverName, state.inventory.ReceiverVersion), "REC # / TYPE / VERS")
+	rinexHeaderLine(writer, fmt.Sprintf("%-20.20s%-20.20s", state.inventory.AntennaSerial, state.inventory.AntennaType), "ANT # / TYPE")
+
+	x, y, z := 0.0, 0.0, 0.0
+	if position := state.inventory.ApproxPosition; position != nil && position.Latitude != nil && position.Longitude != nil && position.Height != nil {
+		x, y, z = geodeticToECEF(*position.Latitude, *position.Longitude, *position.Height)
+	} else if settings.ReferencePosition != nil {
+		x, y, z = geodeticToECEF(settings.ReferencePosition.Latitude, settings.ReferencePosition.Longitude, settings.ReferencePosition.Height)
+	}
+	rinexHeaderLine(writer, fmt.Sprintf("%14.4f%14.4f%14.4f", x, y, z), "APPROX POSITION XYZ")
+
+	deltaH, deltaE, deltaN := 0.0, 0.0, 0.0
+	if offset := state.inventory.AntennaOffset; offset != nil {
+		if offset.DeltaH != nil {
+			deltaH = *offset.DeltaH
+		}
+		if offset.DeltaE != nil {
+			deltaE = *offset.DeltaE
+		}
+		if offset.DeltaN != nil {
+			deltaN = *offset.DeltaN
+		}
+	}
+	rinexHeaderLine(writer, fmt.Sprintf("%14.4f%14.4f%14.4f", deltaH, deltaE, deltaN), "ANTENNA: DELTA H/E/N")
+
+	// Observation types, 13 per line
+	for _, system := range rinexSystemOrder {
+		types := []string{}
+		for _, code := range rinexObservationCodes[system] {
+			types = append(types, "C"+code, "L"+code, "D"+code, "S"+code)
+		}
+		for i := 0; i < len(types); i += 13 {
+			content := "      "
+			if i == 0 {
+				content = fmt.Sprintf("%s  %3d", systemPrefixes[system], len(types))
+			}
+			for j := i; j < i+13 && j < len(types); j++ {
+				content += " " + types[j]
+			}
+			rinexHeaderLine(writer, content, "SYS / # / OBS TYPES")
+		}
+	}
+	rinexHeaderLine(writer, "DBHZ", "SIGNAL STRENGTH UNIT")
+	rinexHeaderLine(writer, fmt.Sprintf("%6d%6d%6d%6d%6d%13.7f     GPS",
+		epoch.Year(), epoch.Month(), epoch.Day(), epoch.Hour(), epoch.Minute(),
+		float64(epoch.Second())+float64(epoch.Nanosecond())/1e9), "TIME OF FIRST OBS")
+
+	// The phase shifts applied by the receiver are not known
+	for _, system := range rinexSystemOrder {
+		rinexHeaderLine(writer, systemPrefixes[system], "SYS / PHASE SHIFT")
+	}
+
+	// GLONASS frequency numbers of the satellites seen so far, 8 per line
+	slots := []int{}
+	for slot := range state.glonassFrequencyNumbers {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+	content := fmt.Sprintf("%3d ", len(slots))
+	for i, slot := range slots {
+		if i > 0 && i%8 == 0 {
+			rinexHeaderLine(writer, content, "GLONASS SLOT / FRQ #")
+			content = "    "
+		}
+		content += fmt.Sprintf("R%02d %2d ", slot, state.glonassFrequencyNumbers[slot])
+	}
+	rinexHeaderLine(writer, content, "GLONASS SLOT / FRQ #")
+
+	// The GLONASS code-phase biases are not known
+	rinexHeaderLine(writer, "", "GLONASS COD/PHS/BIS")
+	rinexHeaderLine(writer, "", "END OF HEADER")
+}
+
+// rinexObservation formats an observation with its loss of lock and signal
+// strength indicators, blank when missing
+func rinexObservation(value *float64, lli int, ssi int) string {
+	if value == nil || math.Abs(*value) >= 1e10 {
+		return strings.Repeat(" ", 16)
+	}
+	indicators := ""
+	if lli == 0 {
+		indicators += " "
+	} else {
+		indicators += strconv.Itoa(lli)
+	}
+	if ssi == 0 {
+		indicators += " "
+	} else {
+		indicators += strconv.Itoa(ssi)
+	}
+	return fmt.Sprintf("%14.3f", *value) + indicators
+}
+
+// rinexSignalStrength maps a C/N0 to the 1-9 RINEX signal strength, 0 when
+// unknown
+func rinexSignalStrength(cn0 *float64) int {
+	if cn0 == nil {
+		return 0
+	}
+	ssi := int(*cn0 / 6)
+	if ssi < 1 {
+		return 1
+	}
+	if ssi > 9 {
+		return 9
+	}
+	return ssi
+}
+
+// open returns the file of the period of t, the GPS time of the data. The file
+// of the previous period is closed, and the header of a new file is written
+// when it is created. No file is opened before the ReceiverSetup block, which
+// fills the header and names the file.
+func (r *rinexFile) open(t time.Time, header func(*bufio.Writer)) *os.File {
+	if !state.receiverSetupSeen {
+		return nil
+	}
+	period := t.Truncate(24 * time.Hour)
+	length := "01D"
+	if settings.RinexRotation == "hourly" {
+		period = t.Truncate(time.Hour)
+		length = "01H"
+	}
+	if r.file != nil && period.Equal(r.period) {
+		return r.file
+	}
+	r.close()
+
+	directory := receiverDirectory(settings.RinexDirectory)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		log.Printf("[ERROR] open - Error creating RINEX directory %s: %s\n", directory, err.Error())
+		return nil
+	}
+	// Observation file names have the data frequency, unspecified here
+	name := fmt.Sprintf("%s_R_%04d%03d%02d%02d_%s", rinexStationName(), period.Year(), period.YearDay(), period.Hour(), period.Minute(), length)
+	if r.fileType == "MO" {
+		name += "_00U"
+	}
+	name = filepath.Join(directory, name+"_"+r.fileType+".rnx")
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("[ERROR] open - Error opening RINEX file %s: %s\n", name, err.Error())
+		return nil
+	}
+	if info, err := file.Stat(); err == nil && info.Size() == 0 {
+		writer := bufio.NewWriter(file)
+		header(writer)
+		if err := writer.Flush(); err != nil {
+			log.Printf("[ERROR] open - Error writing RINEX file %s: %s\n", name, err.Error())
+		}
+	}
+	log.Printf("[INFO] open - Writing RINEX data to %s\n", name)
+	r.file = file
+	r.period = period
+	return file
+}
+
+// close closes the file of the current period, the next data opens it again
+func (r *rinexFile) close() {
+	if r.file == nil {
+		return
+	}
+	if err := r.file.Close(); err != nil {
+		log.Printf("[ERROR] close - Error closing RINEX file %s: %s\n", r.file.Name(), err.Error())
+	}
+	r.file = nil
+}
+
+// rinexStationName returns the 9 character station name of the file names:
+// the 4 character station code, the monument and receiver indices and the
+// country code, XXXX00XXX when ReceiverSetup did not set them
+func rinexStationName() string {
+	code := state.inventory.StationCode
+	if code == "" {
+		code = state.inventory.MarkerName
+	}
+	code = strings.ToUpper(strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, code))
+	code = (code + "XXXX")[:4]
+
+	monument, receiver := 0, 0
+	if state.inventory.MonumentIdx != nil && *state.inventory.MonumentIdx < 10 {
+		monument = int(*state.inventory.MonumentIdx)
+	}
+	if state.inventory.ReceiverIdx != nil && *state.inventory.ReceiverIdx < 10 {
+		receiver = int(*state.inventory.ReceiverIdx)
+	}
+	country := strings.ToUpper(state.inventory.CountryCode)
+	if len(country) != 3 {
+		country = "XXX"
+	}
+	return fmt.Sprintf("%s%d%d%s", code, monument, receiver, country)
+}
+
+// rinexProgramLine writes the program, agency and creation time line
+func rinexProgramLine(writer *bufio.Writer) {
+	rinexHeaderLine(writer, fmt.Sprintf("%-20.20s%-20.20s%-20s", rinexProgram, state.inventory.Agency, time.Now().UTC().Format("20060102 150405")+" UTC"), "PGM / RUN BY / DATE")
+}
+
+// rinexHeaderLine writes a header line, 60 columns of content and the label
+func rinexHeaderLine(writer *bufio.Writer, content string, label string) {
+	fmt.Fprintf(writer, "%-60.60s%-20s\n", content, label)
+}
@@ -0,0 +1,212 @@
+package sbf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/**
+ * Replays testdata/measepoch.sbf through the RINEX observation writer and
+ * checks the file column by column. The recording holds a MeasEpoch epoch
+ * before the ReceiverSetup block, which must not be written, ReceiverSetup
+ * revision 4 of station BRUX00BEL and two MeasEpoch epochs of G05 (L1C/A and
+ * L2P, the L2P phase with a half cycle ambiguity) and E11 (E1 and E5a). The
+ * G05 lock time drops in the second epoch.
+ */
+
+// rinexObservationField returns the F14.3 value and the loss of lock and
+// signal strength indicators of the index-th observation of a record
+func rinexObservationField(t *testing.T, record string, index int) (string, byte, byte) {
+	t.Helper()
+	start := 3 + index*16
+	// Trailing blanks of a record are trimmed
+	if len(record) < start+16 {
+		record += strings.Repeat(" ", start+16-len(record))
+	}
+	return record[start : start+14], record[start+14], record[start+15]
+}
+
+func replayRinexFixture(t *testing.T) []string {
+	t.Helper()
+	defer func(previous Settings) { settings = previous }(settings)
+	settings.RinexDirectory = t.TempDir()
+	settings.RinexVersion = "3.05"
+	settings.RinexRotation = "daily"
+
+	data, err := os.ReadFile(filepath.Join("testdata", "measepoch.sbf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	NewReceiver("").Parse(&data)
+	if len(data) != 0 {
+		t.Fatalf("%d bytes of the fixture not parsed", len(data))
+	}
+
+	files, err := filepath.Glob(filepath.Join(settings.RinexDirectory, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "BRUX00BEL_R_20240350000_01D_00U_MO.rnx" {
+		t.Fatalf("expected BRUX00BEL_R_20240350000_01D_00U_MO.rnx, got %v", files)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func TestRinexObservationHeader(t *testing.T) {
+	lines := replayRinexFixture(t)
+
+	labels := []string{}
+	header := 0
+	for i, line := range lines {
+		if len(line) != 80 {
+			t.Fatalf("header line %d is %d columns long: %q", i+1, len(line), line)
+		}
+		label := strings.TrimRight(line[60:], " ")
+		if len(labels) == 0 || labels[len(labels)-1] != label {
+			labels = append(labels, label)
+		}
+		if label == "END OF HEADER" {
+			header = i + 1
+			break
+		}
+	}
+	expectedLabels := []string{
+		"RINEX VERSION / TYPE",
+		"PGM / RUN BY / DATE",
+		"MARKER NAME",
+		"MARKER NUMBER",
+		"MARKER TYPE",
+		"OBSERVER / AGENCY",
+		"REC # / TYPE / VERS",
+		"ANT # / TYPE",
+		"APPROX POSITION XYZ",
+		"ANTENNA: DELTA H/E/N",
+		"SYS / # / OBS TYPES",
+		"SIGNAL STRENGTH UNIT",
+		"TIME OF FIRST OBS",
+		"SYS / PHASE SHIFT",
+		"GLONASS SLOT / FRQ #",
+		"GLONASS COD/PHS/BIS",
+		"END OF HEADER",
+	}
+	if strings.Join(labels, "|") != strings.Join(expectedLabels, "|") {
+		t.Fatalf("header labels\n%v\nexpected\n%v", labels, expectedLabels)
+	}
+
+	// Header content, in columns 1-60
+	expectedContent := map[string][]string{
+		"RINEX VERSION / TYPE": {"     3.05           OBSERVATION DATA    M: Mixed"},
+		"MARKER NAME":          {"BRUX"},
+		"MARKER NUMBER":        {"13101M010"},
+		"MARKER TYPE":          {"GEODETIC"},
+		"OBSERVER / AGENCY":    {"Observer            Agency"},
+		"REC # / TYPE / VERS":  {"3012345             SEPT POLARX5        5.5.0"},
+		"ANT # / TYPE":         {"A1234               SEPCHOKE_B3E6   NONE"},
+		"APPROX POSITION XYZ":  {"  4027902.8042   307001.3193  4919481.5118"},
+		"ANTENNA: DELTA H/E/N": {"        0.1234        0.0000        0.0000"},
+		"SIGNAL STRENGTH UNIT": {"DBHZ"},
+		"TIME OF FIRST OBS":    {"  2024     2     4     1     0    0.0000000     GPS"},
+		"SYS / # / OBS TYPES": {
+			"G   24 C1C L1C D1C S1C C1W L1W D1W S1W C1L L1L D1L S1L C2W",
+			"       L2W D2W S2W C2L L2L D2L S2L C5Q L5Q D5Q S5Q",
+			"R   20 C1C L1C D1C S1C C1P L1P D1P S1P C2C L2C D2C S2C C2P",
+			"       L2P D2P S2P C3Q L3Q D3Q S3Q",
+			"E   20 C1C L1C D1C S1C C5Q L5Q D5Q S5Q C6C L6C D6C S6C C7Q",
+			"       L7Q D7Q S7Q C8Q L8Q D8Q S8Q",
+			"J   28 C1C L1C D1C S1C C1L L1L D1L S1L C1Z L1Z D1Z S1Z C2L",
+			"       L2L D2L S2L C5Q L5Q D5Q S5Q C5Z L5Z D5Z S5Z C6L L6L",
+			"       D6L S6L",
+			"C   24 C2I L2I D2I S2I C7I L7I D7I S7I C6I L6I D6I S6I C1P",
+			"       L1P D1P S1P C5P L5P D5P S5P C7D L7D D7D S7D",
+			"S    8 C1C L1C D1C S1C C5I L5I D5I S5I",
+			"I    4 C5A L5A D5A S5A",
+		},
+	}
+	content := map[string][]string{}
+	for _, line := range lines[:header] {
+		label := strings.TrimRight(line[60:], " ")
+		content[label] = append(content[label], strings.TrimRight(line[:60], " "))
+	}
+	for label, expected := range expectedContent {
+		if strings.Join(content[label], "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%s\n%s\nexpected\n%s", label, strings.Join(content[label], "\n"), strings.Join(expected, "\n"))
+		}
+	}
+}
+
+func TestRinexObservationEpochs(t *testing.T) {
+	lines := replayRinexFixture(t)
+	for len(lines) > 0 && !strings.HasPrefix(lines[0][60:], "END OF HEADER") {
+		lines = lines[1:]
+	}
+	if len(lines) != 7 {
+		t.Fatalf("expected 2 epochs of 2 satellites, got %d lines after the header", len(lines)-1)
+	}
+
+	type observation struct {
+		index int
+		value string
+		lli   byte
+		ssi   byte
+	}
+	epochs := []struct {
+		line    string
+		records map[string][]observation
+	}{
+		{
+			line: "> 2024 02 04 01 00  0.0000000  0  2",
+			records: map[string][]observation{
+				"G05": {
+					{0, "  21135368.800", ' ', ' '}, // C1C
+					{1, " 111067113.685", ' ', '7'},
+					{2, "         1.234", ' ', ' '},
+					{3, "        45.000", ' ', ' '},
+					{12, "  21135370.800", ' ', ' '}, // C2W
+					{13, "  86545810.782", '2', '5'},
+					{14, "         0.963", ' ', ' '},
+					{15, "        30.000", ' ', ' '},
+				},
+				"E11": {
+					{0, "  21974836.480", ' ', ' '}, // C1C
+					{1, " 115478611.654", ' ', '6'},
+					{2, "        -2.222", ' ', ' '},
+					{3, "        40.000", ' ', ' '},
+					{4, "  21974839.480", ' ', ' '}, // C5Q
+					{5, "  86233990.970", ' ', '5'},
+					{6, "        -1.657", ' ', ' '},
+					{7, "        35.000", ' ', ' '},
+				},
+			},
+		},
+		{
+			line: "> 2024 02 04 01 00  1.0000000  0  2",
+			records: map[string][]observation{
+				// The lock time of L1C/A went down
+				"G05": {{1, " 111067113.685", '1', '7'}},
+			},
+		},
+	}
+
+	lines = lines[1:]
+	for _, epoch := range epochs {
+		if lines[0] != epoch.line {
+			t.Errorf("epoch line\n%q\nexpected\n%q", lines[0], epoch.line)
+		}
+		for _, record := range lines[1:3] {
+			satellite := record[:3]
+			for _, expected := range epoch.records[satellite] {
+				value, lli, ssi := rinexObservationField(t, record, expected.index)
+				if value != expected.value || lli != expected.lli || ssi != expected.ssi {
+					t.Errorf("%s observation %d is %q%c%c, expected %q%c%c", satellite, expected.index, value, lli, ssi, expected.value, expected.lli, expected.ssi)
+				}
+			}
+		}
+		lines = lines[3:]
+	}
+}
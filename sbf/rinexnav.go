@@ -0,0 +1,339 @@
+package sbf
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+/**
+ * Mixed RINEX 3.05 or 4.00 navigation files written from the SBF navigation
+ * blocks, next to the observation files of rinex.go and rotated with them.
+ * A record is written once per issue of data of a satellite, and the latest
+ * record of every satellite is repeated at the start of each new file so a
+ * file is complete on its own. RINEX 3 files carry the ionosphere and UTC
+ * parameters known when they are started in their header, RINEX 4 files get
+ * ION and STO records when the parameters change.
+ */
+
+/* RINEX value for unknown orbit fields */
+const rinexUnknown = 0.999999999999e9
+
+// rinexNavigationRecord is the latest record of a satellite or parameter set
+type rinexNavigationRecord struct {
+	issue string // Changes with the content of the record
+	text  string
+}
+
+// writeRinexEphemeris writes the record of a GPS, Galileo, BeiDou or QZSS
+// ephemeris
+func writeRinexEphemeris(eph Ephemeris) {
+	if settings.RinexDirectory == "" || eph.Time == nil {
+		return
+	}
+	prefix := systemPrefixes[eph.System]
+
+	// BeiDou weeks and times are BDT, 14 s behind GPS time
+	weekStart := gpsEpoch.Add(time.Duration(eph.Week) * 7 * 24 * time.Hour)
+	transmission := eph.TOW + float64(int(eph.WNc)-int(eph.Week))*604800
+	message := "LNAV"
+	switch eph.System {
+	case "Galileo":
+		message = "INAV"
+		if eph.DataSources != nil && *eph.DataSources&0x02 != 0 {
+			message = "FNAV"
+		}
+	case "BeiDou":
+		weekStart = weekStart.Add(beidouWeekOffset * 7 * 24 * time.Hour)
+		transmission = eph.TOW - 14 + float64(int(eph.WNc)-beidouWeekOffset-int(eph.Week))*604800
+		// GEO satellites broadcast D2
+		message = "D1"
+		if number := eph.Satellite[1:]; number <= "05" || (number >= "59" && number <= "63") {
+			message = "D2"
+		}
+	}
+
+	// The clock reference time can be in the week before or after the orbit's
+	toc := weekStart.Add(time.Duration(eph.Toc * float64(time.Second)))
+	if eph.Toc-eph.Toe > 302400 {
+		toc = toc.Add(-7 * 24 * time.Hour)
+	} else if eph.Toe-eph.Toc > 302400 {
+		toc = toc.Add(7 * 24 * time.Hour)
+	}
+
+	iodc, accuracy, tgd, tgd2, fitInterval := 0.0, 0.0, 0.0, 0.0, 0.0
+	if eph.IODC != nil {
+		iodc = float64(*eph.IODC)
+	}
+	if eph.Accuracy != nil {
+		accuracy = *eph.Accuracy
+	} else if eph.System == "Galileo" {
+		accuracy = -1 // No accuracy prediction available
+	}
+	if eph.TGD != nil {
+		tgd = *eph.TGD
+	}
+	if eph.TGD2 != nil {
+		tgd2 = *eph.TGD2
+	}
+	if eph.FitInterval != nil {
+		fitInterval = *eph.FitInterval
+	}
+
+	text := rinexRecordStart("EPH", eph.Satellite, message) +
+		rinexEpochLine(eph.Satellite, toc, eph.Af0, eph.Af1, eph.Af2) +
+		rinexOrbitLine(float64(eph.IODE), eph.Crs, eph.DeltaN, eph.M0) +
+		rinexOrbitLine(eph.Cuc, eph.Eccentricity, eph.Cus, eph.SqrtA) +
+		rinexOrbitLine(eph.Toe, eph.Cic, eph.Omega0, eph.Cis) +
+		rinexOrbitLine(eph.I0, eph.Crc, eph.Omega, eph.OmegaDot)
+	switch eph.System {
+	case "GPS", "QZSS":
+		codes, l2p := 0.0, 0.0
+		if eph.CodesOnL2 != nil {
+			codes = float64(*eph.CodesOnL2)
+		}
+		if eph.L2PDataFlag != nil && *eph.L2PDataFlag {
+			l2p = 1
+		}
+		// QZSS has the fit interval flag instead of the interval
+		if prefix == "J" {
+			if fitInterval > 2 {
+				fitInterval = 1
+			} else {
+				fitInterval = 0
+			}
+		}
+		text += rinexOrbitLine(eph.IDot, codes, float64(eph.Week), l2p) +
+			rinexOrbitLine(accuracy, float64(eph.Health), tgd, iodc) +
+			rinexOrbitLine(transmission, fitInterval)
+	case "Galileo":
+		sources := 0.0
+		if eph.DataSources != nil {
+			sources = float64(*eph.DataSources)
+		}
+		text += rinexOrbitLine(eph.IDot, sources, float64(eph.Week)) +
+			rinexOrbitLine(accuracy, float64(eph.Health), tgd, tgd2) +
+			rinexOrbitLine(transmission)
+	case "BeiDou":
+		text += rinexOrbitLine(eph.IDot, 0, float64(eph.Week)) +
+			rinexOrbitLine(accuracy, float64(eph.Health), tgd, tgd2) +
+			rinexOrbitLine(transmission, iodc)
+	default:
+		return
+	}
+
+	issue := fmt.Sprintf("%d/%d/%.0f", eph.IODE, eph.Week, eph.Toe)
+	writeRinexNavigationRecord(eph.Satellite+message, issue, eph.BlockTime, text)
+}
+
+// writeRinexGLONASSEphemeris writes the record of a GLONASS ephemeris with
+// the predicted accuracy index F_T of GLONAV
+func writeRinexGLONASSEphemeris(eph GLONASSEphemeris, accuracyIndex uint16) {
+	if settings.RinexDirectory == "" || eph.Time == nil || eph.Toe == nil || strings.HasSuffix(eph.Satellite, "00") {
+		return
+	}
+
+	// The message frame time is in s of the UTC week
+	frameTime := math.Mod(eph.TOW-float64(gpsUtcLeapSeconds)+604800, 604800)
+	accuracy := rinexUnknown
+	if accuracyIndex < 15 {
+		accuracy = float64(accuracyIndex)
+	}
+
+	// The status and health flags of the last orbit line are not decoded
+	text := rinexRecordStart("EPH", eph.Satellite, "FDMA") +
+		rinexEpochLine(eph.Satellite, *eph.Toe, -eph.TauN, eph.GammaN, frameTime) +
+		rinexOrbitLine(eph.X, eph.VelocityX, eph.AccelerationX, float64(eph.Health)) +
+		rinexOrbitLine(eph.Y, eph.VelocityY, eph.AccelerationY, float64(eph.FrequencyNumber)) +
+		rinexOrbitLine(eph.Z, eph.VelocityZ, eph.AccelerationZ, float64(eph.Age)) +
+		rinexOrbitLine(rinexUnknown, eph.DeltaTauN, accuracy, rinexUnknown)
+
+	writeRinexNavigationRecord(eph.Satellite+"FDMA", eph.Toe.Format(time.RFC3339), eph.BlockTime, text)
+}
+
+// writeRinexSBASEphemeris writes the record of an SBAS ephemeris
+func writeRinexSBASEphemeris(eph SBASEphemeris) {
+	if settings.RinexDirectory == "" || eph.Time == nil || eph.Toe == nil {
+		return
+	}
+
+	accuracy := 32767.0 // Do not use
+	if eph.Accuracy != nil {
+		accuracy = *eph.Accuracy
+	}
+	text := rinexRecordStart("EPH", eph.Satellite, "SBAS") +
+		rinexEpochLine(eph.Satellite, *eph.Toe, eph.Af0, eph.Af1, eph.TOW) +
+		rinexOrbitLine(eph.X, eph.VelocityX, eph.AccelerationX, 0) +
+		rinexOrbitLine(eph.Y, eph.VelocityY, eph.AccelerationY, accuracy) +
+		rinexOrbitLine(eph.Z, eph.VelocityZ, eph.AccelerationZ, float64(eph.IODN))
+
+	writeRinexNavigationRecord(eph.Satellite+"SBAS", eph.Toe.Format(time.RFC3339), eph.BlockTime, text)
+}
+
+// writeRinexIonosphere keeps the ionosphere parameters for the RINEX 3
+// headers, RINEX 4 files get an ION record
+func writeRinexIonosphere(parameters IonosphereParameters) {
+	if settings.RinexDirectory == "" || parameters.Time == nil {
+		return
+	}
+	transmission, _ := gpsTime(uint32(parameters.TOW*1000), parameters.WNc)
+
+	var issue, text string
+	switch parameters.System {
+	case "GPS":
+		a, b := parameters.Alpha, parameters.Beta
+		state.rinexIonosphereLines["GPSA"] = fmt.Sprintf("GPSA %12.4E%12.4E%12.4E%12.4E", a[0], a[1], a[2], a[3])
+		state.rinexIonosphereLines["GPSB"] = fmt.Sprintf("GPSB %12.4E%12.4E%12.4E%12.4E", b[0], b[1], b[2], b[3])
+		issue = fmt.Sprint(a, b)
+		text = rinexRecordStart("ION", parameters.Satellite, "LNAV") +
+			rinexEpochLine("   ", transmission, a[0], a[1], a[2]) +
+			rinexOrbitLine(a[3], b[0], b[1], b[2]) +
+			rinexOrbitLine(b[3])
+	case "Galileo":
+		a := parameters.EffectiveIonisation
+		flags := 0.0
+		if parameters.StormFlags != nil {
+			flags = float64(*parameters.StormFlags)
+		}
+		state.rinexIonosphereLines["GAL"] = fmt.Sprintf("GAL  %12.4E%12.4E%12.4E%12.4E", a[0], a[1], a[2], 0.0)
+		issue = fmt.Sprint(a, flags)
+		text = rinexRecordStart("ION", parameters.Satellite, "IFNV") +
+			rinexEpochLine("   ", transmission, a[0], a[1], a[2]) +
+			rinexOrbitLine(flags)
+	default:
+		return
+	}
+
+	if strings.HasPrefix(settings.RinexVersion, "4") {
+		writeRinexNavigationRecord("ION"+parameters.System, issue, parameters.BlockTime, text)
+	}
+}
+
+// writeRinexUTC keeps the UTC parameters for the RINEX 3 headers, RINEX 4
+// files get an STO record
+func writeRinexUTC(parameters UTCParameters) {
+	if settings.RinexDirectory == "" || parameters.Time == nil {
+		return
+	}
+
+	code, message := "GPUT", "LNAV"
+	if parameters.System == "Galileo" {
+		code, message = "GAUT", "IFNV"
+	} else if parameters.System != "GPS" {
+		return
+	}
+	state.rinexTimeCorrectionLines[code] = fmt.Sprintf("%s %17.10E%16.9E %6d %4d", code, parameters.A0, parameters.A1, parameters.Tot, parameters.Week)
+	if parameters.System == "GPS" {
+		state.rinexLeapSecondsLine = fmt.Sprintf("%6d%6d%6d%6d", parameters.LeapSeconds, parameters.LeapSecondsFuture, parameters.LeapSecondsWeek, parameters.LeapSecondsDay)
+	}
+
+	reference := gpsEpoch.Add(time.Duration(parameters.Week)*7*24*time.Hour + time.Duration(parameters.Tot)*time.Second)
+	transmission := parameters.TOW + float64(int(parameters.WNc)-int(parameters.Week))*604800
+	text := rinexRecordStart("STO", parameters.Satellite, message) +
+		fmt.Sprintf("    %04d %02d %02d %02d %02d %02d     %s\n", reference.Year(), reference.Month(), reference.Day(),
+			reference.Hour(), reference.Minute(), reference.Second(), code) +
+		rinexOrbitLine(transmission, parameters.A0, parameters.A1, 0)
+
+	if strings.HasPrefix(settings.RinexVersion, "4") {
+		issue := fmt.Sprint(parameters.A0, parameters.A1, parameters.Tot, parameters.Week)
+		writeRinexNavigationRecord("STO"+code, issue, parameters.BlockTime, text)
+	}
+}
+
+// writeRinexNavigationRecord appends a record to the navigation file of the
+// period of the block when its issue changed. The records kept so far are
+// written after the header of a new file.
+func writeRinexNavigationRecord(key string, issue string, blockTime BlockTime, text string) {
+	received, ok := gpsTime(uint32(blockTime.TOW*1000), blockTime.WNc)
+	if !ok {
+		return
+	}
+
+	previous, known := state.rinexNavigationRecords[key]
+	state.rinexNavigationRecords[key] = rinexNavigationRecord{issue: issue, text: text}
+	newFile := false
+	file := state.rinexNavigationFile.open(received, func(writer *bufio.Writer) {
+		writeRinexNavigationHeader(writer)
+		newFile = true
+	})
+	if file == nil {
+		return
+	}
+
+	writer := bufio.NewWriter(file)
+	if newFile {
+		keys := []string{}
+		for k := range state.rinexNavigationRecords {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writer.WriteString(state.rinexNavigationRecords[k].text)
+		}
+	} else if !known || previous.issue != issue {
+		writer.WriteString(text)
+	}
+	if err := writer.Flush(); err != nil {
+		log.Printf("[ERROR] writeRinexNavigationRecord - Error writing RINEX file %s: %s\n", file.Name(), err.Error())
+	}
+}
+
+// writeRinexNavigationHeader writes the header of a navigation file
+func writeRinexNavigationHeader(writer *bufio.Writer) {
+	rinexHeaderLine(writer, fmt.Sprintf("%9s%11s%-20s%-20s", settings.RinexVersion, "", "N: GNSS NAV DATA", "M: Mixed"), "RINEX VERSION / TYPE")
+	rinexProgramLine(writer)
+	if !strings.HasPrefix(settings.RinexVersion, "4") {
+		for _, code := range []string{"GAL", "GPSA", "GPSB"} {
+			if line, ok := state.rinexIonosphereLines[code]; ok {
+				rinexHeaderLine(writer, line, "IONOSPHERIC CORR")
+			}
+		}
+		for _, code := range []string{"GAUT", "GPUT"} {
+			if line, ok := state.rinexTimeCorrectionLines[code]; ok {
+				rinexHeaderLine(writer, line, "TIME SYSTEM CORR")
+			}
+		}
+	}
+	if state.rinexLeapSecondsLine != "" {
+		rinexHeaderLine(writer, state.rinexLeapSecondsLine, "LEAP SECONDS")
+	}
+	rinexHeaderLine(writer, "", "END OF HEADER")
+}
+
+// rinexRecordStart returns the line that starts a RINEX 4 record, e.g.
+// "> EPH G05 LNAV", and nothing for RINEX 3
+func rinexRecordStart(recordType string, satellite string, message string) string {
+	if !strings.HasPrefix(settings.RinexVersion, "4") {
+		return ""
+	}
+	return fmt.Sprintf("> %s %s %s\n", recordType, satellite, message)
+}
+
+// rinexEpochLine returns the first line of a record, the satellite, the epoch
+// and three values
+func rinexEpochLine(satellite string, epoch time.Time, values ...float64) string {
+	line := fmt.Sprintf("%-3s %04d %02d %02d %02d %02d %02d", satellite, epoch.Year(), epoch.Month(), epoch.Day(),
+		epoch.Hour(), epoch.Minute(), epoch.Second())
+	for _, value := range values {
+		line += rinexValue(value)
+	}
+	return line + "\n"
+}
+
+// rinexOrbitLine returns a broadcast orbit line of up to four values
+func rinexOrbitLine(values ...float64) string {
+	line := "    "
+	for _, value := range values {
+		line += rinexValue(value)
+	}
+	return line + "\n"
+}
+
+// rinexValue formats a navigation value in the 19 column exponent format
+func rinexValue(value float64) string {
+	return fmt.Sprintf("%19.12E", value)
+}
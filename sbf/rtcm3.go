@@ -0,0 +1,700 @@
+package sbf
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+/**
+ * Decoding of RTCM 3.x messages, received interleaved with SBF or unwrapped
+ * from EncapsulatedOutput and RawDataIn. Frames start with 0xD3 and are
+ * protected by a CRC-24Q. Station coordinates (1005/1006) and descriptors
+ * (1033) are published on their own topic, MSM4/MSM7 observations and the
+ * 1019/1020/1042/1046 ephemerides in the shapes of observations.go. Other
+ * messages are published on rtcm/other with their number and length only.
+ */
+
+const rtcmStationTopic = "rtcm/station"
+const rtcmDescriptorTopic = "rtcm/descriptor"
+const rtcmOtherTopic = "rtcm/other"
+
+/* Start of every RTCM3 frame */
+const rtcm3Preamble = 0xD3
+
+/* RTCM3 frame layout: preamble, 6 reserved bits and a 10 bit length, the message and a CRC-24Q */
+const rtcm3HeaderLength = 3
+const rtcm3CRCLength = 3
+
+// RTCMStation is the position of a reference station from message 1005 or
+// 1006
+type RTCMStation struct {
+	MessageType              uint16   `json:"messageType"`
+	StationID                uint16   `json:"stationId"`
+	ITRFYear                 uint8    `json:"itrfYear"`
+	GPS                      bool     `json:"gps"`
+	GLONASS                  bool     `json:"glonass"`
+	Galileo                  bool     `json:"galileo"`
+	ReferenceStation         bool     `json:"referenceStation"` // false for a physical station, true for a virtual one
+	SingleReceiverOscillator bool     `json:"singleReceiverOscillator"`
+	QuarterCycleIndicator    uint8    `json:"quarterCycleIndicator"`
+	X                        float64  `json:"x"`                       // m, ECEF of the antenna reference point
+	Y                        float64  `json:"y"`                       // m
+	Z                        float64  `json:"z"`                       // m
+	AntennaHeight            *float64 `json:"antennaHeight,omitempty"` // m, 1006 only
+	Latitude                 float64  `json:"latitude"`                // °, WGS84
+	Longitude                float64  `json:"longitude"`               // °
+	Height                   float64  `json:"height"`                  // m, ellipsoidal
+}
+
+// RTCMDescriptor is the antenna and receiver description of a reference
+// station from message 1033
+type RTCMDescriptor struct {
+	MessageType       uint16 `json:"messageType"`
+	StationID         uint16 `json:"stationId"`
+	AntennaDescriptor string `json:"antennaDescriptor"`
+	AntennaSetupID    uint8  `json:"antennaSetupId"`
+	AntennaSerial     string `json:"antennaSerial"`
+	ReceiverType      string `json:"receiverType"`
+	ReceiverFirmware  string `json:"receiverFirmware"`
+	ReceiverSerial    string `json:"receiverSerial"`
+}
+
+// RTCMOther is a message without a dedicated decoder
+type RTCMOther struct {
+	MessageType uint16 `json:"messageType"`
+	Length      int    `json:"length"` // bytes
+}
+
+/* Constellation of the MSM messages by message number / 10 */
+var msmSystems = map[uint16]string{
+	107: "GPS",
+	108: "GLONASS",
+	109: "Galileo",
+	111: "QZSS",
+	112: "BeiDou",
+}
+
+/* RINEX observation codes of the MSM signal mask bits (1-32) per constellation */
+var msmSignals = map[string]map[int]string{
+	"GPS": {
+		2: "1C", 3: "1P", 4: "1W", 8: "2C", 9: "2P", 10: "2W", 15: "2S", 16: "2L", 17: "2X",
+		22: "5I", 23: "5Q", 24: "5X", 30: "1S", 31: "1L", 32: "1X",
+	},
+	"GLONASS": {
+		2: "1C", 3: "1P", 8: "2C", 9: "2P",
+	},
+	"Galileo": {
+		2: "1C", 3: "1A", 4: "1B", 5: "1X", 6: "1Z", 8: "6C", 9: "6A", 10: "6B", 11: "6X", 12: "6Z",
+		14: "7I", 15: "7Q", 16: "7X", 18: "8I", 19: "8Q", 20: "8X", 22: "5I", 23: "5Q", 24: "5X",
+	},
+	"QZSS": {
+		2: "1C", 9: "6S", 10: "6L", 11: "6X", 15: "2S", 16: "2L", 17: "2X", 22: "5I", 23: "5Q", 24: "5X",
+		30: "1S", 31: "1L", 32: "1X",
+	},
+	"BeiDou": {
+		2: "2I", 3: "2Q", 4: "2X", 8: "6I", 9: "6Q", 10: "6X", 14: "7I", 15: "7Q", 16: "7X",
+		22: "5D", 23: "5P", 24: "5X", 25: "7D", 30: "1D", 31: "1P", 32: "1X",
+	},
+}
+
+/* GPS URA index to accuracy (m) */
+var gpsURA = []float64{2.4, 3.4, 4.85, 6.85, 9.65, 13.65, 24, 48, 96, 192, 384, 768, 1536, 3072, 6144, 6144}
+
+var crc24qTable = makeCRC24QTable()
+
+func makeCRC24QTable() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 16
+		for j := 0; j < 8; j++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864CFB
+			}
+		}
+		table[i] = crc & 0xFFFFFF
+	}
+	return table
+}
+
+// crc24q computes the CRC-24Q of RTCM3 over data
+func crc24q(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = (crc<<8)&0xFFFFFF ^ crc24qTable[byte(crc>>16)^b]
+	}
+	return crc
+}
+
+// rtcm3FrameLength returns the total length of the RTCM3 frame at the start
+// of data, including header and CRC. It returns false when data does not
+// start with a valid header.
+func rtcm3FrameLength(data []byte) (int, bool) {
+	if len(data) < rtcm3HeaderLength || data[0] != rtcm3Preamble || data[1]&0xFC != 0 {
+		return 0, false
+	}
+	return rtcm3HeaderLength + (int(data[1]&0x03)<<8 | int(data[2])) + rtcm3CRCLength, true
+}
+
+// rtcm3CRCValid checks the CRC-24Q of a complete frame
+func rtcm3CRCValid(frame []byte) bool {
+	end := len(frame) - rtcm3CRCLength
+	expected := uint32(frame[end])<<16 | uint32(frame[end+1])<<8 | uint32(frame[end+2])
+	return crc24q(frame[:end]) == expected
+}
+
+// rtcmReader reads the big endian bit fields of an RTCM3 message. Reading
+// past the end of the message sets overrun and returns 0.
+type rtcmReader struct {
+	data    []byte
+	pos     int
+	overrun bool
+}
+
+// u reads an unsigned field of n bits
+func (r *rtcmReader) u(n int) uint64 {
+	if r.pos+n > len(r.data)*8 {
+		r.overrun = true
+		r.pos += n
+		return 0
+	}
+	var value uint64
+	for i := 0; i < n; i++ {
+		bit := r.data[(r.pos+i)/8] >> (7 - uint((r.pos+i)%8)) & 1
+		value = value<<1 | uint64(bit)
+	}
+	r.pos += n
+	return value
+}
+
+// s reads a two's complement field of n bits
+func (r *rtcmReader) s(n int) int64 {
+	value := r.u(n)
+	if value&(1<<uint(n-1)) != 0 {
+		return int64(value) - int64(1)<<uint(n)
+	}
+	return int64(value)
+}
+
+// sm reads a sign-magnitude field of n bits, as used by GLONASS
+func (r *rtcmReader) sm(n int) int64 {
+	value := r.u(n)
+	magnitude := int64(value & (1<<uint(n-1) - 1))
+	if value&(1<<uint(n-1)) != 0 {
+		return -magnitude
+	}
+	return magnitude
+}
+
+// text reads a character string of n bytes
+func (r *rtcmReader) text(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(byte(r.u(8)))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// handleRTCM3Frame decodes a complete frame with a valid CRC
+func handleRTCM3Frame(frame []byte) []interface{} {
+	message := frame[rtcm3HeaderLength : len(frame)-rtcm3CRCLength]
+	if len(message) < 2 {
+		return []interface{}{}
+	}
+	r := &rtcmReader{data: message}
+	messageType := uint16(r.u(12))
+
+	var payloads []interface{}
+	switch messageType {
+	case 1005, 1006:
+		payloads = []interface{}{Payload{Topic: rtcmStationTopic, Data: decodeRTCMStation(r, messageType)}}
+	case 1033:
+		payloads = []interface{}{Payload{Topic: rtcmDescriptorTopic, Data: decodeRTCMDescriptor(r, messageType)}}
+	case 1074, 1077, 1084, 1087, 1094, 1097, 1114, 1117, 1124, 1127:
+		payloads = []interface{}{Payload{Topic: observationsTopic, Data: decodeRTCMMSM(r, messageType)}}
+	case 1019:
+		payloads = []interface{}{Payload{Topic: ephemerisTopic, Data: decodeRTCMGPSEphemeris(r)}}
+	case 1020:
+		payloads = []interface{}{Payload{Topic: ephemerisTopic, Data: decodeRTCMGLONASSEphemeris(r)}}
+	case 1042:
+		payloads = []interface{}{Payload{Topic: ephemerisTopic, Data: decodeRTCMBeiDouEphemeris(r)}}
+	case 1046:
+		payloads = []interface{}{Payload{Topic: ephemerisTopic, Data: decodeRTCMGalileoEphemeris(r)}}
+	default:
+		return []interface{}{Payload{Topic: rtcmOtherTopic, Data: RTCMOther{MessageType: messageType, Length: len(message)}}}
+	}
+	if r.overrun {
+		log.Printf("[ERROR] handleRTCM3Frame - RTCM3 message %d too short: %d bytes\n", messageType, len(message))
+		return []interface{}{}
+	}
+	return payloads
+}
+
+func decodeRTCMStation(r *rtcmReader, messageType uint16) RTCMStation {
+	station := RTCMStation{MessageType: messageType}
+	station.StationID = uint16(r.u(12))
+	station.ITRFYear = uint8(r.u(6))
+	station.GPS = r.u(1) == 1
+	station.GLONASS = r.u(1) == 1
+	station.Galileo = r.u(1) == 1
+	station.ReferenceStation = r.u(1) == 1
+	station.X = float64(r.s(38)) * 0.0001
+	station.SingleReceiverOscillator = r.u(1) == 1
+	r.u(1) // Reserved
+	station.Y = float64(r.s(38)) * 0.0001
+	station.QuarterCycleIndicator = uint8(r.u(2))
+	station.Z = float64(r.s(38)) * 0.0001
+	if messageType == 1006 {
+		height := float64(r.u(16)) * 0.0001
+		station.AntennaHeight = &height
+	}
+	station.Latitude, station.Longitude, station.Height = ecefToGeodetic(station.X, station.Y, station.Z)
+	return station
+}
+
+func decodeRTCMDescriptor(r *rtcmReader, messageType uint16) RTCMDescriptor {
+	descriptor := RTCMDescriptor{MessageType: messageType}
+	descriptor.StationID = uint16(r.u(12))
+	descriptor.AntennaDescriptor = r.text(int(r.u(8)))
+	descriptor.AntennaSetupID = uint8(r.u(8))
+	descriptor.AntennaSerial = r.text(int(r.u(8)))
+	descriptor.ReceiverType = r.text(int(r.u(8)))
+	descriptor.ReceiverFirmware = r.text(int(r.u(8)))
+	descriptor.ReceiverSerial = r.text(int(r.u(8)))
+	return descriptor
+}
+
+// decodeRTCMMSM decodes an MSM4 or MSM7 observation message
+func decodeRTCMMSM(r *rtcmReader, messageType uint16) Observations {
+	system := msmSystems[messageType/10]
+	msm7 := messageType%10 == 7
+
+	stationID := uint16(r.u(12))
+	epoch := uint32(r.u(30))
+	r.u(1 + 3 + 7 + 2 + 2 + 1 + 3) // Multiple message bit, IODS, reserved, clock steering and external clock, smoothing
+
+	satellites := []int{}
+	for i := 1; i <= 64; i++ {
+		if r.u(1) == 1 {
+			satellites = append(satellites, i)
+		}
+	}
+	signals := []int{}
+	for i := 1; i <= 32; i++ {
+		if r.u(1) == 1 {
+			signals = append(signals, i)
+		}
+	}
+	type cell struct{ satellite, signal int }
+	cells := []cell{}
+	if len(satellites)*len(signals) <= 64 {
+		for satellite := range satellites {
+			for signal := range signals {
+				if r.u(1) == 1 {
+					cells = append(cells, cell{satellite, signal})
+				}
+			}
+		}
+	} else {
+		r.overrun = true
+	}
+
+	// Satellite data, field by field for all satellites
+	roughInteger := make([]uint64, len(satellites))
+	roughModulo := make([]uint64, len(satellites))
+	extendedInfo := make([]uint64, len(satellites))
+	roughRate := make([]int64, len(satellites))
+	for i := range satellites {
+		roughInteger[i] = r.u(8)
+	}
+	if msm7 {
+		for i := range satellites {
+			extendedInfo[i] = r.u(4)
+		}
+	}
+	for i := range satellites {
+		roughModulo[i] = r.u(10)
+	}
+	if msm7 {
+		for i := range satellites {
+			roughRate[i] = r.s(14)
+		}
+	}
+
+	// Signal data, field by field for all cells
+	finePseudorange := make([]int64, len(cells))
+	finePhase := make([]int64, len(cells))
+	lockTime := make([]uint64, len(cells))
+	halfCycle := make([]uint64, len(cells))
+	cn0 := make([]uint64, len(cells))
+	fineRate := make([]int64, len(cells))
+	pseudorangeBits, phaseBits, lockBits, cn0Bits := 15, 22, 4, 6
+	if msm7 {
+		pseudorangeBits, phaseBits, lockBits, cn0Bits = 20, 24, 10, 10
+	}
+	for i := range cells {
+		finePseudorange[i] = r.s(pseudorangeBits)
+	}
+	for i := range cells {
+		finePhase[i] = r.s(phaseBits)
+	}
+	for i := range cells {
+		lockTime[i] = r.u(lockBits)
+	}
+	for i := range cells {
+		halfCycle[i] = r.u(1)
+	}
+	for i := range cells {
+		cn0[i] = r.u(cn0Bits)
+	}
+	if msm7 {
+		for i := range cells {
+			fineRate[i] = r.s(15)
+		}
+	}
+
+	observations := Observations{
+		BlockTime:  receptionBlockTime(msmEpochToGPS(system, epoch)),
+		Source:     "rtcm3",
+		StationID:  &stationID,
+		Satellites: []SatelliteObservations{},
+	}
+	if r.overrun {
+		return observations
+	}
+
+	// Pseudoranges and phases are stored in ms of light travel time
+	msToMeters := speedOfLight / 1000
+	satelliteIndex := map[int]int{}
+	for i, c := range cells {
+		number := satellites[c.satellite]
+		index, ok := satelliteIndex[c.satellite]
+		if !ok {
+			index = len(observations.Satellites)
+			satelliteIndex[c.satellite] = index
+			satellite := SatelliteObservations{
+				Satellite: rinexSatellite(system, number),
+				System:    system,
+				Signals:   []SignalObservation{},
+			}
+			if system == "GLONASS" {
+				if msm7 && extendedInfo[c.satellite] <= 13 {
+					k := int(extendedInfo[c.satellite]) - 7
+					satellite.FrequencyNumber = &k
+				} else if k, ok := state.glonassFrequencyNumbers[number]; ok {
+					satellite.FrequencyNumber = &k
+				}
+			}
+			observations.Satellites = append(observations.Satellites, satellite)
+		}
+
+		signal := SignalObservation{
+			Signal:             msmSignals[system][signals[c.signal]],
+			HalfCycleAmbiguity: halfCycle[i] == 1,
+		}
+		if signal.Signal == "" {
+			signal.Signal = fmt.Sprintf("unknown(%d)", signals[c.signal])
+		}
+
+		frequency := carrierFrequency(system, signal.Signal, observations.Satellites[index].FrequencyNumber)
+
+		if roughInteger[c.satellite] != 255 {
+			rough := float64(roughInteger[c.satellite]) + float64(roughModulo[c.satellite])/1024
+			if msm7 && finePseudorange[i] != -524288 {
+				signal.Pseudorange = floatPtr((rough + float64(finePseudorange[i])*math.Pow(2, -29)) * msToMeters)
+			} else if !msm7 && finePseudorange[i] != -16384 {
+				signal.Pseudorange = floatPtr((rough + float64(finePseudorange[i])*math.Pow(2, -24)) * msToMeters)
+			}
+			if frequency != 0 {
+				if msm7 && finePhase[i] != -8388608 {
+					signal.CarrierPhase = floatPtr((rough + float64(finePhase[i])*math.Pow(2, -31)) * msToMeters * frequency / speedOfLight)
+				} else if !msm7 && finePhase[i] != -2097152 {
+					signal.CarrierPhase = floatPtr((rough + float64(finePhase[i])*math.Pow(2, -29)) * msToMeters * frequency / speedOfLight)
+				}
+				if msm7 && roughRate[c.satellite] != -8192 && fineRate[i] != -16384 {
+					rate := float64(roughRate[c.satellite]) + float64(fineRate[i])*0.0001
+					signal.Doppler = floatPtr(-rate * frequency / speedOfLight)
+				}
+			}
+		}
+		if cn0[i] != 0 {
+			if msm7 {
+				signal.CN0 = floatPtr(float64(cn0[i]) * 0.0625)
+			} else {
+				signal.CN0 = floatPtr(float64(cn0[i]))
+			}
+		}
+		if msm7 {
+			signal.LockTime = floatPtr(msmExtendedLockTime(lockTime[i]) / 1000)
+		} else {
+			signal.LockTime = floatPtr(msmLockTime(lockTime[i]) / 1000)
+		}
+
+		observations.Satellites[index].Signals = append(observations.Satellites[index].Signals, signal)
+	}
+	return observations
+}
+
+// msmEpochToGPS converts the epoch time of an MSM message to a GPS time of
+// week in ms. GLONASS epochs are the day of week and time of day in Moscow
+// time, BeiDou epochs are in BDT, 14 s behind GPS time.
+func msmEpochToGPS(system string, epoch uint32) uint32 {
+	const weekMs = 7 * 86400000
+	switch system {
+	case "GLONASS":
+		day := int64(epoch >> 27)
+		timeOfDay := int64(epoch & 0x7FFFFFF)
+		if day == 7 {
+			// Day unknown, take the current one
+			_, nowTow := currentGPSTime()
+			day = int64(nowTow) / 86400000
+		}
+		tow := day*86400000 + timeOfDay - 3*3600000 + int64(gpsUtcLeapSeconds)*1000
+		return uint32((tow%weekMs + weekMs) % weekMs)
+	case "BeiDou":
+		return (epoch + 14000) % weekMs
+	}
+	return epoch
+}
+
+// msmLockTime converts the MSM4 lock time indicator to the minimum lock time
+// in ms
+func msmLockTime(indicator uint64) float64 {
+	if indicator == 0 {
+		return 0
+	}
+	return math.Pow(2, float64(indicator+4))
+}
+
+// msmExtendedLockTime converts the MSM7 extended lock time indicator to the
+// lock time in ms. The resolution halves every 32 steps above 64.
+func msmExtendedLockTime(indicator uint64) float64 {
+	if indicator < 64 {
+		return float64(indicator)
+	}
+	if indicator >= 704 {
+		return 67108864
+	}
+	start := 64.0
+	for k := uint64(1); ; k++ {
+		if indicator < 32*k+64 {
+			return start + math.Pow(2, float64(k))*float64(indicator-(32*k+32))
+		}
+		start += 32 * math.Pow(2, float64(k))
+	}
+}
+
+func decodeRTCMGPSEphemeris(r *rtcmReader) Ephemeris {
+	eph := Ephemeris{Source: "rtcm3", System: "GPS"}
+	week, _ := currentGPSTime()
+	eph.BlockTime = receptionBlockTime(0)
+
+	number := int(r.u(6))
+	eph.Satellite = rinexSatellite(eph.System, number)
+	eph.Week = uint16(fullWeek(int(r.u(10)), 10, week))
+	eph.Accuracy = floatPtr(gpsURA[r.u(4)])
+	codes := uint8(r.u(2))
+	eph.CodesOnL2 = &codes
+	eph.IDot = float64(r.s(14)) * math.Pow(2, -43) * math.Pi
+	eph.IODE = uint16(r.u(8))
+	eph.Toc = float64(r.u(16)) * 16
+	eph.Af2 = float64(r.s(8)) * math.Pow(2, -55)
+	eph.Af1 = float64(r.s(16)) * math.Pow(2, -43)
+	eph.Af0 = float64(r.s(22)) * math.Pow(2, -31)
+	iodc := uint16(r.u(10))
+	eph.IODC = &iodc
+	eph.Crs = float64(r.s(16)) * math.Pow(2, -5)
+	eph.DeltaN = float64(r.s(16)) * math.Pow(2, -43) * math.Pi
+	eph.M0 = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.Cuc = float64(r.s(16)) * math.Pow(2, -29)
+	eph.Eccentricity = float64(r.u(32)) * math.Pow(2, -33)
+	eph.Cus = float64(r.s(16)) * math.Pow(2, -29)
+	eph.SqrtA = float64(r.u(32)) * math.Pow(2, -19)
+	eph.Toe = float64(r.u(16)) * 16
+	eph.Cic = float64(r.s(16)) * math.Pow(2, -29)
+	eph.Omega0 = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.Cis = float64(r.s(16)) * math.Pow(2, -29)
+	eph.I0 = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.Crc = float64(r.s(16)) * math.Pow(2, -5)
+	eph.Omega = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.OmegaDot = float64(r.s(24)) * math.Pow(2, -43) * math.Pi
+	eph.TGD = floatPtr(float64(r.s(8)) * math.Pow(2, -31))
+	eph.Health = uint16(r.u(6))
+	l2p := r.u(1) == 1
+	eph.L2PDataFlag = &l2p
+	if r.u(1) == 0 {
+		eph.FitInterval = floatPtr(4)
+	} else {
+		eph.FitInterval = floatPtr(6)
+	}
+	return eph
+}
+
+func decodeRTCMGalileoEphemeris(r *rtcmReader) Ephemeris {
+	eph := Ephemeris{Source: "rtcm3", System: "Galileo"}
+	week, _ := currentGPSTime()
+	eph.BlockTime = receptionBlockTime(0)
+
+	number := int(r.u(6))
+	eph.Satellite = rinexSatellite(eph.System, number)
+	// Galileo weeks start at GPS week 1024
+	eph.Week = uint16(fullWeek(int(r.u(12)), 12, week-1024) + 1024)
+	eph.IODE = uint16(r.u(10))
+	eph.Accuracy = galileoSISA(uint8(r.u(8)))
+	eph.IDot = float64(r.s(14)) * math.Pow(2, -43) * math.Pi
+	eph.Toc = float64(r.u(14)) * 60
+	eph.Af2 = float64(r.s(6)) * math.Pow(2, -59)
+	eph.Af1 = float64(r.s(21)) * math.Pow(2, -46)
+	eph.Af0 = float64(r.s(31)) * math.Pow(2, -34)
+	eph.Crs = float64(r.s(16)) * math.Pow(2, -5)
+	eph.DeltaN = float64(r.s(16)) * math.Pow(2, -43) * math.Pi
+	eph.M0 = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.Cuc = float64(r.s(16)) * math.Pow(2, -29)
+	eph.Eccentricity = float64(r.u(32)) * math.Pow(2, -33)
+	eph.Cus = float64(r.s(16)) * math.Pow(2, -29)
+	eph.SqrtA = float64(r.u(32)) * math.Pow(2, -19)
+	eph.Toe = float64(r.u(14)) * 60
+	eph.Cic = float64(r.s(16)) * math.Pow(2, -29)
+	eph.Omega0 = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.Cis = float64(r.s(16)) * math.Pow(2, -29)
+	eph.I0 = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.Crc = float64(r.s(16)) * math.Pow(2, -5)
+	eph.Omega = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.OmegaDot = float64(r.s(24)) * math.Pow(2, -43) * math.Pi
+	eph.TGD = floatPtr(float64(r.s(10)) * math.Pow(2, -32))
+	eph.TGD2 = floatPtr(float64(r.s(10)) * math.Pow(2, -32))
+
+	// Health bits as in RINEX: E1-B DVS and HS in bits 0-2, E5b DVS and HS in bits 6-8
+	e5bHealth := r.u(2)
+	e5bValidity := r.u(1)
+	e1bHealth := r.u(2)
+	e1bValidity := r.u(1)
+	eph.Health = uint16(e1bValidity | e1bHealth<<1 | e5bValidity<<6 | e5bHealth<<7)
+
+	// I/NAV with the clock for E5b/E1
+	sources := uint16(0x205)
+	eph.DataSources = &sources
+	return eph
+}
+
+func decodeRTCMBeiDouEphemeris(r *rtcmReader) Ephemeris {
+	eph := Ephemeris{Source: "rtcm3", System: "BeiDou"}
+	week, _ := currentGPSTime()
+	eph.BlockTime = receptionBlockTime(0)
+
+	number := int(r.u(6))
+	eph.Satellite = rinexSatellite(eph.System, number)
+	// BDT weeks start at GPS week 1356
+	eph.Week = uint16(fullWeek(int(r.u(13)), 13, week-1356))
+	eph.Accuracy = floatPtr(gpsURA[r.u(4)])
+	eph.IDot = float64(r.s(14)) * math.Pow(2, -43) * math.Pi
+	eph.IODE = uint16(r.u(5))
+	eph.Toc = float64(r.u(17)) * 8
+	eph.Af2 = float64(r.s(11)) * math.Pow(2, -66)
+	eph.Af1 = float64(r.s(22)) * math.Pow(2, -50)
+	eph.Af0 = float64(r.s(24)) * math.Pow(2, -33)
+	aodc := uint16(r.u(5))
+	eph.IODC = &aodc
+	eph.Crs = float64(r.s(18)) * math.Pow(2, -6)
+	eph.DeltaN = float64(r.s(16)) * math.Pow(2, -43) * math.Pi
+	eph.M0 = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.Cuc = float64(r.s(18)) * math.Pow(2, -31)
+	eph.Eccentricity = float64(r.u(32)) * math.Pow(2, -33)
+	eph.Cus = float64(r.s(18)) * math.Pow(2, -31)
+	eph.SqrtA = float64(r.u(32)) * math.Pow(2, -19)
+	eph.Toe = float64(r.u(17)) * 8
+	eph.Cic = float64(r.s(18)) * math.Pow(2, -31)
+	eph.Omega0 = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.Cis = float64(r.s(18)) * math.Pow(2, -31)
+	eph.I0 = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.Crc = float64(r.s(18)) * math.Pow(2, -6)
+	eph.Omega = float64(r.s(32)) * math.Pow(2, -31) * math.Pi
+	eph.OmegaDot = float64(r.s(24)) * math.Pow(2, -43) * math.Pi
+	eph.TGD = floatPtr(float64(r.s(10)) * 1e-10)
+	eph.TGD2 = floatPtr(float64(r.s(10)) * 1e-10)
+	eph.Health = uint16(r.u(1))
+	return eph
+}
+
+func decodeRTCMGLONASSEphemeris(r *rtcmReader) GLONASSEphemeris {
+	eph := GLONASSEphemeris{Source: "rtcm3", System: "GLONASS"}
+	eph.BlockTime = receptionBlockTime(0)
+
+	number := int(r.u(6))
+	eph.Satellite = rinexSatellite(eph.System, number)
+	eph.FrequencyNumber = int(r.u(5)) - 7
+	r.u(1 + 1 + 2) // Almanac health, health availability, P1
+	eph.Tk = uint32(r.u(5))*3600 + uint32(r.u(6))*60 + uint32(r.u(1))*30
+	eph.Health = uint8(r.u(1))
+	r.u(1) // P2
+	eph.Tb = uint16(r.u(7)) * 15
+	eph.VelocityX = float64(r.sm(24)) * math.Pow(2, -20)
+	eph.X = float64(r.sm(27)) * math.Pow(2, -11)
+	eph.AccelerationX = float64(r.sm(5)) * math.Pow(2, -30)
+	eph.VelocityY = float64(r.sm(24)) * math.Pow(2, -20)
+	eph.Y = float64(r.sm(27)) * math.Pow(2, -11)
+	eph.AccelerationY = float64(r.sm(5)) * math.Pow(2, -30)
+	eph.VelocityZ = float64(r.sm(24)) * math.Pow(2, -20)
+	eph.Z = float64(r.sm(27)) * math.Pow(2, -11)
+	eph.AccelerationZ = float64(r.sm(5)) * math.Pow(2, -30)
+	r.u(1) // P3
+	eph.GammaN = float64(r.sm(11)) * math.Pow(2, -40)
+	r.u(2 + 1) // P, ln
+	eph.TauN = float64(r.sm(22)) * math.Pow(2, -30)
+	eph.DeltaTauN = float64(r.sm(5)) * math.Pow(2, -30)
+	eph.Age = uint8(r.u(5))
+
+	// tb is the time of day in Moscow time, UTC+3, of the current day
+	moscow := currentTime().UTC().Add(3 * time.Hour)
+	day := time.Date(moscow.Year(), moscow.Month(), moscow.Day(), 0, 0, 0, 0, time.UTC)
+	toe := day.Add(time.Duration(eph.Tb)*time.Minute - 3*time.Hour)
+	if toe.Sub(currentTime()) > 12*time.Hour {
+		toe = toe.Add(-24 * time.Hour)
+	}
+	eph.Toe = &toe
+
+	if !r.overrun {
+		state.glonassFrequencyNumbers[number] = eph.FrequencyNumber
+	}
+	return eph
+}
+
+// galileoSISA converts a Galileo SISA index to the accuracy in m, nil when
+// no accuracy prediction is available
+func galileoSISA(index uint8) *float64 {
+	switch {
+	case index < 50:
+		return floatPtr(float64(index) * 0.01)
+	case index < 75:
+		return floatPtr(0.5 + float64(index-50)*0.02)
+	case index < 100:
+		return floatPtr(1 + float64(index-75)*0.04)
+	case index < 126:
+		return floatPtr(2 + float64(index-100)*0.16)
+	}
+	return nil
+}
+
+// ecefToGeodetic converts WGS84 ECEF coordinates (m) to latitude and
+// longitude (°) and ellipsoidal height (m)
+func ecefToGeodetic(x, y, z float64) (float64, float64, float64) {
+	const a = 6378137.0
+	const f = 1 / 298.257223563
+	e2 := f * (2 - f)
+
+	p := math.Hypot(x, y)
+	if p == 0 && z == 0 {
+		return 0, 0, 0
+	}
+	latitude := math.Atan2(z, p*(1-e2))
+	height := 0.0
+	for i := 0; i < 10; i++ {
+		n := a / math.Sqrt(1-e2*math.Sin(latitude)*math.Sin(latitude))
+		height = p/math.Cos(latitude) - n
+		latitude = math.Atan2(z, p*(1-e2*n/(n+height)))
+	}
+	return latitude * 180 / math.Pi, math.Atan2(y, x) * 180 / math.Pi, height
+}
@@ -14,21 +14,23 @@ const maxASCIIDisplaySize = 16384
 const maxFormattedInformationBlockSize = 4096
 const maxASCIICommandReplySize = 4096
 const maxEventSize = 256
-
-var hasPrompt bool = false
+const maxNMEASentenceSize = 1024 // Septentrio proprietary sentences exceed the standard 82 characters
 
 //Taken from parse function in ssnrx.cpp
-func Parse(buffer *[]byte) []interface{} {
+func parse(buffer *[]byte) []interface{} {
+	payloads := []interface{}{}
 	done := false
 	for !done {
+		var received []interface{}
 		// We are looking for either
 		// - a prompt (which may be caused by sending [Enter])
 		// - a message, starting with '$', and followed by a character
 		//   indicating the kind of message
+		// - an RTCM3 frame, starting with 0xD3
 		bufferSize := len(*buffer)
 		ndx := 0
 		for ndx = 0; ndx < bufferSize; ndx++ {
-			if (*buffer)[ndx] == '>' || (*buffer)[ndx] == '$' {
+			if (*buffer)[ndx] == '>' || (*buffer)[ndx] == '$' || (*buffer)[ndx] == rtcm3Preamble {
 				break
 			}
 		}
@@ -37,23 +39,27 @@ func Parse(buffer *[]byte) []interface{} {
 		if ndx < bufferSize {
 			if (*buffer)[ndx] == '>' {
 				// '>' terminates a prompt
-				done = handleCommandPrompt(buffer, ndx)
+				done, received = handleCommandPrompt(buffer, ndx)
+			} else if (*buffer)[ndx] == rtcm3Preamble {
+				done, received = handleRTCM3(buffer, ndx)
 			} else {
 				// '$' was found
-				done = handleReceivedData(buffer, ndx)
+				done, received = handleReceivedData(buffer, ndx)
 			}
+			payloads = append(payloads, received...)
 		} else {
 			// We've reached the end of the buffer with nothing found. Discard all data,
 			//except for the last PromptLength-1, because we may have the start of a new prompt
 			done = true
 			if bufferSize > promptLength-1 {
-				*buffer = (*buffer)[promptLength-1:]
+				*buffer = (*buffer)[bufferSize-(promptLength-1):]
 				if bufferSize-len(*buffer) > 0 {
 					log.Printf("[DEBUG] parse - Discarding %d bytes from buffer\n", bufferSize-len(*buffer))
 				}
 			}
 		}
 	}
+	return payloads
 }
 
 func handleCommandPrompt(buffer *[]byte, ndx int) (bool, []interface{}) {
@@ -77,11 +83,12 @@ func handleCommandPrompt(buffer *[]byte, ndx int) (bool, []interface{}) {
 		if ndx-len(prompt)+1 > 0 {
 			log.Printf("[DEBUG] parse - Command prompt received: %s\n", string(prompt))
 		}
+		state.hasPrompt = true
 		// TODO - Implement this code if we need the adapter to handle command prompts
 		//setPrompt(prompt);
 	}
 	log.Printf("[DEBUG] parse - Removing prompt from buffer: %s\n", string(prompt))
-	*buffer = append((*buffer)[:ndx-promptLength], (*buffer)[ndx+1:]...)
+	*buffer = (*buffer)[ndx+1:]
 	return false, payloads
 }
 
@@ -92,12 +99,14 @@ func handleReceivedData(buffer *[]byte, ndx int) (bool, []interface{}) {
 		*buffer = (*buffer)[ndx:]
 	}
 	if len(*buffer) >= 2 {
-		if (*buffer)[1] == '@' || (*buffer)[1] == 'R' || (*buffer)[1] == 'T' || (*buffer)[1] == '-' {
+		if (*buffer)[1] == '@' || (*buffer)[1] == 'R' || (*buffer)[1] == 'T' || (*buffer)[1] == '-' || isNMEAAddress((*buffer)[1]) {
 			notEnoughData := false
 			processedBytes := 0
 
 			if (*buffer)[1] == '@' {
-				processedBytes, notEnoughData = parseSBF(buffer, 0)
+				var blockPayloads []interface{}
+				processedBytes, notEnoughData, blockPayloads = parseSBF(buffer, 0)
+				payloads = append(payloads, blockPayloads...)
 			} else if (*buffer)[1] == 'R' {
 				processedBytes, notEnoughData = parseASCIICommandReply(buffer, 0)
 			} else if (*buffer)[1] == 'T' {
@@ -112,6 +121,10 @@ func handleReceivedData(buffer *[]byte, ndx int) (bool, []interface{}) {
 				}
 			} else if (*buffer)[1] == '-' {
 				processedBytes, notEnoughData = parseFormattedInformationBlock(buffer, 0)
+			} else {
+				var sentencePayloads []interface{}
+				processedBytes, notEnoughData, sentencePayloads = parseNMEA(buffer, 0)
+				payloads = append(payloads, sentencePayloads...)
 			}
 
 			if processedBytes > 0 {
@@ -138,44 +151,99 @@ func handleReceivedData(buffer *[]byte, ndx int) (bool, []interface{}) {
 	}
 }
 
-func parseSBF(buffer *[]byte, ndx int) (int, bool) {
+func parseSBF(buffer *[]byte, ndx int) (int, bool, []interface{}) {
 	bufferSize := len(*buffer)
 	notEnoughData := false
 
 	if string((*buffer)[ndx:ndx+2]) != "$@" {
-		return -1, notEnoughData
+		return -1, notEnoughData, nil
 	}
 	if bufferSize-ndx < 8 {
 		notEnoughData = true
-		return -1, notEnoughData
+		return -1, notEnoughData, nil
 	}
 
-	// get the length field from the SBF header
-	length := uint16((*buffer)[6])<<8 + uint16((*buffer)[7])
-	if length < 8 {
+	// get the length field from the SBF header (SBF is little endian)
+	length := int(uint16((*buffer)[ndx+6]) + uint16((*buffer)[ndx+7])<<8)
+	if length < MIN_SBFSIZE {
 		log.Printf("[ERROR] parseSBF - Invalid SBF block length: %d\n", length)
-		return -1, notEnoughData
+		return -1, notEnoughData, nil
 	}
-	if length > uint16(bufferSize-ndx) {
+	if length > bufferSize-ndx {
 		notEnoughData = true
-		return -1, notEnoughData
+		return -1, notEnoughData, nil
 	}
 
 	//Parse the CRC
-	expectedCRC := uint16((*buffer)[ndx+2])<<8 + uint16((*buffer)[ndx+3])
-	//Recalculate the CRC
-	actualCRC := crc.CalculateCRC(crc.CCITT, (*buffer)[ndx+4:length-4])
+	expectedCRC := uint16((*buffer)[ndx+2]) + uint16((*buffer)[ndx+3])<<8
+	//Recalculate the CRC over the ID, length and body of the block
+	actualCRC := crc.CalculateCRC(crc.XMODEM, (*buffer)[ndx+4:ndx+length])
 
 	if actualCRC != uint64(expectedCRC) {
 		log.Printf("[ERROR] parseSBF - SBF CRC error. Expected: %d, calculated:%d\n", expectedCRC, actualCRC)
-		return -1, notEnoughData
+		return -1, notEnoughData, nil
 	}
 	// emit newSBFBlock(mBuffer.mid(startIndex, length));
 	// emit newSBFBlockWithId(mBuffer.mid(startIndex, length), (actualID & 0x1fff), (actualID >> 13));
 
-	//TODO - Send the SBF block for parsing
-	handleSbfBlock((*buffer)[ndx:length])
-	return int(length), notEnoughData
+	return length, notEnoughData, handleSbfBlock((*buffer)[ndx : ndx+length])
+}
+
+func handleRTCM3(buffer *[]byte, ndx int) (bool, []interface{}) {
+	if ndx > 0 {
+		*buffer = (*buffer)[ndx:]
+	}
+	if len(*buffer) < rtcm3HeaderLength {
+		// the header is not complete yet, parsing will be reattempted upon receiving more data
+		return true, []interface{}{}
+	}
+
+	length, ok := rtcm3FrameLength(*buffer)
+	if !ok {
+		// not the start of a frame
+		*buffer = (*buffer)[1:]
+		return false, []interface{}{}
+	}
+	if length > len(*buffer) {
+		return true, []interface{}{}
+	}
+	if !rtcm3CRCValid((*buffer)[:length]) {
+		log.Printf("[DEBUG] handleRTCM3 - RTCM3 CRC error, discarding %d bytes from buffer\n", 1)
+		*buffer = (*buffer)[1:]
+		return false, []interface{}{}
+	}
+
+	payloads := handleRTCM3Frame((*buffer)[:length])
+	*buffer = (*buffer)[length:]
+	return false, payloads
+}
+
+// isNMEAAddress tells whether the character following a '$' starts the address
+// of an NMEA sentence. $R and $T are taken by command replies and ASCII
+// displays and events.
+func isNMEAAddress(c byte) bool {
+	return c >= 'A' && c <= 'Z' && c != 'R' && c != 'T'
+}
+
+func parseNMEA(buffer *[]byte, ndx int) (int, bool, []interface{}) {
+	notEnoughData := false
+
+	endIndex := strings.IndexByte(string((*buffer)[ndx:]), '\n')
+	if endIndex == -1 || endIndex >= maxNMEASentenceSize {
+		if endIndex == -1 && len(*buffer)-ndx < maxNMEASentenceSize {
+			notEnoughData = true
+		} else {
+			log.Printf("[ERROR] parseNMEA - NMEA sentence exceeds %d bytes\n", maxNMEASentenceSize)
+		}
+		return -1, notEnoughData, nil
+	}
+
+	sentence := string((*buffer)[ndx : ndx+endIndex+1])
+	if _, err := nmeaChecksum(sentence); err != nil {
+		log.Printf("[ERROR] parseNMEA - Invalid NMEA sentence: %s\n", err.Error())
+		return -1, notEnoughData, nil
+	}
+	return endIndex + 1, notEnoughData, handleNMEASentence(sentence) // total processed bytes, including the line end
 }
 
 func parseASCIICommandReply(buffer *[]byte, ndx int) (int, bool) {
@@ -327,17 +395,18 @@ func parseFormattedInformationBlock(buffer *[]byte, ndx int) (int, bool) {
 	// }
 }
 
-func handleSbfBlock(buffer []byte) {
+func handleSbfBlock(buffer []byte) []interface{} {
 	//Parse the SBF ID
-	sbfID := uint16(buffer[4])<<8 + uint16(buffer[5])
+	sbfID := uint16(buffer[4]) + uint16(buffer[5])<<8
+	payloads := []interface{}{}
 
 	switch sbfID {
 	/* Measurement Blocks */
 	case sbfnr_GenMeasEpoch_1: //    = 5944
 	//case sbfid_GenMeasEpoch_1_0: //= 5944 | 0x0
-	case sbfnr_MeasEpoch_2: //= 4027
-	//case sbfid_MeasEpoch_2_0: //= 4027 | 0x0
-	case sbfid_MeasEpoch_2_1: //= 4027 | 0x2000
+	case sbfnr_MeasEpoch_2, sbfid_MeasEpoch_2_1: //= 4027, 4027 | 0x2000
+		//case sbfid_MeasEpoch_2_0: //= 4027 | 0x0
+		payloads = handleMeasEpoch(buffer)
 	case sbfnr_MeasExtra_1: //= 4000
 	//case sbfid_MeasExtra_1_0: //= 4000 | 0x0
 	case sbfid_MeasExtra_1_1: //= 4000 | 0x2000
@@ -346,6 +415,7 @@ func handleSbfBlock(buffer []byte) {
 	case sbfnr_MeasFullRange_1: //= 4098
 	//case sbfid_MeasFullRange_1_0: //= 4098 | 0x0
 	case sbfid_MeasFullRange_1_1: //= 4098 | 0x2000
+	/* The compressed Meas3 blocks are not decoded, observations come from MeasEpoch only */
 	case sbfnr_Meas3Ranges_1: //= 4109
 	//case sbfid_Meas3Ranges_1_0: //= 4109 | 0x0
 	case sbfnr_Meas3CN0HiRes_1: //= 4110
@@ -426,19 +496,23 @@ func handleSbfBlock(buffer []byte) {
 
 	/* GPS Decoded Message Blocks */
 	case sbfnr_GPSNav_1: //= 5891
-	//case sbfid_GPSNav_1_0: //= 5891 | 0x0
+		//case sbfid_GPSNav_1_0: //= 5891 | 0x0
+		payloads = handleGPSNav(buffer)
 	case sbfnr_GPSAlm_1: //= 5892
 	//case sbfid_GPSAlm_1_0: //= 5892 | 0x0
 	case sbfnr_GPSIon_1: //= 5893
-	//case sbfid_GPSIon_1_0: //= 5893 | 0x0
+		//case sbfid_GPSIon_1_0: //= 5893 | 0x0
+		payloads = handleGPSIon(buffer)
 	case sbfnr_GPSUtc_1: //= 5894
-	//case sbfid_GPSUtc_1_0: //= 5894 | 0x0
+		//case sbfid_GPSUtc_1_0: //= 5894 | 0x0
+		payloads = handleGPSUtc(buffer)
 	case sbfnr_GPSCNav_1: //= 4042
 	//case sbfid_GPSCNav_1_0: //= 4042 | 0x0
 
 	/* GLONASS Decoded Message Blocks */
 	case sbfnr_GLONav_1: //= 4004
-	//case sbfid_GLONav_1_0: //= 4004 | 0x0
+		//case sbfid_GLONav_1_0: //= 4004 | 0x0
+		payloads = handleGLONav(buffer)
 	case sbfnr_GLOAlm_1: //= 4005
 	//case sbfid_GLOAlm_1_0: //= 4005 | 0x0
 	case sbfnr_GLOTime_1: //= 4036
@@ -446,13 +520,16 @@ func handleSbfBlock(buffer []byte) {
 
 	/* Galileo Decoded Message Blocks */
 	case sbfnr_GALNav_1: //= 4002
-	//case sbfid_GALNav_1_0: //= 4002 | 0x0
+		//case sbfid_GALNav_1_0: //= 4002 | 0x0
+		payloads = handleGALNav(buffer)
 	case sbfnr_GALAlm_1: //= 4003
 	//case sbfid_GALAlm_1_0: //= 4003 | 0x0
 	case sbfnr_GALIon_1: //= 4030
-	//case sbfid_GALIon_1_0: //= 4030 | 0x0
+		//case sbfid_GALIon_1_0: //= 4030 | 0x0
+		payloads = handleGALIon(buffer)
 	case sbfnr_GALUtc_1: //= 4031
-	//case sbfid_GALUtc_1_0: //= 4031 | 0x0
+		//case sbfid_GALUtc_1_0: //= 4031 | 0x0
+		payloads = handleGALUtc(buffer)
 	case sbfnr_GALGstGps_1: //= 4032
 	//case sbfid_GALGstGps_1_0: //= 4032 | 0x0
 	case sbfnr_GALSARRLM_1: //= 4034
@@ -460,7 +537,8 @@ func handleSbfBlock(buffer []byte) {
 
 	/* BeiDou Decoded Message Blocks */
 	case sbfnr_BDSNav_1: //= 4081
-	//case sbfid_BDSNav_1_0: //= 4081 | 0x0
+		//case sbfid_BDSNav_1_0: //= 4081 | 0x0
+		payloads = handleBDSNav(buffer)
 	case sbfnr_BDSAlm_1: //= 4119
 	//case sbfid_BDSAlm_1_0: //= 4119 | 0x0
 	case sbfnr_BDSIon_1: //= 4120
@@ -470,7 +548,8 @@ func handleSbfBlock(buffer []byte) {
 
 	/* QZSS Decoded Message Blocks */
 	case sbfnr_QZSNav_1: //= 4095
-	//case sbfid_QZSNav_1_0: //= 4095 | 0x0
+		//case sbfid_QZSNav_1_0: //= 4095 | 0x0
+		payloads = handleQZSNav(buffer)
 	case sbfnr_QZSAlm_1: //= 4116
 	//case sbfid_QZSAlm_1_0: //= 4116 | 0x0
 
@@ -486,7 +565,8 @@ func handleSbfBlock(buffer []byte) {
 	case sbfnr_GEOFastCorrDegr_1: //= 5929
 	//case sbfid_GEOFastCorrDegr_1_0: //= 5929 | 0x0
 	case sbfnr_GEONav_1: //= 5896
-	//case sbfid_GEONav_1_0: //= 5896 | 0x0
+		//case sbfid_GEONav_1_0: //= 5896 | 0x0
+		payloads = handleGEONav(buffer)
 	case sbfnr_GEODegrFactors_1: //= 5930
 	//case sbfid_GEODegrFactors_1_0: //= 5930 | 0x0
 	case sbfnr_GEONetworkTime_1: //= 5918
@@ -525,10 +605,9 @@ func handleSbfBlock(buffer []byte) {
 	//case sbfid_PVTCartesian_2_0: //= 4006 | 0x0
 	case sbfid_PVTCartesian_2_1: //= 4006 | 0x2000
 	case sbfid_PVTCartesian_2_2: //= 4006 | 0x4000
-	case sbfnr_PVTGeodetic_2: //= 4007
-	//case sbfid_PVTGeodetic_2_0: //= 4007 | 0x0
-	case sbfid_PVTGeodetic_2_1: //= 4007 | 0x2000
-	case sbfid_PVTGeodetic_2_2: //= 4007 | 0x4000
+	case sbfnr_PVTGeodetic_2, sbfid_PVTGeodetic_2_1, sbfid_PVTGeodetic_2_2: //= 4007, 4007 | 0x2000, 4007 | 0x4000
+		//case sbfid_PVTGeodetic_2_0: //= 4007 | 0x0
+		payloads = handlePVTGeodetic(buffer)
 	case sbfnr_PVTGeodeticAuth_1: //= 4232
 	//case sbfid_PVTGeodeticAuth_1_0: //= 4232 | 0x0
 	case sbfid_PVTGeodeticAuth_1_1: //= 4232 | 0x2000
@@ -536,13 +615,15 @@ func handleSbfBlock(buffer []byte) {
 	case sbfnr_PosCovCartesian_1: //= 5905
 	//case sbfid_PosCovCartesian_1_0: //= 5905 | 0x0
 	case sbfnr_PosCovGeodetic_1: //= 5906
-	//case sbfid_PosCovGeodetic_1_0: //= 5906 | 0x0
+		//case sbfid_PosCovGeodetic_1_0: //= 5906 | 0x0
+		payloads = handlePosCovGeodetic(buffer)
 	case sbfnr_VelCovCartesian_1: //= 5907
 	//case sbfid_VelCovCartesian_1_0: //= 5907 | 0x0
 	case sbfnr_VelCovGeodetic_1: //= 5908
 	//case sbfid_VelCovGeodetic_1_0: //= 5908 | 0x0
 	case sbfnr_DOP_2: //= 4001
-	//case sbfid_DOP_2_0: //= 4001 | 0x0
+		//case sbfid_DOP_2_0: //= 4001 | 0x0
+		payloads = handleDOP(buffer)
 	case sbfnr_PosCart_1: //= 4044
 	//case sbfid_PosCart_1_0: //= 4044 | 0x0
 	case sbfnr_PosLocal_1: //= 4052
@@ -572,34 +653,42 @@ func handleSbfBlock(buffer []byte) {
 
 	/* INS/GNSS Integrated Blocks */
 	case sbfnr_IntPVCart_1: //= 4060
-	//case sbfid_IntPVCart_1_0: //= 4060 | 0x0
+		//case sbfid_IntPVCart_1_0: //= 4060 | 0x0
+		payloads = handleIntPVCart(buffer)
 	case sbfnr_IntPVGeod_1: //= 4061
-	//case sbfid_IntPVGeod_1_0: //= 4061 | 0x0
+		//case sbfid_IntPVGeod_1_0: //= 4061 | 0x0
+		payloads = handleIntPVGeod(buffer)
 	case sbfnr_IntPosCovCart_1: //= 4062
 	//case sbfid_IntPosCovCart_1_0: //= 4062 | 0x0
 	case sbfnr_IntVelCovCart_1: //= 4063
 	//case sbfid_IntVelCovCart_1_0: //= 4063 | 0x0
 	case sbfnr_IntPosCovGeod_1: //= 4064
-	//case sbfid_IntPosCovGeod_1_0: //= 4064 | 0x0
+		//case sbfid_IntPosCovGeod_1_0: //= 4064 | 0x0
+		payloads = handleIntPosCovGeod(buffer)
 	case sbfnr_IntVelCovGeod_1: //= 4065
 	//case sbfid_IntVelCovGeod_1_0: //= 4065 | 0x0
-	case sbfnr_IntAttEuler_1: //= 4070
-	//case sbfid_IntAttEuler_1_0: //= 4070 | 0x0
-	case sbfid_IntAttEuler_1_1: //= 4070 | 0x2000
+	case sbfnr_IntAttEuler_1, sbfid_IntAttEuler_1_1: //= 4070, 4070 | 0x2000
+		//case sbfid_IntAttEuler_1_0: //= 4070 | 0x0
+		payloads = handleIntAttEuler(buffer)
 	case sbfnr_IntAttCovEuler_1: //= 4072
 	//case sbfid_IntAttCovEuler_1_0: //= 4072 | 0x0
 	case sbfnr_IntPVAAGeod_1: //= 4045
-	//case sbfid_IntPVAAGeod_1_0: //= 4045 | 0x0
+		//case sbfid_IntPVAAGeod_1_0: //= 4045 | 0x0
+		payloads = handleIntPVAAGeod(buffer)
 	case sbfnr_INSNavCart_1: //= 4225
-	//case sbfid_INSNavCart_1_0: //= 4225 | 0x0
+		//case sbfid_INSNavCart_1_0: //= 4225 | 0x0
+		payloads = handleINSNavCart(buffer)
 	case sbfnr_INSNavGeod_1: //= 4226
-	//case sbfid_INSNavGeod_1_0: //= 4226 | 0x0
+		//case sbfid_INSNavGeod_1_0: //= 4226 | 0x0
+		payloads = handleINSNavGeod(buffer)
 	case sbfnr_IMUBias_1: //= 4241
-	//case sbfid_IMUBias_1_0: //= 4241 | 0x0
+		//case sbfid_IMUBias_1_0: //= 4241 | 0x0
+		payloads = handleIMUBias(buffer)
 
 	/* GNSS Attitude Blocks */
 	case sbfnr_AttEuler_1: //= 5938
-	//case sbfid_AttEuler_1_0: //= 5938 | 0x0
+		//case sbfid_AttEuler_1_0: //= 5938 | 0x0
+		payloads = handleAttEuler(buffer)
 	case sbfnr_AttCovEuler_1: //= 5939
 	//case sbfid_AttCovEuler_1_0: //= 5939 | 0x0
 	case sbfnr_AuxAntPositions_1: //= 5942
@@ -613,35 +702,38 @@ func handleSbfBlock(buffer []byte) {
 
 	/* Receiver Time Blocks */
 	case sbfnr_ReceiverTime_1: //= 5914
-	//case sbfid_ReceiverTime_1_0: //= 5914 | 0x0
+		//case sbfid_ReceiverTime_1_0: //= 5914 | 0x0
+		payloads = handleReceiverTime(buffer)
 	case sbfnr_xPPSOffset_1: //= 5911
-	//case sbfid_xPPSOffset_1_0: //= 5911 | 0x0
-	case sbfnr_SysTimeOffset_1: //= 4039
-	//case sbfid_SysTimeOffset_1_0: //= 4039 | 0x0
-	case sbfid_SysTimeOffset_1_1: //= 4039 | 0x2000
+		//case sbfid_xPPSOffset_1_0: //= 5911 | 0x0
+		payloads = handleXPPSOffset(buffer)
+	case sbfnr_SysTimeOffset_1, sbfid_SysTimeOffset_1_1: //= 4039, 4039 | 0x2000
+		//case sbfid_SysTimeOffset_1_0: //= 4039 | 0x0
+		payloads = handleSysTimeOffset(buffer)
 
 	/* External Event Blocks */
-	case sbfnr_ExtEvent_1: //= 5924
-	//case sbfid_ExtEvent_1_0: //= 5924 | 0x0
-	case sbfid_ExtEvent_1_1: //= 5924 | 0x2000
-	case sbfnr_ExtEventPVTCartesian_1: //= 4037
-	//case sbfid_ExtEventPVTCartesian_1_0: //= 4037 | 0x0
-	case sbfid_ExtEventPVTCartesian_1_1: //= 4037 | 0x2000
-	case sbfid_ExtEventPVTCartesian_1_2: //= 4037 | 0x4000
-	case sbfnr_ExtEventPVTGeodetic_1: //= 4038
-	//case sbfid_ExtEventPVTGeodetic_1_0: //= 4038 | 0x0
-	case sbfid_ExtEventPVTGeodetic_1_1: //= 4038 | 0x2000
-	case sbfid_ExtEventPVTGeodetic_1_2: //= 4038 | 0x4000
+	case sbfnr_ExtEvent_1, sbfid_ExtEvent_1_1: //= 5924, 5924 | 0x2000
+		//case sbfid_ExtEvent_1_0: //= 5924 | 0x0
+		payloads = handleExtEvent(buffer)
+	case sbfnr_ExtEventPVTCartesian_1, sbfid_ExtEventPVTCartesian_1_1, sbfid_ExtEventPVTCartesian_1_2: //= 4037, 4037 | 0x2000, 4037 | 0x4000
+		//case sbfid_ExtEventPVTCartesian_1_0: //= 4037 | 0x0
+		payloads = handleExtEventPVTCartesian(buffer)
+	case sbfnr_ExtEventPVTGeodetic_1, sbfid_ExtEventPVTGeodetic_1_1, sbfid_ExtEventPVTGeodetic_1_2: //= 4038, 4038 | 0x2000, 4038 | 0x4000
+		//case sbfid_ExtEventPVTGeodetic_1_0: //= 4038 | 0x0
+		payloads = handleExtEventPVTGeodetic(buffer)
 	case sbfnr_ExtEventBaseVectCart_1: //= 4216
 	//case sbfid_ExtEventBaseVectCart_1_0: //= 4216 | 0x0
 	case sbfnr_ExtEventBaseVectGeod_1: //= 4217
-	//case sbfid_ExtEventBaseVectGeod_1_0: //= 4217 | 0x0
+		//case sbfid_ExtEventBaseVectGeod_1_0: //= 4217 | 0x0
+		payloads = handleExtEventBaseVectGeod(buffer)
 	case sbfnr_ExtEventINSNavCart_1: //= 4229
 	//case sbfid_ExtEventINSNavCart_1_0: //= 4229 | 0x0
 	case sbfnr_ExtEventINSNavGeod_1: //= 4230
-	//case sbfid_ExtEventINSNavGeod_1_0: //= 4230 | 0x0
+		//case sbfid_ExtEventINSNavGeod_1_0: //= 4230 | 0x0
+		payloads = handleExtEventINSNavGeod(buffer)
 	case sbfnr_ExtEventAttEuler_1: //= 4237
-	//case sbfid_ExtEventAttEuler_1_0: //= 4237 | 0x0
+		//case sbfid_ExtEventAttEuler_1_0: //= 4237 | 0x0
+		payloads = handleExtEventAttEuler(buffer)
 
 	/* Differential Correction Blocks */
 	case sbfnr_DiffCorrIn_1: //= 5919
@@ -655,95 +747,112 @@ func handleSbfBlock(buffer []byte) {
 
 	/* L-Band Demodulator Blocks */
 	case sbfnr_LBandReceiverStatus_1: //= 4200
-	//case sbfid_LBandReceiverStatus_1_0: //= 4200 | 0x0
-	case sbfnr_LBandTrackerStatus_1: //= 4201
-	//case sbfid_LBandTrackerStatus_1_0: //= 4201 | 0x0
-	case sbfid_LBandTrackerStatus_1_1: //= 4201 | 0x2000
-	case sbfid_LBandTrackerStatus_1_2: //= 4201 | 0x4000
-	case sbfid_LBandTrackerStatus_1_3: //= 4201 | 0x6000
-	case sbfnr_LBAS1DecoderStatus_1: //= 4202
-	//case sbfid_LBAS1DecoderStatus_1_0: //= 4202 | 0x0
-	case sbfid_LBAS1DecoderStatus_1_1: //= 4202 | 0x2000
-	case sbfid_LBAS1DecoderStatus_1_2: //= 4202 | 0x4000
+		//case sbfid_LBandReceiverStatus_1_0: //= 4200 | 0x0
+		payloads = handleLBandReceiverStatus(buffer)
+	case sbfnr_LBandTrackerStatus_1, sbfid_LBandTrackerStatus_1_1, sbfid_LBandTrackerStatus_1_2, sbfid_LBandTrackerStatus_1_3: //= 4201, 4201 | 0x2000, 4201 | 0x4000, 4201 | 0x6000
+		//case sbfid_LBandTrackerStatus_1_0: //= 4201 | 0x0
+		payloads = handleLBandTrackerStatus(buffer)
+	case sbfnr_LBAS1DecoderStatus_1, sbfid_LBAS1DecoderStatus_1_1, sbfid_LBAS1DecoderStatus_1_2: //= 4202, 4202 | 0x2000, 4202 | 0x4000
+		//case sbfid_LBAS1DecoderStatus_1_0: //= 4202 | 0x0
+		payloads = handleLBAS1DecoderStatus(buffer)
 	case sbfnr_LBAS1Messages_1: //= 4203
-	//case sbfid_LBAS1Messages_1_0: //= 4203 | 0x0
+		//case sbfid_LBAS1Messages_1_0: //= 4203 | 0x0
+		payloads = handleLBAS1Messages(buffer)
 	case sbfnr_LBandBeams_1: //= 4204
-	//case sbfid_LBandBeams_1_0: //= 4204 | 0x0
+		//case sbfid_LBandBeams_1_0: //= 4204 | 0x0
+		payloads = handleLBandBeams(buffer)
 	case sbfnr_LBandRaw_1: //= 4212
-	//case sbfid_LBandRaw_1_0: //= 4212 | 0x0
+		//case sbfid_LBandRaw_1_0: //= 4212 | 0x0
+		payloads = handleLBandRaw(buffer)
 	case sbfnr_FugroStatus_1: //= 4214
-	//case sbfid_FugroStatus_1_0: //= 4214 | 0x0
+		//case sbfid_FugroStatus_1_0: //= 4214 | 0x0
+		payloads = handleFugroStatus(buffer)
 
 	/* External Sensor Blocks */
 	case sbfnr_ExtSensorMeas_1: //= 4050
-	//case sbfid_ExtSensorMeas_1_0: //= 4050 | 0x0
+		//case sbfid_ExtSensorMeas_1_0: //= 4050 | 0x0
+		payloads = handleExtSensorMeas(buffer)
 	case sbfnr_ExtSensorStatus_1: //= 4056
-	//case sbfid_ExtSensorStatus_1_0: //= 4056 | 0x0
-	case sbfnr_ExtSensorSetup_1: //= 4057
-	//case sbfid_ExtSensorSetup_1_0: //= 4057 | 0x0
-	case sbfid_ExtSensorSetup_1_1: //= 4057 | 0x2000
-	case sbfid_ExtSensorSetup_1_2: //= 4057 | 0x4000
+		//case sbfid_ExtSensorStatus_1_0: //= 4056 | 0x0
+		payloads = handleExtSensorStatus(buffer)
+	case sbfnr_ExtSensorSetup_1, sbfid_ExtSensorSetup_1_1, sbfid_ExtSensorSetup_1_2: //= 4057, 4057 | 0x2000, 4057 | 0x4000
+		//case sbfid_ExtSensorSetup_1_0: //= 4057 | 0x0
+		payloads = handleExtSensorSetup(buffer)
 	case sbfnr_ExtSensorStatus_2: //= 4223
-	//case sbfid_ExtSensorStatus_2_0: //= 4223 | 0x0
+		//case sbfid_ExtSensorStatus_2_0: //= 4223 | 0x0
+		payloads = handleExtSensorStatus2(buffer)
 	case sbfnr_ExtSensorInfo_1: //= 4222
-	//case sbfid_ExtSensorInfo_1_0: //= 4222 | 0x0
+		//case sbfid_ExtSensorInfo_1_0: //= 4222 | 0x0
+		payloads = handleExtSensorInfo(buffer)
 	case sbfnr_IMUSetup_1: //= 4224
-	//case sbfid_IMUSetup_1_0: //= 4224 | 0x0
+		//case sbfid_IMUSetup_1_0: //= 4224 | 0x0
+		payloads = handleIMUSetup(buffer)
 
 	/* Status Blocks */
 	case sbfnr_ReceiverStatus_1: //= 5913
 	//case sbfid_ReceiverStatus_1_0: //= 5913 | 0x0
 	case sbfnr_TrackingStatus_1: //= 5912
-	//case sbfid_TrackingStatus_1_0: //= 5912 | 0x0
+		//case sbfid_TrackingStatus_1_0: //= 5912 | 0x0
+		payloads = handleTrackingStatus(buffer)
 	case sbfnr_ChannelStatus_1: //= 4013
-	//case sbfid_ChannelStatus_1_0: //= 4013 | 0x0
-	case sbfnr_ReceiverStatus_2: //= 4014
-	//case sbfid_ReceiverStatus_2_0: //= 4014 | 0x0
-	case sbfid_ReceiverStatus_2_1: //= 4014 | 0x2000
+		//case sbfid_ChannelStatus_1_0: //= 4013 | 0x0
+		payloads = handleChannelStatus(buffer)
+	case sbfnr_ReceiverStatus_2, sbfid_ReceiverStatus_2_1: //= 4014, 4014 | 0x2000
+		//case sbfid_ReceiverStatus_2_0: //= 4014 | 0x0
+		payloads = handleReceiverStatus2(buffer)
 	case sbfnr_SatVisibility_1: //= 4012
-	//case sbfid_SatVisibility_1_0: //= 4012 | 0x0
+		//case sbfid_SatVisibility_1_0: //= 4012 | 0x0
+		payloads = handleSatVisibility(buffer)
 	case sbfnr_InputLink_1: //= 4090
-	//case sbfid_InputLink_1_0: //= 4090 | 0x0
-	case sbfnr_OutputLink_1: //= 4091
-	//case sbfid_OutputLink_1_0: //= 4091 | 0x0
-	case sbfid_OutputLink_1_1: //= 4091 | 0x2000
+		//case sbfid_InputLink_1_0: //= 4090 | 0x0
+		payloads = handleInputLink(buffer)
+	case sbfnr_OutputLink_1, sbfid_OutputLink_1_1: //= 4091, 4091 | 0x2000
+		//case sbfid_OutputLink_1_0: //= 4091 | 0x0
+		payloads = handleOutputLink(buffer)
 	//case sbfnr_NTRIPClientStatus_1: //= 4053
 	case sbfid_NTRIPClientStatus_1_0: //= 4053 | 0x0
+		payloads = handleNTRIPStatus(buffer, "client")
 	case sbfnr_NTRIPServerStatus_1: //= 4122
-	//case sbfid_NTRIPServerStatus_1_0: //= 4122 | 0x0
-	case sbfnr_IPStatus_1: //= 4058
-	//case sbfid_IPStatus_1_0: //= 4058 | 0x0
-	case sbfid_IPStatus_1_1: //= 4058 | 0x2000
+		//case sbfid_NTRIPServerStatus_1_0: //= 4122 | 0x0
+		payloads = handleNTRIPStatus(buffer, "server")
+	case sbfnr_IPStatus_1, sbfid_IPStatus_1_1: //= 4058, 4058 | 0x2000
+		//case sbfid_IPStatus_1_0: //= 4058 | 0x0
+		payloads = handleIPStatus(buffer)
 	case sbfnr_WiFiAPStatus_1: //= 4054
-	//case sbfid_WiFiAPStatus_1_0: //= 4054 | 0x0
+		//case sbfid_WiFiAPStatus_1_0: //= 4054 | 0x0
+		payloads = handleWiFiAPStatus(buffer)
 	case sbfnr_WiFiClientStatus_1: //= 4096
-	//case sbfid_WiFiClientStatus_1_0: //= 4096 | 0x0
-	case sbfnr_CellularStatus_1: //= 4055
-	//case sbfid_CellularStatus_1_0: //= 4055 | 0x0
-	case sbfid_CellularStatus_1_1: //= 4055 | 0x2000
+		//case sbfid_WiFiClientStatus_1_0: //= 4096 | 0x0
+		payloads = handleWiFiClientStatus(buffer)
+	case sbfnr_CellularStatus_1, sbfid_CellularStatus_1_1: //= 4055, 4055 | 0x2000
+		//case sbfid_CellularStatus_1_0: //= 4055 | 0x0
+		payloads = handleCellularStatus(buffer)
 	case sbfnr_BluetoothStatus_1: //= 4051
-	//case sbfid_BluetoothStatus_1_0: //= 4051 | 0x0
-	case sbfnr_DynDNSStatus_1: //= 4105
-	//case sbfid_DynDNSStatus_1_0: //= 4105 | 0x0
-	case sbfid_DynDNSStatus_1_1: //= 4105 | 0x2000
-	case sbfnr_BatteryStatus_1: //= 4083
-	//case sbfid_BatteryStatus_1_0: //= 4083 | 0x0
-	case sbfid_BatteryStatus_1_1: //= 4083 | 0x2000
-	case sbfid_BatteryStatus_1_2: //= 4083 | 0x4000
+		//case sbfid_BluetoothStatus_1_0: //= 4051 | 0x0
+		payloads = handleBluetoothStatus(buffer)
+	case sbfnr_DynDNSStatus_1, sbfid_DynDNSStatus_1_1: //= 4105, 4105 | 0x2000
+		//case sbfid_DynDNSStatus_1_0: //= 4105 | 0x0
+		payloads = handleDynDNSStatus(buffer)
+	case sbfnr_BatteryStatus_1, sbfid_BatteryStatus_1_1, sbfid_BatteryStatus_1_2: //= 4083, 4083 | 0x2000, 4083 | 0x4000
+		//case sbfid_BatteryStatus_1_0: //= 4083 | 0x0
+		payloads = handleBatteryStatus(buffer)
 	case sbfnr_PowerStatus_1: //= 4101
-	//case sbfid_PowerStatus_1_0: //= 4101 | 0x0
+		//case sbfid_PowerStatus_1_0: //= 4101 | 0x0
+		payloads = handlePowerStatus(buffer)
 	case sbfnr_QualityInd_1: //= 4082
-	//case sbfid_QualityInd_1_0: //= 4082 | 0x0
-	case sbfnr_DiskStatus_1: //= 4059
-	//case sbfid_DiskStatus_1_0: //= 4059 | 0x0
-	case sbfid_DiskStatus_1_1: //= 4059 | 0x2000
+		//case sbfid_QualityInd_1_0: //= 4082 | 0x0
+		payloads = handleQualityInd(buffer)
+	case sbfnr_DiskStatus_1, sbfid_DiskStatus_1_1: //= 4059, 4059 | 0x2000
+		//case sbfid_DiskStatus_1_0: //= 4059 | 0x0
+		payloads = handleDiskStatus(buffer)
 	case sbfnr_LogStatus_1: //= 4102
-	//case sbfid_LogStatus_1_0: //= 4102 | 0x0
+		//case sbfid_LogStatus_1_0: //= 4102 | 0x0
+		payloads = handleLogStatus(buffer)
 	case sbfnr_UHFStatus_1: //= 4085
 	//case sbfid_UHFStatus_1_0: //= 4085 | 0x0
 	case sbfnr_RFStatus_1: //= 4092
 		//case sbfid_RFStatus_1_0: //= 4092 | 0x0
-		handleRFStatus(buffer)
+		payloads = handleRFStatus(buffer)
 	case sbfnr_RIMSHealth_1: //= 4089
 	//case sbfid_RIMSHealth_1_0: //= 4089 | 0x0
 	case sbfnr_OSNMAStatus_1: //= 4231
@@ -760,28 +869,32 @@ func handleSbfBlock(buffer []byte) {
 	//case sbfid_CosmosStatus_1_0: //= 4243 | 0x0
 
 	/* Miscellaneous Blocks */
-	case sbfnr_ReceiverSetup_1: //= 5902
-	//case sbfid_ReceiverSetup_1_0: //= 5902 | 0x0
-	case sbfid_ReceiverSetup_1_1: //= 5902 | 0x2000
-	case sbfid_ReceiverSetup_1_2: //= 5902 | 0x4000
-	case sbfid_ReceiverSetup_1_3: //= 5902 | 0x6000
-	case sbfid_ReceiverSetup_1_4: //= 5902 | 0x8000
+	case sbfnr_ReceiverSetup_1, sbfid_ReceiverSetup_1_1, sbfid_ReceiverSetup_1_2, sbfid_ReceiverSetup_1_3, sbfid_ReceiverSetup_1_4: //= 5902, 5902 | 0x2000, 5902 | 0x4000, 5902 | 0x6000, 5902 | 0x8000
+		//case sbfid_ReceiverSetup_1_0: //= 5902 | 0x0
+		payloads = handleReceiverSetup(buffer)
 	case sbfnr_RxComponents_1: //= 4084
-	//case sbfid_RxComponents_1_0: //= 4084 | 0x0
+		//case sbfid_RxComponents_1_0: //= 4084 | 0x0
+		payloads = handleRxComponents(buffer)
 	case sbfnr_RxMessage_1: //= 4103
-	//case sbfid_RxMessage_1_0: //= 4103 | 0x0
+		//case sbfid_RxMessage_1_0: //= 4103 | 0x0
+		payloads = handleRxMessage(buffer)
 	case sbfnr_Commands_1: //= 4015
-	//case sbfid_Commands_1_0: //= 4015 | 0x0
+		//case sbfid_Commands_1_0: //= 4015 | 0x0
+		payloads = handleCommands(buffer)
 	case sbfnr_Comment_1: //= 5936
-	//case sbfid_Comment_1_0: //= 5936 | 0x0
+		//case sbfid_Comment_1_0: //= 5936 | 0x0
+		payloads = handleComment(buffer)
 	case sbfnr_BBSamples_1: //= 4040
 	//case sbfid_BBSamples_1_0: //= 4040 | 0x0
 	case sbfnr_ASCIIIn_1: //= 4075
-	//case sbfid_ASCIIIn_1_0: //= 4075 | 0x0
+		//case sbfid_ASCIIIn_1_0: //= 4075 | 0x0
+		payloads = handleASCIIIn(buffer)
 	case sbfnr_EncapsulatedOutput_1: //= 4097
-	//case sbfid_EncapsulatedOutput_1_0: //= 4097 | 0x0
+		//case sbfid_EncapsulatedOutput_1_0: //= 4097 | 0x0
+		payloads = handleEncapsulatedOutput(buffer)
 	case sbfnr_RawDataIn_1: //= 4236
-	//case sbfid_RawDataIn_1_0: //= 4236 | 0x0
+		//case sbfid_RawDataIn_1_0: //= 4236 | 0x0
+		payloads = handleRawDataIn(buffer)
 
 	/* TUR Specific Blocks */
 	case sbfnr_TURPVTSatCorrections_1: //= 4035
@@ -818,8 +931,5 @@ func handleSbfBlock(buffer []byte) {
 	default:
 		log.Printf("[ERROR] handleSbfBlock - Unsupported SBF block ID %d\n", sbfID)
 	}
-}
-
-func handleRFStatus(buffer []byte) {
-
+	return payloads
 }
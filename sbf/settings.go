@@ -0,0 +1,61 @@
+package sbf
+
+/**
+ * Settings controlling how decoded SBF data is published. The adapter fills
+ * them from its adapter_settings before any data is parsed.
+ */
+
+// Settings holds the decoder settings that can be set in adapter_settings
+type Settings struct {
+	ExtSensorDecimation        int                `json:"extSensorDecimation"`        // Publish every Nth ExtSensorMeas block
+	GeotagDirectory            string             `json:"geotagDirectory"`            // Directory of the event geotag files, empty to disable them
+	PPSOffsetLimit             float64            `json:"ppsOffsetLimit"`             // ns, alarm when the xPPS offset exceeds it, 0 to disable
+	MinTimeSyncLevel           string             `json:"minTimeSyncLevel"`           // none, coarse or fine, alarm below it, empty to disable
+	SubscriptionWarningDays    int                `json:"subscriptionWarningDays"`    // Alarm this many days before a correction subscription expires
+	JammingRaiseBlocks         int                `json:"jammingRaiseBlocks"`         // RFStatus blocks with interference before the jamming alarm is raised
+	JammingClearBlocks         int                `json:"jammingClearBlocks"`         // RFStatus blocks without interference before it is cleared
+	AGCDropLimit               float64            `json:"agcDropLimit"`               // dB, alarm when a frontend's AGC gain drops this far below its mean, 0 to disable
+	QualityThresholds          map[string]int     `json:"qualityThresholds"`          // Minimum 0-10 score of each QualityInd indicator, keyed by indicator name
+	DiskFullPercent            float64            `json:"diskFullPercent"`            // %, alarm when a disk is filled this far
+	LowBatteryPercent          float64            `json:"lowBatteryPercent"`          // %, alarm when a battery's charge is below it
+	ASCIIInPatterns            []ASCIIInPattern   `json:"asciiInPatterns"`            // Regular expressions parsing ASCIIIn strings, tried in order
+	CorrectionsInterval        float64            `json:"correctionsInterval"`        // s between RTCM3 MSM corrections, 0 to disable them
+	CorrectionsMSM             int                `json:"correctionsMSM"`             // 4 or 7
+	CorrectionsStationID       int                `json:"correctionsStationId"`       // RTCM3 reference station ID, 0-4095
+	CorrectionsStationInterval float64            `json:"correctionsStationInterval"` // s between the station position and descriptor messages
+	ReferencePosition          *ReferencePosition `json:"referencePosition"`          // Marker position of the base station
+	RinexDirectory             string             `json:"rinexDirectory"`             // Directory of the RINEX files, empty to disable them
+	RinexVersion               string             `json:"rinexVersion"`               // 3.05 or 4.00
+	RinexRotation              string             `json:"rinexRotation"`              // hourly or daily
+	NMEAOutput                 bool               `json:"nmeaOutput"`                 // Generate NMEA sentences from the PVT and attitude blocks
+	TrackRetention             float64            `json:"trackRetention"`             // s of track kept for track requests
+	TrackInterval              float64            `json:"trackInterval"`              // Minimum s between track points
+	TrackDirectory             string             `json:"trackDirectory"`             // Directory of the rolling track files, empty to disable them
+	TrackRotation              string             `json:"trackRotation"`              // hourly or daily
+	TrackFormats               []string           `json:"trackFormats"`               // Formats of the track files: geojson, gpx and/or kml
+	NTRIPCorrectionAgeLimit    float64            `json:"ntripCorrectionAgeLimit"`    // s, alarm when the mean correction age exceeds it, 0 to disable
+}
+
+var settings = Settings{
+	ExtSensorDecimation:        1,
+	SubscriptionWarningDays:    14,
+	JammingRaiseBlocks:         3,
+	JammingClearBlocks:         10,
+	QualityThresholds:          map[string]int{"overall": 5},
+	DiskFullPercent:            95,
+	LowBatteryPercent:          20,
+	CorrectionsMSM:             4,
+	CorrectionsStationInterval: 10,
+	RinexVersion:               "3.05",
+	RinexRotation:              "daily",
+	TrackRetention:             86400,
+	TrackInterval:              1,
+	TrackRotation:              "daily",
+	TrackFormats:               TrackFormats,
+}
+
+// Configure replaces the decoder settings
+func Configure(s Settings) {
+	settings = s
+	compileASCIIInPatterns()
+}
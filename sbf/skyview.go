@@ -0,0 +1,346 @@
+package sbf
+
+import (
+	"log"
+	"sort"
+	"strconv"
+)
+
+/**
+ * Sky view combining ChannelStatus, TrackingStatus and SatVisibility. The
+ * visibility blocks give the position in the sky of every satellite above the
+ * horizon, the channel blocks which of them are tracked on which signals and
+ * whether they are used in the PVT. Every block updates its part of the view
+ * and publishes the merged result.
+ */
+
+const skyViewTopic = "skyview"
+
+/* Offsets of the first sub-block */
+const trackingStatusSubBlockOffset = 16
+const channelStatusSubBlockOffset = 20
+const satVisibilitySubBlockOffset = 16
+
+// SkyView lists the visible and tracked satellites, with per constellation
+// counts
+type SkyView struct {
+	BlockTime
+	Satellites []SkySatellite             `json:"satellites"`
+	Counts     map[string]SatelliteCounts `json:"counts"`
+}
+
+// SkySatellite is one satellite of the sky plot
+type SkySatellite struct {
+	Satellite string           `json:"sat"` // RINEX name, e.g. G05
+	System    string           `json:"system"`
+	Azimuth   *float64         `json:"az,omitempty"` // °
+	Elevation *float64         `json:"el,omitempty"` // °
+	RiseSet   string           `json:"riseSet"`
+	Channel   *uint8           `json:"channel,omitempty"`
+	Antennas  []int            `json:"antennas,omitempty"` // int so JSON does not encode them as base64
+	Health    string           `json:"health"`
+	Signals   []SignalTracking `json:"signals,omitempty"`
+	Tracked   bool             `json:"tracked"`
+	Used      bool             `json:"used"` // At least one signal used in the PVT
+}
+
+// SignalTracking is the tracking and PVT usage state of one signal of a
+// satellite on one antenna
+type SignalTracking struct {
+	Signal   string `json:"signal"`
+	Antenna  uint8  `json:"antenna"`
+	Tracking string `json:"tracking"`
+	PVT      string `json:"pvt"`
+}
+
+// SatelliteCounts counts the satellites of one constellation
+type SatelliteCounts struct {
+	Visible int `json:"visible"`
+	Tracked int `json:"tracked"`
+	Used    int `json:"used"`
+}
+
+/* Position in the sky of a satellite, from SatVisibility */
+type satelliteVisibility struct {
+	azimuth   *float64
+	elevation *float64
+	riseSet   string
+}
+
+/* Channel state of a satellite, from ChannelStatus or TrackingStatus */
+type satelliteTracking struct {
+	satelliteVisibility
+	channel  uint8
+	antennas []int
+	health   string
+	signals  []SignalTracking
+	used     bool
+}
+
+/* Signal types indexing the 2 bit fields of ChannelStateInfo and HealthStatus */
+var channelStatusSignals = map[string][]string{
+	"GPS":     {"L1C/A", "L1P", "L2P", "L2C", "L5", "L1C"},
+	"GLONASS": {"L1C/A", "L1P", "L2P", "L2C/A", "L3"},
+	"Galileo": {"E1", "E6", "E5a", "E5b", "E5AltBOC"},
+	"SBAS":    {"L1", "L5"},
+	"BeiDou":  {"B1I", "B2I", "B3I", "B1C", "B2a", "B2b"},
+	"QZSS":    {"L1C/A", "L1C", "L2C", "L5", "L6", "L1S"},
+	"NavIC":   {"L5"},
+}
+
+var trackingStates = map[uint8]string{
+	0: "idle",
+	1: "search",
+	2: "sync",
+	3: "tracking",
+}
+
+var pvtUsageStates = map[uint8]string{
+	0: "notUsed",
+	1: "waitingForEphemeris",
+	2: "used",
+	3: "rejected",
+}
+
+var healthStates = map[uint8]string{
+	0: "unknown",
+	1: "healthy",
+	3: "unhealthy",
+}
+
+var riseSetStates = map[uint8]string{
+	0: "setting",
+	1: "rising",
+}
+
+func signalName(system string, index int) string {
+	if names, ok := channelStatusSignals[system]; ok && index < len(names) {
+		return names[index]
+	}
+	return "signal" + strconv.Itoa(index)
+}
+
+// combinedHealth summarizes the 2 bit health of every signal: unhealthy when
+// any signal is, healthy when at least one is and unknown otherwise
+func combinedHealth(healthStatus uint16) string {
+	health := "unknown"
+	for i := uint(0); i < 8; i++ {
+		switch healthStates[uint8(healthStatus>>(2*i))&0x03] {
+		case "unhealthy":
+			return "unhealthy"
+		case "healthy":
+			health = "healthy"
+		}
+	}
+	return health
+}
+
+func handleChannelStatus(buffer []byte) []interface{} {
+	block := ChannelStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleChannelStatus - Error decoding ChannelStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	tracking := map[uint8]satelliteTracking{}
+	offset := channelStatusSubBlockOffset
+	for i := 0; i < int(block.N); i++ {
+		sat := ChannelSatInfo_1_0_t{}
+		if !decodeSizedSubBlock(buffer, offset, int(block.SB1Size), &sat) {
+			log.Printf("[ERROR] handleChannelStatus - ChannelStatus block too short for %d satellites\n", block.N)
+			break
+		}
+		offset += int(block.SB1Size)
+
+		system, _ := satelliteSystem(sat.SVID)
+		satellite := satelliteTracking{
+			satelliteVisibility: satelliteVisibility{
+				azimuth:   optScaled(int64(sat.Az_RiseSet&0x01FF), 511, 1),
+				elevation: optScaled(int64(sat.Elev), -128, 1),
+				riseSet:   "unknown",
+			},
+			channel:  sat.Channel,
+			antennas: []int{},
+			health:   combinedHealth(sat.HealthStatus),
+			signals:  []SignalTracking{},
+		}
+		if name, ok := riseSetStates[uint8(sat.Az_RiseSet>>14)]; ok {
+			satellite.riseSet = name
+		}
+
+		for j := 0; j < int(sat.N2); j++ {
+			info := ChannelStateInfo_1_0_t{}
+			if !decodeSizedSubBlock(buffer, offset, int(block.SB2Size), &info) {
+				log.Printf("[ERROR] handleChannelStatus - ChannelStatus block too short for %d states of %s\n", sat.N2, satelliteName(sat.SVID))
+				break
+			}
+			offset += int(block.SB2Size)
+
+			satellite.antennas = append(satellite.antennas, int(info.Antenna))
+			for k := 0; k < 8; k++ {
+				trackingState := uint8(info.TrackingStatus>>(2*k)) & 0x03
+				if trackingState == 0 {
+					continue
+				}
+				pvtState := uint8(info.PVTStatus>>(2*k)) & 0x03
+				satellite.signals = append(satellite.signals, SignalTracking{
+					Signal:   signalName(system, k),
+					Antenna:  info.Antenna,
+					Tracking: trackingStates[trackingState],
+					PVT:      pvtUsageStates[pvtState],
+				})
+				if pvtState == 2 {
+					satellite.used = true
+				}
+			}
+		}
+		tracking[sat.SVID] = satellite
+	}
+	state.skyTracking = tracking
+
+	return []interface{}{Payload{Topic: skyViewTopic, Data: newSkyView(newBlockTime(block.TOW, block.WNc))}}
+}
+
+// handleTrackingStatus decodes the TrackingStatus block older receivers output
+// instead of ChannelStatus. It lacks the per signal states.
+func handleTrackingStatus(buffer []byte) []interface{} {
+	block := TrackingStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleTrackingStatus - Error decoding TrackingStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	tracking := map[uint8]satelliteTracking{}
+	for i := 0; i < int(block.N); i++ {
+		sb := TrackingStatusChannel_1_0_t{}
+		if !decodeSizedSubBlock(buffer, trackingStatusSubBlockOffset+i*int(block.SBSize), int(block.SBSize), &sb) {
+			log.Printf("[ERROR] handleTrackingStatus - TrackingStatus block too short for %d channels\n", block.N)
+			break
+		}
+		if sb.SVID == 0 {
+			continue
+		}
+		satellite := satelliteTracking{
+			satelliteVisibility: satelliteVisibility{
+				azimuth:   optScaled(int64(sb.Azimuth), -32768, 1),
+				elevation: optScaled(int64(sb.Elevation), -128, 1),
+				riseSet:   "unknown",
+			},
+			channel:  sb.RxChannel,
+			antennas: []int{},
+			health:   combinedHealth(uint16(sb.Health)),
+			signals:  []SignalTracking{},
+		}
+		if sb.ElevChange > 0 && sb.ElevChange != 127 {
+			satellite.riseSet = "rising"
+		} else if sb.ElevChange < 0 && sb.ElevChange != -128 {
+			satellite.riseSet = "setting"
+		}
+		tracking[sb.SVID] = satellite
+	}
+	state.skyTracking = tracking
+
+	return []interface{}{Payload{Topic: skyViewTopic, Data: newSkyView(newBlockTime(block.TOW, block.WNc))}}
+}
+
+func handleSatVisibility(buffer []byte) []interface{} {
+	block := SatVisibility_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleSatVisibility - Error decoding SatVisibility block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	visibility := map[uint8]satelliteVisibility{}
+	for i := 0; i < int(block.N); i++ {
+		sb := SatInfo_1_0_t{}
+		if !decodeSizedSubBlock(buffer, satVisibilitySubBlockOffset+i*int(block.SBLength), int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleSatVisibility - SatVisibility block too short for %d satellites\n", block.N)
+			break
+		}
+		satellite := satelliteVisibility{
+			azimuth:   optScaled(int64(sb.Azimuth), 65535, 0.01),
+			elevation: optScaled(int64(sb.Elevation), -32768, 0.01),
+			riseSet:   "unknown",
+		}
+		if name, ok := riseSetStates[sb.RiseSet]; ok {
+			satellite.riseSet = name
+		}
+		visibility[sb.SVID] = satellite
+	}
+	state.skyVisibility = visibility
+
+	return []interface{}{Payload{Topic: skyViewTopic, Data: newSkyView(newBlockTime(block.TOW, block.WNc))}}
+}
+
+// newSkyView merges the latest visibility and tracking states. SatVisibility
+// gives the more precise azimuth and elevation, the channel blocks fill in
+// those of tracked satellites it does not list.
+func newSkyView(blockTime BlockTime) SkyView {
+	svids := []int{}
+	for svid := range state.skyVisibility {
+		svids = append(svids, int(svid))
+	}
+	for svid := range state.skyTracking {
+		if _, ok := state.skyVisibility[svid]; !ok {
+			svids = append(svids, int(svid))
+		}
+	}
+	sort.Ints(svids)
+
+	view := SkyView{
+		BlockTime:  blockTime,
+		Satellites: []SkySatellite{},
+		Counts:     map[string]SatelliteCounts{},
+	}
+	for _, id := range svids {
+		svid := uint8(id)
+		system, _ := satelliteSystem(svid)
+		if system == "" {
+			system = "unknown"
+		}
+		satellite := SkySatellite{
+			Satellite: satelliteName(svid),
+			System:    system,
+			RiseSet:   "unknown",
+			Health:    "unknown",
+		}
+		counts := view.Counts[system]
+
+		visibility, visible := state.skyVisibility[svid]
+		tracking, tracked := state.skyTracking[svid]
+		if tracked {
+			channel := tracking.channel
+			satellite.Azimuth = tracking.azimuth
+			satellite.Elevation = tracking.elevation
+			satellite.RiseSet = tracking.riseSet
+			satellite.Channel = &channel
+			satellite.Antennas = tracking.antennas
+			satellite.Health = tracking.health
+			satellite.Signals = tracking.signals
+			satellite.Tracked = true
+			satellite.Used = tracking.used
+			counts.Tracked++
+			if tracking.used {
+				counts.Used++
+			}
+		}
+		if visible {
+			if visibility.azimuth != nil {
+				satellite.Azimuth = visibility.azimuth
+			}
+			if visibility.elevation != nil {
+				satellite.Elevation = visibility.elevation
+			}
+			if visibility.riseSet != "unknown" {
+				satellite.RiseSet = visibility.riseSet
+			}
+		}
+		if satellite.Elevation != nil && *satellite.Elevation >= 0 {
+			counts.Visible++
+		}
+
+		view.Counts[system] = counts
+		view.Satellites = append(view.Satellites, satellite)
+	}
+	return view
+}
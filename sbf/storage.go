@@ -0,0 +1,220 @@
+package sbf
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+)
+
+/**
+ * Decoding of DiskStatus and LogStatus, the state of the receiver's internal
+ * logging. An alarm is raised when a disk fills up past
+ * settings.DiskFullPercent, reports an error or when a log session fails to
+ * upload its files, so a remote unit does not silently stop logging.
+ */
+
+const storageDiskTopic = "storage/disk"
+const storageLogTopic = "storage/log"
+const storageAlarmTopic = "storage/alarm"
+
+/* Offsets of the first sub-block */
+const diskStatusSubBlockOffset = 20
+const logStatusSubBlockOffset = 20
+
+/* Number of bytes of the first LogSession fields, before its FileUploadStatus sub-blocks */
+const logSessionHeaderLength = 4
+
+// DiskStatus lists the receiver's disks
+type DiskStatus struct {
+	BlockTime
+	Disks []Disk `json:"disks"`
+}
+
+// Disk is the usage and state of one disk
+type Disk struct {
+	Disk              string   `json:"disk"`
+	Mounted           bool     `json:"mounted"`
+	Full              bool     `json:"full"`
+	Activity          bool     `json:"activity"`
+	Usage             uint64   `json:"usage"`                  // bytes
+	Size              *uint64  `json:"size,omitempty"`         // bytes
+	UsagePercent      *float64 `json:"usagePercent,omitempty"` // %
+	CreateDeleteCount uint8    `json:"createDeleteCount"`
+	Error             uint8    `json:"error"` // Error bits as defined by the SBF reference guide, 0 without errors
+}
+
+// LogStatus lists the receiver's log sessions
+type LogStatus struct {
+	BlockTime
+	Sessions []LogSession `json:"sessions"`
+}
+
+// LogSession is the state of one log session and of its file uploads
+type LogSession struct {
+	Session string       `json:"session"`
+	Active  bool         `json:"active"`
+	Status  uint8        `json:"status"`
+	Uploads []FileUpload `json:"uploads"`
+}
+
+// FileUpload is the state of the file upload of a log session
+type FileUpload struct {
+	Type            uint8 `json:"type"`
+	ErrorCode       uint8 `json:"errorCode"` // 0 without errors
+	RetryQueueSize  uint8 `json:"retryQueueSize"`
+	FailedTransfers uint8 `json:"failedTransfers"`
+}
+
+var disks = map[uint8]string{
+	1: "internal",
+	2: "external",
+}
+
+/* DiskData Status bits */
+const diskStatusMounted = 0x01
+const diskStatusFull = 0x02
+const diskStatusActivity = 0x04
+
+/* LogSession SessionStatus bits */
+const logSessionActive = 0x01
+
+func handleDiskStatus(buffer []byte) []interface{} {
+	block := DiskStatus_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleDiskStatus - Error decoding DiskStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := DiskStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Disks:     []Disk{},
+	}
+	payloads := []interface{}{}
+	for i := 0; i < int(block.N); i++ {
+		// Revision 0 sub-blocks lack the Error field, which is then left 0
+		sb := DiskData_1_1_t{}
+		if !decodeSizedSubBlock(buffer, diskStatusSubBlockOffset+i*int(block.SBLength), int(block.SBLength), &sb) {
+			log.Printf("[ERROR] handleDiskStatus - DiskStatus block too short for %d disks\n", block.N)
+			break
+		}
+		disk := Disk{
+			Disk:              mapString(disks, sb.DiskID),
+			Mounted:           sb.Status&diskStatusMounted != 0,
+			Full:              sb.Status&diskStatusFull != 0,
+			Activity:          sb.Status&diskStatusActivity != 0,
+			Usage:             uint64(sb.DiskUsageMSB)<<32 | uint64(sb.DiskUsageLSB),
+			CreateDeleteCount: sb.CreateDeleteCount,
+			Error:             sb.Error,
+		}
+		if sb.DiskSize != 0 {
+			size := uint64(sb.DiskSize) * 1024 * 1024
+			usagePercent := float64(disk.Usage) / float64(size) * 100
+			disk.Size = &size
+			disk.UsagePercent = &usagePercent
+		}
+		status.Disks = append(status.Disks, disk)
+		payloads = append(payloads, diskAlarms(disk, status.BlockTime)...)
+	}
+
+	return append([]interface{}{Payload{Topic: storageDiskTopic, Data: status}}, payloads...)
+}
+
+// diskAlarms checks a disk for the diskFull and diskError alarms
+func diskAlarms(disk Disk, blockTime BlockTime) []interface{} {
+	full := disk.Full
+	message := fmt.Sprintf("%s disk full", disk.Disk)
+	if disk.UsagePercent != nil {
+		full = full || (settings.DiskFullPercent > 0 && *disk.UsagePercent >= settings.DiskFullPercent)
+		message = fmt.Sprintf("%s disk %.1f%% used", disk.Disk, *disk.UsagePercent)
+	}
+	alarm := Alarm{
+		BlockTime: blockTime,
+		Alarm:     "diskFull/" + disk.Disk,
+		Active:    full,
+		Message:   message,
+		Value:     disk.UsagePercent,
+	}
+	if settings.DiskFullPercent > 0 {
+		alarm.Limit = floatPtr(settings.DiskFullPercent)
+	}
+	payloads := updateAlarm(storageAlarmTopic, alarm)
+
+	return append(payloads, updateAlarm(storageAlarmTopic, Alarm{
+		BlockTime: blockTime,
+		Alarm:     "diskError/" + disk.Disk,
+		Active:    disk.Error != 0,
+		Message:   fmt.Sprintf("%s disk error bits 0x%02x", disk.Disk, disk.Error),
+		Value:     floatPtr(float64(disk.Error)),
+	})...)
+}
+
+func handleLogStatus(buffer []byte) []interface{} {
+	block := LogStatus_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleLogStatus - Error decoding LogStatus block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	status := LogStatus{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Sessions:  []LogSession{},
+	}
+	payloads := []interface{}{}
+	offset := logStatusSubBlockOffset
+	for i := 0; i < int(block.N1); i++ {
+		sb := LogSession_1_0_t{}
+		if !decodeSizedSubBlock(buffer, offset, logSessionHeaderLength, &sb) {
+			log.Printf("[ERROR] handleLogStatus - LogStatus block too short for %d log sessions\n", block.N1)
+			break
+		}
+		session := LogSession{
+			Session: "LOG" + strconv.Itoa(int(sb.SessionID)),
+			Active:  sb.SessionStatus&logSessionActive != 0,
+			Status:  sb.SessionStatus,
+			Uploads: []FileUpload{},
+		}
+		offset += int(block.SB1Length)
+
+		failures := []string{}
+		for j := 0; j < int(sb.N2); j++ {
+			upload := FileUploadStatus_1_0_t{}
+			if !decodeSizedSubBlock(buffer, offset, int(block.SB2Length), &upload) {
+				log.Printf("[ERROR] handleLogStatus - LogStatus block too short for %d uploads of %s\n", sb.N2, session.Session)
+				break
+			}
+			offset += int(block.SB2Length)
+
+			session.Uploads = append(session.Uploads, FileUpload{
+				Type:            upload.Type,
+				ErrorCode:       upload.ErrorCode,
+				RetryQueueSize:  upload.RetryQueueSize,
+				FailedTransfers: upload.NrFailedTransfers,
+			})
+
+			// An upload fails when it reports an error or its failed transfer count went up
+			key := fmt.Sprintf("%s/%d", session.Session, j)
+			previous, seen := state.previousFailedTransfers[key]
+			state.previousFailedTransfers[key] = upload.NrFailedTransfers
+			if upload.ErrorCode != 0 {
+				failures = append(failures, fmt.Sprintf("upload %d error %d", j, upload.ErrorCode))
+			} else if seen && upload.NrFailedTransfers > previous {
+				failures = append(failures, fmt.Sprintf("upload %d %d failed transfers", j, upload.NrFailedTransfers-previous))
+			}
+		}
+		status.Sessions = append(status.Sessions, session)
+
+		message := fmt.Sprintf("%s uploading", session.Session)
+		if len(failures) > 0 {
+			message = fmt.Sprintf("%s %s", session.Session, failures[0])
+		}
+		payloads = append(payloads, updateAlarm(storageAlarmTopic, Alarm{
+			BlockTime: status.BlockTime,
+			Alarm:     "logUpload/" + session.Session,
+			Active:    len(failures) > 0,
+			Message:   message,
+			Value:     floatPtr(float64(len(failures))),
+		})...)
+	}
+
+	return append([]interface{}{Payload{Topic: storageLogTopic, Data: status}}, payloads...)
+}
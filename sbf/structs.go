@@ -287,7 +287,7 @@ type MeasExtraChannelSub_1_1_t struct {
 	LockTime      uint16
 	CumLossCont   uint8
 	CarMPCorr     int8
-	_padding      [SBF_MEASEXTRACHANNELSUB_1_1__PADDING_LENGTH]uint8
+	_             [SBF_MEASEXTRACHANNELSUB_1_1__PADDING_LENGTH]uint8
 }
 
 /** MeasExtra_1_1_t */
@@ -319,7 +319,7 @@ type MeasExtraChannelSub_1_2_t struct {
 	CumLossCont   uint8
 	CarMPCorr     int8
 	Info          uint8
-	_padding      [SBF_MEASEXTRACHANNELSUB_1_2__PADDING_LENGTH]uint8
+	_             [SBF_MEASEXTRACHANNELSUB_1_2__PADDING_LENGTH]uint8
 }
 
 /** MeasExtra_1_2_t */
@@ -407,7 +407,7 @@ type MeasFullRangeSub_1_1_t struct {
 	CarrierMinCode float32
 	CN0            int16
 	ObsInfo        uint8
-	_padding       [SBF_MEASFULLRANGESUB_1_1__PADDING_LENGTH]uint8
+	_              [SBF_MEASFULLRANGESUB_1_1__PADDING_LENGTH]uint8
 }
 
 /** MeasFullRange_1_1_t */
@@ -1341,32 +1341,32 @@ type gpEph_1_0_t struct {
 	WN         uint16    /* Week number (modulo 1024). [061-070:1] */
 	CAorPonL2  uint8     /* C/A code or P code on L2. [071-072:1] */
 	URA        uint8     /* User range accuracy index. [073-076:1] */
-	health     uint8     /* SV health. [077-082:1] */
+	Health     uint8     /* SV health. [077-082:1] */
 	L2DataFlag uint8     /* L2 P data flag. [091-091:1] */
 	IODC       uint16    /* Issue of data clock. [083-084:1] [211-218:1] */
 	IODE2      uint8     /* Issue of data eph. frame 2. [061-068:2] */
 	IODE3      uint8     /* Issue of data eph. frame 3. [271-278:3] */
 	FitIntFlg  uint8     /* fit interval flag [287-287:2] */
-	dummy      uint8     /* introduced w.r.t. 32-bits memory alignment */
+	_          uint8     /* introduced w.r.t. 32-bits memory alignment */
 	T_gd       float32   /* Correction term T_gd (s). [197-204:1] */
-	t_oc       uint32    /* Clock correction t_oc (s). [219-234:1] */
-	a_f2       float32   /* Clock correction a_f2 (s/s^2). [241-248:1] */
-	a_f1       float32   /* Clock correction a_f1 (s/s). [249-264:1] */
-	a_f0       float32   /* Clock correction a_f0 (s). [271-292:1] */
+	T_oc       uint32    /* Clock correction t_oc (s). [219-234:1] */
+	A_f2       float32   /* Clock correction a_f2 (s/s^2). [241-248:1] */
+	A_f1       float32   /* Clock correction a_f1 (s/s). [249-264:1] */
+	A_f0       float32   /* Clock correction a_f0 (s). [271-292:1] */
 	C_rs       float32   /* radius sin ampl (m) [069-084:2] */
 	DEL_N      float32   /* mean motion diff (semi-circles/s) [091-106:2] */
 	M_0        SBFDOUBLE /* Mean Anom (semi-circles) [107-114:2] [121-144:2] */
 	C_uc       float32   /* lat cosine ampl (r) [151-166:2] */
-	e          SBFDOUBLE /* Eccentricity [167-174:2] [181-204:2] */
+	E          SBFDOUBLE /* Eccentricity [167-174:2] [181-204:2] */
 	C_us       float32   /* Lat sine ampl   (r) [211-226:2] */
 	SQRT_A     SBFDOUBLE /* SQRT(A) (m^1/2) [227-234:2 241-264:2] */
-	t_oe       uint32    /* Reference time of ephemeris (s) [271-286:2] */
+	T_oe       uint32    /* Reference time of ephemeris (s) [271-286:2] */
 	C_ic       float32   /* inclin cos ampl (r) [061-076:3] */
 	OMEGA_0    SBFDOUBLE /* Right Ascen at TOA (semi-circles) [077-084:3] [091-114:3] */
 	C_is       float32   /* inclin sin ampl (r) [121-136:3] */
-	i_0        SBFDOUBLE /* Orbital Inclination (semi-circles) [137-144:3] [151-174:3] */
+	I_0        SBFDOUBLE /* Orbital Inclination (semi-circles) [137-144:3] [151-174:3] */
 	C_rc       float32   /* radius cos ampl (m) [181-196:3] */
-	omega      SBFDOUBLE /* Argument of Perigee(semi-circle) [197-204:3] [211-234:3] */
+	Omega      SBFDOUBLE /* Argument of Perigee(semi-circle) [197-204:3] [211-234:3] */
 	OMEGADOT   float32   /* Rate of Right Ascen(semi-circles/s) [241-264:3] */
 	IDOT       float32   /* Rate of inclin (semi-circles/s) [279-292:3] */
 	WNt_oc     uint16    /* modified WN to go with t_oc (still modulo 1024) */
@@ -1430,14 +1430,14 @@ type gpIon_1_0_t struct {
 
 	PRN      uint8
 	Reserved uint8
-	alpha_0  float32 /* (sec) [069-076:4p18] */
-	alpha_1  float32 /* (sec/semicircle) [077-084:4p18] */
-	alpha_2  float32 /* (sec/semicircle^2) [091-098:4p18] */
-	alpha_3  float32 /* (sec/semicircle^3) [099-106:4p18] */
-	beta_0   float32 /* (sec) [107-114:4p18] */
-	beta_1   float32 /* (sec/semicircle) [121-128:4p18] */
-	beta_2   float32 /* (sec/semicircle^2) [129-136:4p18] */
-	beta_3   float32 /* (sec/semicircle^3) [137-144:4p18] */
+	Alpha_0  float32 /* (sec) [069-076:4p18] */
+	Alpha_1  float32 /* (sec/semicircle) [077-084:4p18] */
+	Alpha_2  float32 /* (sec/semicircle^2) [091-098:4p18] */
+	Alpha_3  float32 /* (sec/semicircle^3) [099-106:4p18] */
+	Beta_0   float32 /* (sec) [107-114:4p18] */
+	Beta_1   float32 /* (sec/semicircle) [121-128:4p18] */
+	Beta_2   float32 /* (sec/semicircle^2) [129-136:4p18] */
+	Beta_3   float32 /* (sec/semicircle^3) [137-144:4p18] */
 }
 
 /** GPSIon_1_0_t */
@@ -1462,7 +1462,7 @@ type gpUtc_1_0_t struct {
 	Reserved  uint8
 	A_1       float32   /* (sec/sec) [151-176:4p18] */
 	A_0       SBFDOUBLE /* (sec) [181-194:4] [211-218:4] */
-	t_ot      uint32    /* (sec) [219-226:4p18] */
+	T_ot      uint32    /* (sec) [219-226:4p18] */
 	WN_t      uint8     /* (wk) [227-234:4p18] */
 	DEL_t_LS  int8      /* (sec) [241-218:4p18] */
 	WN_LSF    uint8     /* (wk) [219-226:4p18] */
@@ -1548,28 +1548,28 @@ type glEph_1_0_t struct {
 
 	SVID   uint8     /* Slot number + 37 */
 	FreqNr uint8     /* Frequency number + 8 */
-	x      SBFDOUBLE /* X component of satellite position in PZ-90 (km) */
-	y      SBFDOUBLE /* Y component of satellite position in PZ-90 (km) */
-	z      SBFDOUBLE /* Z component of satellite position in PZ-90 (km) */
-	dx     float32   /* X component of satellite velocity in PZ-90 (km/s) */
-	dy     float32   /* Y component of satellite velocity in PZ-90 (km/s) */
-	dz     float32   /* Z component of satellite velocity in PZ-90 (km/s) */
-	ddx    float32   /* X component of satellite acceleration in PZ-90 (km/s2) */
-	ddy    float32   /* Y component of satellite acceleration in PZ-90 (km/s2) */
-	ddz    float32   /* Z component of satellite acceleration in PZ-90 (km/s2) */
-	gamma  float32   /* relative deviation of predicted carrier frequency */
-	tau    float32   /* corr to nth satellite time rel to GLONASS time t_c */
-	dtau   float32   /* time difference between L2 and L1 sub-band (s) */
-	t_oe   uint32    /* reference time of GLONASS ephemeris in GPS time frame */
+	X      SBFDOUBLE /* X component of satellite position in PZ-90 (km) */
+	Y      SBFDOUBLE /* Y component of satellite position in PZ-90 (km) */
+	Z      SBFDOUBLE /* Z component of satellite position in PZ-90 (km) */
+	Dx     float32   /* X component of satellite velocity in PZ-90 (km/s) */
+	Dy     float32   /* Y component of satellite velocity in PZ-90 (km/s) */
+	Dz     float32   /* Z component of satellite velocity in PZ-90 (km/s) */
+	Ddx    float32   /* X component of satellite acceleration in PZ-90 (km/s2) */
+	Ddy    float32   /* Y component of satellite acceleration in PZ-90 (km/s2) */
+	Ddz    float32   /* Z component of satellite acceleration in PZ-90 (km/s2) */
+	Gamma  float32   /* relative deviation of predicted carrier frequency */
+	Tau    float32   /* corr to nth satellite time rel to GLONASS time t_c */
+	Dtau   float32   /* time difference between L2 and L1 sub-band (s) */
+	T_oe   uint32    /* reference time of GLONASS ephemeris in GPS time frame */
 	WNt_oe uint16    /* reference WN of GLONASS ephemeris in GPS time frame */
 	P1     uint8     /* length of applicability interval (min) */
 	P2     uint8     /* odd/even flag of t_b */
 	E      uint8     /* age of immediate data (days) */
 	B      uint8     /* health flag, unhealthy if MSB is set */
-	tb     uint16    /* time of day [min] defining middle of validity interval */
+	Tb     uint16    /* time of day [min] defining middle of validity interval */
 	M      uint8     /* GLONASS-M only: GLONASS-M satellite identifier (01, otherwise 00) */
 	P      uint8     /* GLONASS-M only: mode of computation of freq/time corr data */
-	l      uint8     /* GLONASS-M only: health flag, 0=healthy, 1=unhealthy */
+	L      uint8     /* GLONASS-M only: health flag, 0=healthy, 1=unhealthy */
 	P4     uint8     /* GLONASS-M only: 'updated' flag of ephemeris data */
 	N_T    uint16    /* GLONASS-M only: current day within 4-year interval */
 	F_T    uint16    /* GLONASS-M only: predicted user range accuracy at time t_b */
@@ -1608,7 +1608,7 @@ type glAlm_1_0_t struct {
 	N        uint16  /* calender day number within 4 year period */
 	M        uint8   /* GLONASS-M only: GLONASS-M satellite identifier (01, otherwise 00) */
 	N_4      uint8   /* GLONASS-M only: 4 year interval number, starting from 1996 */
-	_padding [SBF_GLALM_1_0__PADDING_LENGTH]uint8
+	_        [SBF_GLALM_1_0__PADDING_LENGTH]uint8
 }
 
 /** GLOAlm_1_0_t */
@@ -1664,9 +1664,9 @@ type gaEph_1_0_t struct {
 	Source       uint8     /* obtained from INAV (2) or FNAV (16) */
 	SQRT_A       SBFDOUBLE /* SQRT(A) (m^1/2) [F2/I1/G1] */
 	M_0          SBFDOUBLE /* Mean Anom (semi-circles) [F2/I1/G1] */
-	e            SBFDOUBLE /* Eccentricity [F2/I1/G1] */
-	i_0          SBFDOUBLE /* Orbital Inclination (semi-circles) [F3/I2/G2] */
-	omega        SBFDOUBLE /* Argument of Perigee (semi-circles) [F3/I2/G2] */
+	E            SBFDOUBLE /* Eccentricity [F2/I1/G1] */
+	I_0          SBFDOUBLE /* Orbital Inclination (semi-circles) [F3/I2/G2] */
+	Omega        SBFDOUBLE /* Argument of Perigee (semi-circles) [F3/I2/G2] */
 	OMEGA_0      SBFDOUBLE /* Right Ascen at TOA (semi-circles) [F2/I2/G2] */
 	OMEGADOT     float32   /* Rate of Right Ascen(semi-circles/s) [F2/I3/G1] */
 	IDOT         float32   /* Rate of inclin (semi-circles/s) [F2/I2/G1] */
@@ -1677,11 +1677,11 @@ type gaEph_1_0_t struct {
 	C_rs         float32   /* radius sin ampl (m) [F3/I3/G3] */
 	C_ic         float32   /* inclin cos ampl (r) [F4/I4/G3] */
 	C_is         float32   /* inclin sin ampl (r) [F4/I4/G3] */
-	t_oe         uint32    /* Reference time of ephemeris (s) [F3/I1/G2] */
-	t_oc         uint32    /* Clock correction t_oc (s) [F1/I4/G1] */
-	a_f2         float32   /* Clock correction a_f2 (s/s^2) [F1/I4/G1] */
-	a_f1         float32   /* Clock correction a_f1 (s/s) [F1/I4/G1] */
-	a_f0         SBFDOUBLE /* Clock correction a_f0 (s) [F1/I4/G1] */
+	T_oe         uint32    /* Reference time of ephemeris (s) [F3/I1/G2] */
+	T_oc         uint32    /* Clock correction t_oc (s) [F1/I4/G1] */
+	A_f2         float32   /* Clock correction a_f2 (s/s^2) [F1/I4/G1] */
+	A_f1         float32   /* Clock correction a_f1 (s/s) [F1/I4/G1] */
+	A_f0         SBFDOUBLE /* Clock correction a_f0 (s) [F1/I4/G1] */
 	WNt_oe       uint16    /* modified WN to go with t_oe */
 	WNt_oc       uint16    /* modified WN to go with t_oc */
 	IODnav       uint16    /* 0 - 1023 [Fx/Ix/Gx] */
@@ -1752,9 +1752,9 @@ type gaIon_1_0_t struct {
 
 	SVID       uint8   /* SBF range 71-102 SIS range 1-64 F1/I4/G1 */
 	Source     uint8   /* bitfield according to sigType */
-	a_i0       float32 /* (sec) [F1/I5/C4] */
-	a_i1       float32 /* (sec/semicircle) [F1/I5/C4] */
-	a_i2       float32 /* (sec/semicircle^2) [F1/I5/C4] */
+	A_i0       float32 /* (sec) [F1/I5/C4] */
+	A_i1       float32 /* (sec/semicircle) [F1/I5/C4] */
+	A_i2       float32 /* (sec/semicircle^2) [F1/I5/C4] */
 	StormFlags uint8   /* 5 bits: 1 for each region [F1/I5/C4] */
 }
 
@@ -1780,7 +1780,7 @@ type gaUtc_1_0_t struct {
 	Source    uint8     /* bitfield according to sigType */
 	A_1       float32   /* (sec/sec) [F4/I6/C5] */
 	A_0       SBFDOUBLE /* (sec) [F4/I6/C5] */
-	t_ot      uint32    /* (sec) [F4/I6/C5] */
+	T_ot      uint32    /* (sec) [F4/I6/C5] */
 	WN_ot     uint8     /* (wk) [F4/I6/C5] */
 	DEL_t_LS  int8      /* (sec) [F4/I6/C5] */
 	WN_LSF    uint8     /* (wk) [F4/I6/C5] */
@@ -1869,24 +1869,24 @@ type cmpEph_1_0_t struct {
 	Reserved2 uint16
 	T_GD1     float32
 	T_GD2     float32
-	t_oc      uint32
-	a_f2      float32
-	a_f1      float32
-	a_f0      float32
+	T_oc      uint32
+	A_f2      float32
+	A_f1      float32
+	A_f0      float32
 	C_rs      float32
 	DEL_N     float32
 	M_0       SBFDOUBLE
 	C_uc      float32
-	e         SBFDOUBLE
+	E         SBFDOUBLE
 	C_us      float32
 	SQRT_A    SBFDOUBLE
-	t_oe      uint32
+	T_oe      uint32
 	C_ic      float32
 	OMEGA_0   SBFDOUBLE
 	C_is      float32
-	i_0       SBFDOUBLE
+	I_0       SBFDOUBLE
 	C_rc      float32
-	omega     SBFDOUBLE
+	Omega     SBFDOUBLE
 	OMEGADOT  float32
 	IDOT      float32
 	WNt_oc    uint16
@@ -2010,7 +2010,7 @@ type qzEph_1_0_t struct {
 	WN         uint16    /* Week number (modulo 1024). [061-070:1] */
 	CAorPonL2  uint8     /* C/A code or P code on L2. [071-072:1] */
 	URA        uint8     /* User range accuracy index. [073-076:1] */
-	health     uint8     /* SV health. [077-082:1] */
+	Health     uint8     /* SV health. [077-082:1] */
 	L2DataFlag uint8     /* L2 P data flag. [091-091:1] */
 	IODC       uint16    /* Issue of data clock. [083-084:1] [211-218:1] */
 	IODE2      uint8     /* Issue of data eph. frame 2. [061-068:2] */
@@ -2018,24 +2018,24 @@ type qzEph_1_0_t struct {
 	FitIntFlg  uint8     /* fit interval flag [287-287:2] */
 	Reserved2  uint8     /* introduced w.r.t. 32-bits memory alignment */
 	T_gd       float32   /* Correction term T_gd (s). [197-204:1] */
-	t_oc       uint32    /* Clock correction t_oc (s). [219-234:1] */
-	a_f2       float32   /* Clock correction a_f2 (s/s^2). [241-248:1] */
-	a_f1       float32   /* Clock correction a_f1 (s/s). [249-264:1] */
-	a_f0       float32   /* Clock correction a_f0 (s). [271-292:1] */
+	T_oc       uint32    /* Clock correction t_oc (s). [219-234:1] */
+	A_f2       float32   /* Clock correction a_f2 (s/s^2). [241-248:1] */
+	A_f1       float32   /* Clock correction a_f1 (s/s). [249-264:1] */
+	A_f0       float32   /* Clock correction a_f0 (s). [271-292:1] */
 	C_rs       float32   /* radius sin ampl (m) [069-084:2] */
 	DEL_N      float32   /* mean motion diff (semi-circles/s) [091-106:2] */
 	M_0        SBFDOUBLE /* Mean Anom (semi-circles) [107-114:2] [121-144:2] */
 	C_uc       float32   /* lat cosine ampl (r) [151-166:2] */
-	e          SBFDOUBLE /* Eccentricity [167-174:2] [181-204:2] */
+	E          SBFDOUBLE /* Eccentricity [167-174:2] [181-204:2] */
 	C_us       float32   /* Lat sine ampl   (r) [211-226:2] */
 	SQRT_A     SBFDOUBLE /* SQRT(A) (m^1/2) [227-234:2 241-264:2] */
-	t_oe       uint32    /* Reference time of ephemeris (s) [271-286:2] */
+	T_oe       uint32    /* Reference time of ephemeris (s) [271-286:2] */
 	C_ic       float32   /* inclin cos ampl (r) [061-076:3] */
 	OMEGA_0    SBFDOUBLE /* Right Ascen at TOA (semi-circles) [077-084:3] [091-114:3] */
 	C_is       float32   /* inclin sin ampl (r) [121-136:3] */
-	i_0        SBFDOUBLE /* Orbital Inclination (semi-circles) [137-144:3] [151-174:3] */
+	I_0        SBFDOUBLE /* Orbital Inclination (semi-circles) [137-144:3] [151-174:3] */
 	C_rc       float32   /* radius cos ampl (m) [181-196:3] */
-	omega      SBFDOUBLE /* Argument of Perigee(semi-circle) [197-204:3] [211-234:3] */
+	Omega      SBFDOUBLE /* Argument of Perigee(semi-circle) [197-204:3] [211-234:3] */
 	OMEGADOT   float32   /* Rate of Right Ascen(semi-circles/s) [241-264:3] */
 	IDOT       float32   /* Rate of inclin (semi-circles/s) [279-292:3] */
 	WNt_oc     uint16    /* modified WN to go with t_oc (still modulo 1024) */
@@ -2231,7 +2231,7 @@ type raEph_1_0_t struct {
 	Reserved uint8
 	IODN     uint16 /* Issue of data, 8-bit, cycles from 0 to 255 */
 	URA      uint16 /* user range accuracy [0,15] */
-	t0       uint32 /* time of day of ephemeris */
+	T0       uint32 /* time of day of ephemeris */
 	Xg       SBFDOUBLE
 	Yg       SBFDOUBLE
 	Zg       SBFDOUBLE
@@ -2241,8 +2241,8 @@ type raEph_1_0_t struct {
 	Xgdd     SBFDOUBLE
 	Ygdd     SBFDOUBLE
 	Zgdd     SBFDOUBLE
-	aGf0     float32 /* Clock bias  [s] */
-	aGf1     float32 /* Clock drift [s/s] */
+	AGf0     float32 /* Clock bias  [s] */
+	AGf1     float32 /* Clock drift [s/s] */
 }
 
 /** GEONav_1_0_t */
@@ -2486,7 +2486,7 @@ type ServiceRegion_1_0_t struct {
 	Longitude1  int16 /* [-180,180] */
 	Longitude2  int16
 	RegionShape uint8
-	_padding    [SBF_SERVICEREGION_1_0__PADDING_LENGTH]uint8
+	_           [SBF_SERVICEREGION_1_0__PADDING_LENGTH]uint8
 }
 
 /** raServiceMsg_1_0_t */
@@ -2629,7 +2629,7 @@ type sbsAlm_1_0_t struct {
 	A                  float32
 	t_oa               uint32
 	PRN_A              uint8
-	_padding           [SBF_SBSALM_1_0__PADDING_LENGTH]uint8
+	_                  [SBF_SBSALM_1_0__PADDING_LENGTH]uint8
 }
 
 /** SBASL5Alm_1_0_t */
@@ -2742,7 +2742,7 @@ type PVTResidual_1_0_t struct {
 	DopplerL1Res int16
 	DopplerL2Res int16
 	PRN          uint8
-	_padding     [SBF_PVTRESIDUAL_1_0__PADDING_LENGTH]uint8
+	_            [SBF_PVTRESIDUAL_1_0__PADDING_LENGTH]uint8
 }
 
 /** PVTResiduals_1_0_t */
@@ -2832,7 +2832,7 @@ type PVTCartesian_2_0_t struct {
 	MeanCorrAge uint16
 	SignalInfo  uint32
 	AlertFlag   uint8
-	_padding    [SBF_PVTCARTESIAN_2_0__PADDING_LENGTH]uint8
+	_           [SBF_PVTCARTESIAN_2_0__PADDING_LENGTH]uint8
 }
 
 /*--PVTCartesian_2_1_t : ----------------------------------------------------*/
@@ -2907,7 +2907,7 @@ type PVTCartesian_2_2_t struct {
 	HAccuracy   uint16
 	VAccuracy   uint16
 	Misc        uint8
-	_padding    [SBF_PVTCARTESIAN_2_2__PADDING_LENGTH]uint8
+	_           [SBF_PVTCARTESIAN_2_2__PADDING_LENGTH]uint8
 }
 
 type PVTCartesian_2_t PVTCartesian_2_2_t
@@ -2943,7 +2943,7 @@ type PVTGeodetic_2_0_t struct {
 	MeanCorrAge uint16
 	SignalInfo  uint32
 	AlertFlag   uint8
-	_padding    [SBF_PVTGEODETIC_2_0__PADDING_LENGTH]uint8
+	_           [SBF_PVTGEODETIC_2_0__PADDING_LENGTH]uint8
 }
 
 /*--PVTGeodetic_2_1_t : -----------------------------------------------------*/
@@ -3018,7 +3018,7 @@ type PVTGeodetic_2_2_t struct {
 	HAccuracy   uint16
 	VAccuracy   uint16
 	Misc        uint8
-	_padding    [SBF_PVTGEODETIC_2_2__PADDING_LENGTH]uint8
+	_           [SBF_PVTGEODETIC_2_2__PADDING_LENGTH]uint8
 }
 
 type PVTGeodetic_2_t PVTGeodetic_2_2_t
@@ -3054,7 +3054,7 @@ type PVTGeodeticAuth_1_0_t struct {
 	MeanCorrAge uint16
 	SignalInfo  uint32
 	AlertFlag   uint8
-	_padding    [SBF_PVTGEODETICAUTH_1_0__PADDING_LENGTH]uint8
+	_           [SBF_PVTGEODETICAUTH_1_0__PADDING_LENGTH]uint8
 }
 
 /*--PVTGeodeticAuth_1_1_t : -------------------------------------------------*/
@@ -3129,7 +3129,7 @@ type PVTGeodeticAuth_1_2_t struct {
 	HAccuracy   uint16
 	VAccuracy   uint16
 	Misc        uint8
-	_padding    [SBF_PVTGEODETICAUTH_1_2__PADDING_LENGTH]uint8
+	_           [SBF_PVTGEODETICAUTH_1_2__PADDING_LENGTH]uint8
 }
 
 type PVTGeodeticAuth_1_t PVTGeodeticAuth_1_2_t
@@ -3317,13 +3317,13 @@ type PosLocal_1_0_t struct {
 	TOW uint32
 	WNc uint16
 
-	Mode     uint8
-	Error    uint8
-	Lat      SBFDOUBLE
-	Lon      SBFDOUBLE
-	Alt      SBFDOUBLE
-	Datum    uint8
-	_padding [SBF_POSLOCAL_1_0__PADDING_LENGTH]uint8
+	Mode  uint8
+	Error uint8
+	Lat   SBFDOUBLE
+	Lon   SBFDOUBLE
+	Alt   SBFDOUBLE
+	Datum uint8
+	_     [SBF_POSLOCAL_1_0__PADDING_LENGTH]uint8
 }
 
 type PosLocal_1_t PosLocal_1_0_t
@@ -3345,7 +3345,7 @@ type PosProjected_1_0_t struct {
 	Easting  SBFDOUBLE
 	Alt      SBFDOUBLE
 	Datum    uint8
-	_padding [SBF_POSPROJECTED_1_0__PADDING_LENGTH]uint8
+	_        [SBF_POSPROJECTED_1_0__PADDING_LENGTH]uint8
 }
 
 type PosProjected_1_t PosProjected_1_0_t
@@ -3397,7 +3397,7 @@ type SatPos_1_1_t struct {
 	IonoLSB   uint8
 	TropoLSB  uint8
 	IonoModel uint8
-	_padding  [SBF_SATPOS_1_1__PADDING_LENGTH]uint8
+	_         [SBF_SATPOS_1_1__PADDING_LENGTH]uint8
 }
 
 /** PVTSatCartesian_1_1_t */
@@ -3544,7 +3544,7 @@ type RAIMStatistics_2_0_t struct {
 	VelocityHERL      float32
 	VelocityVERL      float32
 	UnityOverallModel uint16
-	_padding          [SBF_RAIMSTATISTICS_2_0__PADDING_LENGTH]uint8
+	_                 [SBF_RAIMSTATISTICS_2_0__PADDING_LENGTH]uint8
 }
 
 type RAIMStatistics_2_t RAIMStatistics_2_0_t
@@ -4439,7 +4439,7 @@ type ReceiverTime_1_0_t struct {
 	UTCSec    int8
 	DeltaLS   int8
 	SyncLevel uint8
-	_padding  [SBF_RECEIVERTIME_1_0__PADDING_LENGTH]uint8
+	_         [SBF_RECEIVERTIME_1_0__PADDING_LENGTH]uint8
 }
 
 type ReceiverTime_1_t ReceiverTime_1_0_t
@@ -4474,7 +4474,7 @@ type xPPSOffset_1_t xPPSOffset_1_0_t
 type TimeOffsetSub_1_0_t struct {
 	TimeSystem uint8
 	Offset     float32
-	_padding   [SBF_TIMEOFFSETSUB_1_0__PADDING_LENGTH]uint8
+	_          [SBF_TIMEOFFSETSUB_1_0__PADDING_LENGTH]uint8
 }
 
 /** SysTimeOffset_1_0_t */
@@ -4603,7 +4603,7 @@ type ExtEventPVTCartesian_1_0_t struct {
 	MeanCorrAge uint16
 	SignalInfo  uint32
 	AlertFlag   uint8
-	_padding    [SBF_EXTEVENTPVTCARTESIAN_1_0__PADDING_LENGTH]uint8
+	_           [SBF_EXTEVENTPVTCARTESIAN_1_0__PADDING_LENGTH]uint8
 }
 
 /*--ExtEventPVTCartesian_1_1_t : --------------------------------------------*/
@@ -4677,7 +4677,7 @@ type ExtEventPVTCartesian_1_2_t struct {
 	HAccuracy   uint16
 	VAccuracy   uint16
 	Misc        uint8
-	_padding    [SBF_EXTEVENTPVTCARTESIAN_1_2__PADDING_LENGTH]uint8
+	_           [SBF_EXTEVENTPVTCARTESIAN_1_2__PADDING_LENGTH]uint8
 }
 
 type ExtEventPVTCartesian_1_t ExtEventPVTCartesian_1_2_t
@@ -4713,7 +4713,7 @@ type ExtEventPVTGeodetic_1_0_t struct {
 	MeanCorrAge uint16
 	SignalInfo  uint32
 	AlertFlag   uint8
-	_padding    [SBF_EXTEVENTPVTGEODETIC_1_0__PADDING_LENGTH]uint8
+	_           [SBF_EXTEVENTPVTGEODETIC_1_0__PADDING_LENGTH]uint8
 }
 
 /*--ExtEventPVTGeodetic_1_1_t : ---------------------------------------------*/
@@ -4788,7 +4788,7 @@ type ExtEventPVTGeodetic_1_2_t struct {
 	HAccuracy   uint16
 	VAccuracy   uint16
 	Misc        uint8
-	_padding    [SBF_EXTEVENTPVTGEODETIC_1_2__PADDING_LENGTH]uint8
+	_           [SBF_EXTEVENTPVTGEODETIC_1_2__PADDING_LENGTH]uint8
 }
 
 type ExtEventPVTGeodetic_1_t ExtEventPVTGeodetic_1_2_t
@@ -5227,7 +5227,7 @@ type TrackData_1_1_t struct {
 	Status     uint8
 	Reserved   uint8 /* Reserved for future use */
 	LockTime   uint16
-	_padding   [SBF_TRACKDATA_1_1__PADDING_LENGTH]uint8
+	_          [SBF_TRACKDATA_1_1__PADDING_LENGTH]uint8
 }
 
 /** LBandTrackerStatus_1_1_t */
@@ -5259,7 +5259,7 @@ type TrackData_1_2_t struct {
 	Status     uint8
 	SVID       uint8
 	LockTime   uint16
-	_padding   [SBF_TRACKDATA_1_2__PADDING_LENGTH]uint8
+	_          [SBF_TRACKDATA_1_2__PADDING_LENGTH]uint8
 }
 
 /** LBandTrackerStatus_1_2_t */
@@ -5291,7 +5291,7 @@ type TrackData_1_3_t struct {
 	SVID       uint8
 	LockTime   uint16
 	Source     uint8
-	_padding   [SBF_TRACKDATA_1_3__PADDING_LENGTH]uint8
+	_          [SBF_TRACKDATA_1_3__PADDING_LENGTH]uint8
 }
 
 /** LBandTrackerStatus_1_3_t */
@@ -5391,7 +5391,7 @@ type LBAS1DecoderStatus_1_2_t struct {
 	PAC               [SBF_LBAS1DECODERSTATUS_1_2_PAC_LENGTH]byte
 	VelocityLimit     uint8
 	SpeedGatingStatus uint8
-	_padding          [SBF_LBAS1DECODERSTATUS_1_2__PADDING_LENGTH]uint8
+	_                 [SBF_LBAS1DECODERSTATUS_1_2__PADDING_LENGTH]uint8
 }
 
 type LBAS1DecoderStatus_1_t LBAS1DecoderStatus_1_2_t
@@ -5635,7 +5635,7 @@ type OneSensor_1_1_t struct {
 	LeverArmSource uint8
 	Reserved1      uint8
 
-	_padding [SBF_ONESENSOR_1_1__PADDING_LENGTH]uint8
+	_ [SBF_ONESENSOR_1_1__PADDING_LENGTH]uint8
 }
 
 /** ExtSensorSetup_1_1_t */
@@ -5787,7 +5787,7 @@ type TrackingStatusChannel_1_0_t struct {
 	Elevation      int8
 	Health         uint8
 	ElevChange     int8
-	_padding       [SBF_TRACKINGSTATUSCHANNEL_1_0__PADDING_LENGTH]uint8
+	_              [SBF_TRACKINGSTATUSCHANNEL_1_0__PADDING_LENGTH]uint8
 }
 
 /** TrackingStatus_1_0_t */
@@ -5980,7 +5980,7 @@ type InputLink_1_t InputLink_1_0_t
 type OutputTypeSub_1_0_t struct {
 	Type       uint8
 	Percentage uint8
-	_padding   [SBF_OUTPUTTYPESUB_1_0__PADDING_LENGTH]uint8
+	_          [SBF_OUTPUTTYPESUB_1_0__PADDING_LENGTH]uint8
 }
 
 /** OutputStatsSub_1_0_t */
@@ -6146,7 +6146,7 @@ type WiFiClient_1_0_t struct {
 	ClientHostName   [SBF_WIFICLIENT_1_0_CLIENTHOSTNAME_LENGTH]byte
 	ClientMACAddress [SBF_WIFICLIENT_1_0_CLIENTMACADDRESS_LENGTH]uint8
 	ClientIPAddress  [SBF_WIFICLIENT_1_0_CLIENTIPADDRESS_LENGTH]uint8
-	_padding         [SBF_WIFICLIENT_1_0__PADDING_LENGTH]uint8
+	_                [SBF_WIFICLIENT_1_0__PADDING_LENGTH]uint8
 }
 
 /** WiFiAPStatus_1_0_t */
@@ -6185,7 +6185,7 @@ type WiFiClientStatus_1_0_t struct {
 	SigLevel  int8
 	Status    uint8
 	ErrorCode uint8
-	_padding  [SBF_WIFICLIENTSTATUS_1_0__PADDING_LENGTH]uint8
+	_         [SBF_WIFICLIENTSTATUS_1_0__PADDING_LENGTH]uint8
 }
 type WiFiClientStatus_1_t WiFiClientStatus_1_0_t
 
@@ -6205,7 +6205,7 @@ type CellularStatus_1_0_t struct {
 	OperatorName   [SBF_CELLULARSTATUS_1_0_OPERATORNAME_LENGTH]byte
 	Status         uint8
 	ErrorCode      uint8
-	_padding       [SBF_CELLULARSTATUS_1_0__PADDING_LENGTH]uint8
+	_              [SBF_CELLULARSTATUS_1_0__PADDING_LENGTH]uint8
 }
 
 /*--CellularStatus_1_1_t : --------------------------------------------------*/
@@ -6236,7 +6236,7 @@ type CellularStatus_1_t CellularStatus_1_1_t
 type BTDevice_1_0_t struct {
 	DeviceName [SBF_BTDEVICE_1_0_DEVICENAME_LENGTH]byte
 	Flags      uint8
-	_padding   [SBF_BTDEVICE_1_0__PADDING_LENGTH]uint8
+	_          [SBF_BTDEVICE_1_0__PADDING_LENGTH]uint8
 }
 
 /** BluetoothStatus_1_0_t */
@@ -6419,7 +6419,7 @@ type DiskData_1_0_t struct {
 	DiskSize          uint32
 	CreateDeleteCount uint8
 
-	_padding [SBF_DISKDATA_1_0__PADDING_LENGTH]uint8
+	_ [SBF_DISKDATA_1_0__PADDING_LENGTH]uint8
 }
 
 /** DiskStatus_1_0_t */
@@ -6446,7 +6446,7 @@ type DiskData_1_1_t struct {
 	CreateDeleteCount uint8
 	Error             uint8
 
-	_padding [SBF_DISKDATA_1_1__PADDING_LENGTH]uint8
+	_ [SBF_DISKDATA_1_1__PADDING_LENGTH]uint8
 }
 
 /** DiskStatus_1_1_t */
@@ -6515,7 +6515,7 @@ type UHFData_1_0_t struct {
 	Bandwidth uint8
 	RSSI      int8
 
-	_padding [SBF_UHFDATA_1_0__PADDING_LENGTH]uint8
+	_ [SBF_UHFDATA_1_0__PADDING_LENGTH]uint8
 }
 
 /** UHFStatus_1_0_t */
@@ -6547,7 +6547,7 @@ type RFBand_1_0_t struct {
 	Bandwidth uint16
 	Info      uint8
 
-	_padding [SBF_RFBAND_1_0__PADDING_LENGTH]uint8
+	_ [SBF_RFBAND_1_0__PADDING_LENGTH]uint8
 }
 
 /** RFStatus_1_0_t */
@@ -6589,7 +6589,7 @@ type RIMSHealth_1_0_t struct {
 	Temperature  int16
 	MemoryLoad   uint8
 
-	_padding [SBF_RIMSHEALTH_1_0__PADDING_LENGTH]uint8
+	_ [SBF_RIMSHEALTH_1_0__PADDING_LENGTH]uint8
 }
 
 type RIMSHealth_1_t RIMSHealth_1_0_t
@@ -6658,7 +6658,7 @@ type GALNavMonitor_1_0_t struct {
 	IODNavPrev   uint16
 	Flags        uint8
 
-	_padding [SBF_GALNAVMONITOR_1_0__PADDING_LENGTH]uint8
+	_ [SBF_GALNAVMONITOR_1_0__PADDING_LENGTH]uint8
 }
 type GALNavMonitor_1_t GALNavMonitor_1_0_t
 
@@ -6677,7 +6677,7 @@ type INAVmonitor_1_0_t struct {
 	RS_status uint8
 	SSPstatus uint8
 
-	_padding [SBF_INAVMONITOR_1_0__PADDING_LENGTH]uint8
+	_ [SBF_INAVMONITOR_1_0__PADDING_LENGTH]uint8
 }
 type INAVmonitor_1_t INAVmonitor_1_0_t
 
@@ -6744,8 +6744,8 @@ type CosmosStatus_1_0_t struct {
 	TOW uint32
 	WNc uint16
 
-	Status   uint8
-	_padding [SBF_COSMOSSTATUS_1_0__PADDING_LENGTH]uint8
+	Status uint8
+	_      [SBF_COSMOSSTATUS_1_0__PADDING_LENGTH]uint8
 }
 
 type CosmosStatus_1_t CosmosStatus_1_0_t
@@ -6773,9 +6773,9 @@ type ReceiverSetup_1_0_t struct {
 	RxVersion    [SBF_RECEIVERSETUP_1_0_RXVERSION_LENGTH]byte
 	AntSerialNbr [SBF_RECEIVERSETUP_1_0_ANTSERIALNBR_LENGTH]byte
 	AntType      [SBF_RECEIVERSETUP_1_0_ANTTYPE_LENGTH]byte
-	deltaH       float32 /* [m] */
-	deltaE       float32 /* [m] */
-	deltaN       float32 /* [m] */
+	DeltaH       float32 /* [m] */
+	DeltaE       float32 /* [m] */
+	DeltaN       float32 /* [m] */
 }
 
 /*--ReceiverSetup_1_1_t : ---------------------------------------------------*/
@@ -6799,9 +6799,9 @@ type ReceiverSetup_1_1_t struct {
 	RxVersion    [SBF_RECEIVERSETUP_1_1_RXVERSION_LENGTH]byte
 	AntSerialNbr [SBF_RECEIVERSETUP_1_1_ANTSERIALNBR_LENGTH]byte
 	AntType      [SBF_RECEIVERSETUP_1_1_ANTTYPE_LENGTH]byte
-	deltaH       float32 /* [m] */
-	deltaE       float32 /* [m] */
-	deltaN       float32 /* [m] */
+	DeltaH       float32 /* [m] */
+	DeltaE       float32 /* [m] */
+	DeltaN       float32 /* [m] */
 	MarkerType   [SBF_RECEIVERSETUP_1_1_MARKERTYPE_LENGTH]byte
 }
 
@@ -6826,9 +6826,9 @@ type ReceiverSetup_1_2_t struct {
 	RxVersion     [SBF_RECEIVERSETUP_1_2_RXVERSION_LENGTH]byte
 	AntSerialNbr  [SBF_RECEIVERSETUP_1_2_ANTSERIALNBR_LENGTH]byte
 	AntType       [SBF_RECEIVERSETUP_1_2_ANTTYPE_LENGTH]byte
-	deltaH        float32 /* [m] */
-	deltaE        float32 /* [m] */
-	deltaN        float32 /* [m] */
+	DeltaH        float32 /* [m] */
+	DeltaE        float32 /* [m] */
+	DeltaN        float32 /* [m] */
 	MarkerType    [SBF_RECEIVERSETUP_1_2_MARKERTYPE_LENGTH]byte
 	GNSSFWVersion [SBF_RECEIVERSETUP_1_2_GNSSFWVERSION_LENGTH]byte
 }
@@ -6854,9 +6854,9 @@ type ReceiverSetup_1_3_t struct {
 	RxVersion     [SBF_RECEIVERSETUP_1_3_RXVERSION_LENGTH]byte
 	AntSerialNbr  [SBF_RECEIVERSETUP_1_3_ANTSERIALNBR_LENGTH]byte
 	AntType       [SBF_RECEIVERSETUP_1_3_ANTTYPE_LENGTH]byte
-	deltaH        float32 /* [m] */
-	deltaE        float32 /* [m] */
-	deltaN        float32 /* [m] */
+	DeltaH        float32 /* [m] */
+	DeltaE        float32 /* [m] */
+	DeltaN        float32 /* [m] */
 	MarkerType    [SBF_RECEIVERSETUP_1_3_MARKERTYPE_LENGTH]byte
 	GNSSFWVersion [SBF_RECEIVERSETUP_1_3_GNSSFWVERSION_LENGTH]byte
 	ProductName   [SBF_RECEIVERSETUP_1_3_PRODUCTNAME_LENGTH]byte
@@ -6883,9 +6883,9 @@ type ReceiverSetup_1_4_t struct {
 	RxVersion     [SBF_RECEIVERSETUP_1_4_RXVERSION_LENGTH]byte
 	AntSerialNbr  [SBF_RECEIVERSETUP_1_4_ANTSERIALNBR_LENGTH]byte
 	AntType       [SBF_RECEIVERSETUP_1_4_ANTTYPE_LENGTH]byte
-	deltaH        float32 /* [m] */
-	deltaE        float32 /* [m] */
-	deltaN        float32 /* [m] */
+	DeltaH        float32 /* [m] */
+	DeltaE        float32 /* [m] */
+	DeltaN        float32 /* [m] */
 	MarkerType    [SBF_RECEIVERSETUP_1_4_MARKERTYPE_LENGTH]byte
 	GNSSFWVersion [SBF_RECEIVERSETUP_1_4_GNSSFWVERSION_LENGTH]byte
 	ProductName   [SBF_RECEIVERSETUP_1_4_PRODUCTNAME_LENGTH]byte
@@ -6913,7 +6913,7 @@ type Component_1_0_t struct {
 	FWVersion    [SBF_COMPONENT_1_0_FWVERSION_LENGTH]byte
 	MACAddress   [SBF_COMPONENT_1_0_MACADDRESS_LENGTH]uint8
 
-	_padding [SBF_COMPONENT_1_0__PADDING_LENGTH]uint8
+	_ [SBF_COMPONENT_1_0__PADDING_LENGTH]uint8
 }
 
 /** RxComponents_1_0_t */
@@ -7167,7 +7167,7 @@ type SatHpca_1_0_t struct {
 	NavAge  uint16
 	FreqNr  uint8
 
-	_padding [SBF_SATHPCA_1_0__PADDING_LENGTH]uint8
+	_ [SBF_SATHPCA_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURHPCAInfo_1_0_t */
@@ -7250,7 +7250,7 @@ type CorrValues_1_0_t struct {
 	Reserved          [3]uint8
 	CorrelationValues [SBF_CORRVALUES_1_0_CORRELATIONVALUES_LENGTH]CorrValue_1_0_t
 
-	_padding [SBF_CORRVALUES_1_0__PADDING_LENGTH]uint8
+	_ [SBF_CORRVALUES_1_0__PADDING_LENGTH]uint8
 }
 
 type CorrValue_1_t CorrValue_1_0_t
@@ -7390,7 +7390,7 @@ type TURFormatMode1RGCrequest_1_0_t struct {
 	uPeriod   uint16
 	uLatency  uint16
 
-	_padding [SBF_TURFORMATMODE1RGCREQUEST_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATMODE1RGCREQUEST_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatRangingCodeReady_1_0_t */
@@ -7398,7 +7398,7 @@ type TURFormatRangingCodeReady_1_0_t struct {
 	uCodeGen uint8
 	uPadding uint8
 
-	_padding [SBF_TURFORMATRANGINGCODEREADY_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATRANGINGCODEREADY_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatMode2RGCrequest_1_0_t */
@@ -7426,7 +7426,7 @@ type TURFormatGetMode2Time_1_0_t struct {
 	uTOW      uint16
 	uPadding  uint8
 
-	_padding [SBF_TURFORMATGETMODE2TIME_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATGETMODE2TIME_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatMode2TimeReply_1_0_t */
@@ -7434,7 +7434,7 @@ type TURFormatMode2TimeReply_1_0_t struct {
 	uRefCount uint16
 	uIndex    uint32
 
-	_padding [SBF_TURFORMATMODE2TIMEREPLY_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATMODE2TIMEREPLY_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatRangingCodeRequest_1_0_t */
@@ -7448,7 +7448,7 @@ type TURFormatRangingCodeRequest_1_0_t struct {
 	uBlock       uint32
 	uM1Parameter uint8
 
-	_padding [SBF_TURFORMATRANGINGCODEREQUEST_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATRANGINGCODEREQUEST_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatEncryptedData_1_0_t */
@@ -7460,7 +7460,7 @@ type TURFormatEncryptedData_1_0_t struct {
 	uData    [SBF_TURFORMATENCRYPTEDDATA_1_0_UDATA_LENGTH]uint8
 	uPadding uint8
 
-	_padding [SBF_TURFORMATENCRYPTEDDATA_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATENCRYPTEDDATA_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatDecryptedData_1_0_t */
@@ -7469,7 +7469,7 @@ type TURFormatDecryptedData_1_0_t struct {
 	uData     [SBF_TURFORMATDECRYPTEDDATA_1_0_UDATA_LENGTH]uint8
 	uPadding  uint8
 
-	_padding [SBF_TURFORMATDECRYPTEDDATA_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATDECRYPTEDDATA_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatPVT_1_0_t */
@@ -7492,7 +7492,7 @@ type TURFormatDenial_1_0_t struct {
 	uDenialFlag uint8
 	uPadding    uint8
 
-	_padding [SBF_TURFORMATDENIAL_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATDENIAL_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatEventLog_1_0_t */
@@ -7517,7 +7517,7 @@ type TURFormatStatusResponse_1_0_t struct {
 	uStatus  uint8
 	uPadding uint8
 
-	_padding [SBF_TURFORMATSTATUSRESPONSE_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATSTATUSRESPONSE_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatOptionA_1_0_t */
@@ -7529,7 +7529,7 @@ type TURFormatOptionA_1_0_t struct {
 	uTOW2      uint32
 	uOptionA_2 uint8
 
-	_padding [SBF_TURFORMATOPTIONA_1_0__PADDING_LENGTH]uint8
+	_ [SBF_TURFORMATOPTIONA_1_0__PADDING_LENGTH]uint8
 }
 
 /** TURFormatOptionB_1_0_t */
@@ -7722,7 +7722,7 @@ type GISAction_1_0_t struct {
 	Database  uint8
 	Reserved  uint8
 	Comment   [SBF_GISACTION_1_0_COMMENT_LENGTH]byte
-	_padding  [SBF_GISACTION_1_0__PADDING_LENGTH]uint8
+	_         [SBF_GISACTION_1_0__PADDING_LENGTH]uint8
 }
 
 type GISAction_1_t GISAction_1_0_t
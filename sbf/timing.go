@@ -0,0 +1,247 @@
+package sbf
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+/**
+ * Decoding of the time blocks used to monitor receivers acting as timing
+ * references. ReceiverTime also keeps the GPS-UTC leap seconds used for the
+ * UTC time of every published block up to date.
+ */
+
+const timingReceiverTimeTopic = "timing/receivertime"
+const timingPPSTopic = "timing/pps"
+const timingSystemOffsetsTopic = "timing/systemoffsets"
+const timingAlarmTopic = "timing/alarm"
+
+/* ReceiverTime SyncLevel bits */
+const syncLevelWNSet = 0x01
+const syncLevelTOWSet = 0x02
+const syncLevelFineTime = 0x04
+
+/* Offset of the first sub-block in SysTimeOffset */
+const sysTimeOffsetSubBlockOffset = 20
+
+/* Number of xPPSOffset blocks the smoothed PPS offset is computed over */
+const ppsOffsetWindow = 60
+
+// ReceiverTime is the UTC time of the receiver and how well it is synchronized
+type ReceiverTime struct {
+	BlockTime
+	UTC         *time.Time `json:"utc,omitempty"`
+	LeapSeconds *int       `json:"leapSeconds,omitempty"`
+	SyncLevel   string     `json:"syncLevel"` // none, coarse or fine
+	WNSet       bool       `json:"wnSet"`
+	TOWSet      bool       `json:"towSet"`
+	FineTime    bool       `json:"fineTime"`
+}
+
+// PPSOffset is the offset of the xPPS pulse with respect to the true one. The
+// receiver reports the sawtooth of the pulse, its quantisation error. The mean
+// over the last pulses is only a smoothed statistic of it.
+type PPSOffset struct {
+	BlockTime
+	TimeScale         string   `json:"timeScale"`
+	SyncAge           uint8    `json:"syncAge"`                     // s
+	QuantizationError *float64 `json:"quantizationError,omitempty"` // ns, sawtooth error of the pulse
+	MeanOffset        *float64 `json:"meanOffset,omitempty"`        // ns, mean sawtooth error of the last pulses
+}
+
+// SystemTimeOffsets are the offsets of the GNSS system times to a reference time system
+type SystemTimeOffsets struct {
+	BlockTime
+	Reference string             `json:"reference"`
+	Offsets   []SystemTimeOffset `json:"offsets"`
+}
+
+// SystemTimeOffset is the offset of one GNSS system time to the reference
+type SystemTimeOffset struct {
+	System string   `json:"system"`
+	Offset *float64 `json:"offset,omitempty"` // ns
+	Mode   *uint8   `json:"mode,omitempty"`
+}
+
+var timeSystems = map[uint8]string{
+	0: "GPS",
+	1: "Galileo",
+	3: "GLONASS",
+	4: "BeiDou",
+	5: "QZSS",
+}
+
+var ppsTimeScales = map[uint8]string{
+	1: "GPS",
+	2: "Galileo",
+	3: "UTC",
+	4: "RxClock",
+	5: "GLONASS",
+	6: "BeiDou",
+	7: "QZSS",
+}
+
+var syncLevels = map[string]int{
+	"none":   0,
+	"coarse": 1,
+	"fine":   2,
+}
+
+func timeSystemString(system uint8) string {
+	if name, ok := timeSystems[system]; ok {
+		return name
+	}
+	return fmt.Sprintf("system%d", system)
+}
+
+func syncLevelString(syncLevel uint8) string {
+	if syncLevel&syncLevelWNSet == 0 || syncLevel&syncLevelTOWSet == 0 {
+		return "none"
+	}
+	if syncLevel&syncLevelFineTime == 0 {
+		return "coarse"
+	}
+	return "fine"
+}
+
+func handleReceiverTime(buffer []byte) []interface{} {
+	block := ReceiverTime_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleReceiverTime - Error decoding ReceiverTime block: %s\n", err.Error())
+		return []interface{}{}
+	}
+
+	// The receiver's leap seconds replace the default, so all block times
+	// published from now on use them
+	if block.DeltaLS != -128 {
+		gpsUtcLeapSeconds = int(block.DeltaLS)
+	}
+
+	receiverTime := ReceiverTime{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		SyncLevel: syncLevelString(block.SyncLevel),
+		WNSet:     block.SyncLevel&syncLevelWNSet != 0,
+		TOWSet:    block.SyncLevel&syncLevelTOWSet != 0,
+		FineTime:  block.SyncLevel&syncLevelFineTime != 0,
+	}
+	if block.DeltaLS != -128 {
+		leapSeconds := int(block.DeltaLS)
+		receiverTime.LeapSeconds = &leapSeconds
+	}
+	if block.UTCYear != -128 && block.UTCMonth != -128 && block.UTCDay != -128 &&
+		block.UTCHour != -128 && block.UTCMin != -128 && block.UTCSec != -128 {
+		milliseconds := 0
+		if block.TOW != towDoNotUse {
+			milliseconds = int(block.TOW % 1000)
+		}
+		utc := time.Date(2000+int(block.UTCYear), time.Month(block.UTCMonth), int(block.UTCDay),
+			int(block.UTCHour), int(block.UTCMin), int(block.UTCSec), milliseconds*int(time.Millisecond), time.UTC)
+		receiverTime.UTC = &utc
+	}
+
+	payloads := []interface{}{Payload{Topic: timingReceiverTimeTopic, Data: receiverTime}}
+
+	if settings.MinTimeSyncLevel != "" {
+		active := syncLevels[receiverTime.SyncLevel] < syncLevels[settings.MinTimeSyncLevel]
+		payloads = append(payloads, updateAlarm(timingAlarmTopic, Alarm{
+			BlockTime: receiverTime.BlockTime,
+			Alarm:     "syncLevel",
+			Active:    active,
+			Message:   fmt.Sprintf("time sync level is %s, minimum is %s", receiverTime.SyncLevel, settings.MinTimeSyncLevel),
+			Value:     floatPtr(float64(syncLevels[receiverTime.SyncLevel])),
+			Limit:     floatPtr(float64(syncLevels[settings.MinTimeSyncLevel])),
+		})...)
+	}
+
+	return payloads
+}
+
+func handleXPPSOffset(buffer []byte) []interface{} {
+	block := xPPSOffset_1_0_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleXPPSOffset - Error decoding xPPSOffset block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	data := block.PPSData
+
+	pps := PPSOffset{
+		BlockTime:         newBlockTime(data.TOW, data.WNc),
+		TimeScale:         "unknown",
+		SyncAge:           data.SyncAge,
+		QuantizationError: optFloat(data.Offset),
+	}
+	if name, ok := ppsTimeScales[data.Source]; ok {
+		pps.TimeScale = name
+	}
+	payloads := []interface{}{}
+
+	if pps.QuantizationError != nil {
+		state.ppsOffsets = append(state.ppsOffsets, *pps.QuantizationError)
+		if len(state.ppsOffsets) > ppsOffsetWindow {
+			state.ppsOffsets = state.ppsOffsets[len(state.ppsOffsets)-ppsOffsetWindow:]
+		}
+		sum := 0.0
+		for _, offset := range state.ppsOffsets {
+			sum += offset
+		}
+		mean := sum / float64(len(state.ppsOffsets))
+		pps.MeanOffset = &mean
+	}
+	payloads = append(payloads, Payload{Topic: timingPPSTopic, Data: pps})
+
+	if settings.PPSOffsetLimit > 0 && pps.QuantizationError != nil {
+		payloads = append(payloads, updateAlarm(timingAlarmTopic, Alarm{
+			BlockTime: pps.BlockTime,
+			Alarm:     "ppsOffset",
+			Active:    math.Abs(*pps.QuantizationError) > settings.PPSOffsetLimit,
+			Message:   fmt.Sprintf("PPS offset is %.3f ns, limit is %.3f ns", *pps.QuantizationError, settings.PPSOffsetLimit),
+			Value:     pps.QuantizationError,
+			Limit:     floatPtr(settings.PPSOffsetLimit),
+		})...)
+	}
+
+	return payloads
+}
+
+func handleSysTimeOffset(buffer []byte) []interface{} {
+	block := SysTimeOffset_1_1_t{}
+	if err := decodeBlock(buffer, &block); err != nil {
+		log.Printf("[ERROR] handleSysTimeOffset - Error decoding SysTimeOffset block: %s\n", err.Error())
+		return []interface{}{}
+	}
+	revision := block.Header.ID >> 13
+
+	offsets := SystemTimeOffsets{
+		BlockTime: newBlockTime(block.TOW, block.WNc),
+		Reference: timeSystemString(block.RefTimeSystem),
+		Offsets:   []SystemTimeOffset{},
+	}
+	for i := 0; i < int(block.N); i++ {
+		offset := sysTimeOffsetSubBlockOffset + i*int(block.SBLength)
+		entry := SystemTimeOffset{}
+		if revision == 0 {
+			sb := TimeOffsetSub_1_0_t{}
+			if !decodeSubBlock(buffer, offset, &sb) {
+				log.Printf("[ERROR] handleSysTimeOffset - SysTimeOffset block too short for %d offsets\n", block.N)
+				break
+			}
+			entry.System = timeSystemString(sb.TimeSystem)
+			entry.Offset = optFloat(sb.Offset)
+		} else {
+			sb := TimeOffsetSub_1_1_t{}
+			if !decodeSubBlock(buffer, offset, &sb) {
+				log.Printf("[ERROR] handleSysTimeOffset - SysTimeOffset block too short for %d offsets\n", block.N)
+				break
+			}
+			mode := sb.Mode
+			entry.System = timeSystemString(sb.TimeSystem)
+			entry.Offset = optFloat(sb.Offset)
+			entry.Mode = &mode
+		}
+		offsets.Offsets = append(offsets.Offsets, entry)
+	}
+
+	return []interface{}{Payload{Topic: timingSystemOffsetsTopic, Data: offsets}}
+}
@@ -0,0 +1,482 @@
+package sbf
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/**
+ * Position track of the receiver, kept from the PVTGeodetic epochs, for GIS
+ * tools. The track of the last settings.TrackRetention seconds is kept in
+ * memory and returned for a time window on request, as a GeoJSON
+ * FeatureCollection, a GPX 1.1 track or a KML document. With
+ * settings.TrackDirectory set the track of every hour or day is also written
+ * to rolling files in the settings.TrackFormats.
+ *
+ * The fix quality is the SBF PVT mode name. GeoJSON features and KML
+ * placemarks hold a run of epochs of the same quality, with a line color per
+ * quality; GPX track points carry it as their type and fix.
+ */
+
+const trackTopic = "track"
+
+/* Epochs further apart than this, in seconds, start a new track segment */
+const trackGapLimit = 60
+
+/* Seconds of data between rewrites of the files of the current period */
+const trackFileInterval = 60
+
+/* Points of the files of a period before the next files are started, so the
+ * rewrites of a daily file stay short */
+const trackFilePoints = 3600
+
+// TrackFormats lists the export formats of the track
+var TrackFormats = []string{"geojson", "gpx", "kml"}
+
+var trackFileExtensions = map[string]string{
+	"geojson": ".geojson",
+	"gpx":     ".gpx",
+	"kml":     ".kml",
+}
+
+// TrackRequest asks for the track of a time window. Start and end are
+// optional, all the kept track is returned without them.
+type TrackRequest struct {
+	Format string     `json:"format"` // geojson, gpx or kml, defaults to geojson
+	Start  *time.Time `json:"start,omitempty"`
+	End    *time.Time `json:"end,omitempty"`
+}
+
+// GeoJSONFeatureCollection is a track exported as GeoJSON
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature is a run of track points of the same fix quality
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   GeoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONGeometry is a LineString, or a Point for an epoch on its own
+type GeoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+/* One PVT epoch of the track */
+type trackPoint struct {
+	time          time.Time // UTC
+	latitude      float64   // °
+	longitude     float64   // °
+	height        float64   // m above the geoid, ellipsoidal without undulation
+	quality       string
+	twoD          bool
+	nrSV          *int
+	hdop          *float64
+	correctionAge *float64 // s
+	referenceID   *int
+}
+
+/* Name of the track and creator of the GPX files */
+type trackDescription struct {
+	name    string
+	creator string
+}
+
+/* Line color (RGB) of the fix qualities, gray for the others */
+var trackQualityColors = map[string]string{
+	"rtkFixed":              "00b050",
+	"movingBaseRtkFixed":    "00b050",
+	"pppFixed":              "00b050",
+	"fixedLocation":         "00b050",
+	"rtkFloat":              "ffa500",
+	"movingBaseRtkFloat":    "ffa500",
+	"rtkFloatWideLaneFixed": "ffa500",
+	"pppFloat":              "ffa500",
+	"differential":          "1e90ff",
+	"sbasAided":             "1e90ff",
+	"standAlone":            "ff0000",
+}
+
+func trackColor(quality string) string {
+	if color, ok := trackQualityColors[quality]; ok {
+		return color
+	}
+	return "808080"
+}
+
+// recordTrackPoint adds a PVT epoch with a position to the track, at most
+// one every settings.TrackInterval seconds
+func recordTrackPoint(block PVTGeodetic_2_2_t, blockTime BlockTime) {
+	if settings.TrackRetention <= 0 && settings.TrackDirectory == "" {
+		return
+	}
+	lat, lon, height := optDouble(block.Lat), optDouble(block.Lon), optDouble(block.Alt)
+	if blockTime.Time == nil || block.Mode&0x0F == MODE_NO_PVT_AVAILABLE || lat == nil || lon == nil || height == nil {
+		return
+	}
+
+	point := trackPoint{
+		time:      *blockTime.Time,
+		latitude:  *lat * 180 / math.Pi,
+		longitude: *lon * 180 / math.Pi,
+		height:    *height,
+		quality:   pvtModeString(block.Mode),
+		twoD:      block.Mode&MODE_2D_PVT != 0,
+	}
+	// GPX and KML expect heights above mean sea level
+	if undulation := optFloat(block.Undulation); undulation != nil {
+		point.height -= *undulation
+	}
+	if block.NrSV != 255 {
+		nrSV := int(block.NrSV)
+		point.nrSV = &nrSV
+	}
+	if state.pvtDOP != nil && pvtBlockCurrent(state.pvtDOP.TOW, state.pvtDOP.WNc, block.TOW, block.WNc) {
+		point.hdop = optScaled(int64(state.pvtDOP.HDOP), 0, 0.01)
+	}
+	if block.Mode&0x0F != MODE_STAND_ALONE_PVT {
+		point.correctionAge = optScaled(int64(block.MeanCorrAge), 65535, 0.01)
+		if point.correctionAge != nil && block.ReferenceId != 65535 {
+			referenceID := int(block.ReferenceId)
+			point.referenceID = &referenceID
+		}
+	}
+
+	state.trackMutex.Lock()
+	if len(state.track) > 0 && point.time.Sub(state.track[len(state.track)-1].time).Seconds() < settings.TrackInterval {
+		state.trackMutex.Unlock()
+		return
+	}
+	state.track = append(state.track, point)
+	state.trackInfo = newTrackDescription()
+	// Drop the points that are no longer kept
+	first := 0
+	for first < len(state.track) && point.time.Sub(state.track[first].time).Seconds() > settings.TrackRetention {
+		first++
+	}
+	if first > 0 {
+		state.track = append([]trackPoint{}, state.track[first:]...)
+	}
+	state.trackMutex.Unlock()
+
+	writeTrackFiles(point)
+}
+
+// handleTrackRequest returns the track of a receiver for the time window of a
+// TrackRequest in the requested format. It is called outside Parse, so the
+// state of the receiver is passed rather than the current one.
+func handleTrackRequest(receiver *decoderState, data []byte) []interface{} {
+	request := TrackRequest{}
+	if err := json.Unmarshal(data, &request); err != nil {
+		log.Printf("[ERROR] handleTrackRequest - Error parsing track request: %s\n", err.Error())
+		return nil
+	}
+	if request.Format == "" {
+		request.Format = "geojson"
+	}
+	if _, ok := trackFileExtensions[request.Format]; !ok {
+		log.Printf("[ERROR] handleTrackRequest - Invalid track format %s\n", request.Format)
+		return nil
+	}
+
+	points := []trackPoint{}
+	receiver.trackMutex.Lock()
+	for _, point := range receiver.track {
+		if (request.Start == nil || !point.time.Before(*request.Start)) && (request.End == nil || !point.time.After(*request.End)) {
+			points = append(points, point)
+		}
+	}
+	description := receiver.trackInfo
+	receiver.trackMutex.Unlock()
+	log.Printf("[DEBUG] handleTrackRequest - Exporting %d track points as %s\n", len(points), request.Format)
+
+	var export interface{}
+	switch request.Format {
+	case "geojson":
+		export = trackGeoJSON(points)
+	case "gpx":
+		export = trackGPX(points, description)
+	case "kml":
+		export = trackKML(points, description)
+	}
+	return []interface{}{Payload{Topic: trackTopic + "/" + request.Format, Data: export}}
+}
+
+// trackRuns splits a track into lines of the same fix quality, that of their
+// last point. A run of another quality starts at the last point of the
+// previous run so the line stays continuous, while a gap of more than
+// trackGapLimit seconds breaks it.
+func trackRuns(points []trackPoint) [][]trackPoint {
+	var runs [][]trackPoint
+	var run []trackPoint
+	for i, point := range points {
+		if i > 0 {
+			previous := points[i-1]
+			if point.time.Sub(previous.time).Seconds() > trackGapLimit {
+				runs = append(runs, run)
+				run = nil
+			} else if point.quality != previous.quality {
+				runs = append(runs, run)
+				run = []trackPoint{previous}
+			}
+		}
+		run = append(run, point)
+	}
+	if len(run) > 0 {
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// trackLines returns the runs to draw as lines. A run of a single point is
+// only kept when it does not start the next run, e.g. after a gap.
+func trackLines(points []trackPoint) [][]trackPoint {
+	runs := trackRuns(points)
+	var lines [][]trackPoint
+	for i, run := range runs {
+		if len(run) == 1 && i+1 < len(runs) && runs[i+1][0].time.Equal(run[0].time) {
+			continue
+		}
+		lines = append(lines, run)
+	}
+	return lines
+}
+
+// trackSegments splits a track at the gaps of more than trackGapLimit seconds
+func trackSegments(points []trackPoint) [][]trackPoint {
+	var segments [][]trackPoint
+	for i, point := range points {
+		if i == 0 || point.time.Sub(points[i-1].time).Seconds() > trackGapLimit {
+			segments = append(segments, nil)
+		}
+		segments[len(segments)-1] = append(segments[len(segments)-1], point)
+	}
+	return segments
+}
+
+func trackGeoJSON(points []trackPoint) GeoJSONFeatureCollection {
+	collection := GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []GeoJSONFeature{}}
+	for _, run := range trackLines(points) {
+		coordinates := [][]float64{}
+		times := []string{}
+		for _, point := range run {
+			coordinates = append(coordinates, []float64{point.longitude, point.latitude, point.height})
+			times = append(times, point.time.Format(time.RFC3339Nano))
+		}
+		geometry := GeoJSONGeometry{Type: "LineString", Coordinates: coordinates}
+		if len(run) == 1 {
+			geometry = GeoJSONGeometry{Type: "Point", Coordinates: coordinates[0]}
+		}
+		quality := run[len(run)-1].quality
+		collection.Features = append(collection.Features, GeoJSONFeature{
+			Type:     "Feature",
+			Geometry: geometry,
+			Properties: map[string]interface{}{
+				"quality":    quality,
+				"start":      times[0],
+				"end":        times[len(times)-1],
+				"coordTimes": times,
+				"stroke":     "#" + trackColor(quality),
+			},
+		})
+	}
+	return collection
+}
+
+// gpxFix maps a fix quality to the fix of a GPX track point
+func gpxFix(point trackPoint) string {
+	switch point.quality {
+	case "standAlone":
+		if point.twoD {
+			return "2d"
+		}
+		return "3d"
+	case "noPVT":
+		return "none"
+	}
+	return "dgps"
+}
+
+func xmlEscape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+func trackGPX(points []trackPoint, description trackDescription) []byte {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString("<gpx version=\"1.1\" creator=\"" + xmlEscape(description.creator) + "\" xmlns=\"http://www.topografix.com/GPX/1/1\">\n")
+	b.WriteString("  <trk>\n    <name>" + xmlEscape(description.name) + "</name>\n")
+	for _, segment := range trackSegments(points) {
+		b.WriteString("    <trkseg>\n")
+		for _, point := range segment {
+			fmt.Fprintf(&b, "      <trkpt lat=\"%.9f\" lon=\"%.9f\">\n", point.latitude, point.longitude)
+			fmt.Fprintf(&b, "        <ele>%.3f</ele>\n", point.height)
+			fmt.Fprintf(&b, "        <time>%s</time>\n", point.time.Format("2006-01-02T15:04:05.999Z"))
+			fmt.Fprintf(&b, "        <type>%s</type>\n", point.quality)
+			fmt.Fprintf(&b, "        <fix>%s</fix>\n", gpxFix(point))
+			if point.nrSV != nil {
+				fmt.Fprintf(&b, "        <sat>%d</sat>\n", *point.nrSV)
+			}
+			if point.hdop != nil {
+				fmt.Fprintf(&b, "        <hdop>%.2f</hdop>\n", *point.hdop)
+			}
+			if point.correctionAge != nil {
+				fmt.Fprintf(&b, "        <ageofdgpsdata>%.2f</ageofdgpsdata>\n", *point.correctionAge)
+			}
+			if point.referenceID != nil && *point.referenceID <= 1023 {
+				fmt.Fprintf(&b, "        <dgpsid>%d</dgpsid>\n", *point.referenceID)
+			}
+			b.WriteString("      </trkpt>\n")
+		}
+		b.WriteString("    </trkseg>\n")
+	}
+	b.WriteString("  </trk>\n</gpx>\n")
+	return []byte(b.String())
+}
+
+// kmlColor converts an RGB color to the aabbggrr of KML
+func kmlColor(rgb string) string {
+	return "ff" + rgb[4:6] + rgb[2:4] + rgb[0:2]
+}
+
+func trackKML(points []trackPoint, description trackDescription) []byte {
+	runs := trackLines(points)
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString("<kml xmlns=\"http://www.opengis.net/kml/2.2\">\n  <Document>\n")
+	b.WriteString("    <name>" + xmlEscape(description.name) + "</name>\n")
+	styles := map[string]bool{}
+	for _, run := range runs {
+		quality := run[len(run)-1].quality
+		if styles[quality] {
+			continue
+		}
+		styles[quality] = true
+		fmt.Fprintf(&b, "    <Style id=\"%s\">\n      <LineStyle>\n        <color>%s</color>\n        <width>3</width>\n      </LineStyle>\n    </Style>\n",
+			quality, kmlColor(trackColor(quality)))
+	}
+	for _, run := range runs {
+		first, last := run[0], run[len(run)-1]
+		b.WriteString("    <Placemark>\n")
+		fmt.Fprintf(&b, "      <name>%s</name>\n", last.quality)
+		fmt.Fprintf(&b, "      <TimeSpan>\n        <begin>%s</begin>\n        <end>%s</end>\n      </TimeSpan>\n",
+			first.time.Format(time.RFC3339Nano), last.time.Format(time.RFC3339Nano))
+		fmt.Fprintf(&b, "      <styleUrl>#%s</styleUrl>\n", last.quality)
+		if len(run) == 1 {
+			fmt.Fprintf(&b, "      <Point>\n        <altitudeMode>absolute</altitudeMode>\n        <coordinates>%.9f,%.9f,%.3f</coordinates>\n      </Point>\n",
+				first.longitude, first.latitude, first.height)
+		} else {
+			b.WriteString("      <LineString>\n        <altitudeMode>absolute</altitudeMode>\n        <coordinates>\n")
+			for _, point := range run {
+				fmt.Fprintf(&b, "          %.9f,%.9f,%.3f\n", point.longitude, point.latitude, point.height)
+			}
+			b.WriteString("        </coordinates>\n      </LineString>\n")
+		}
+		b.WriteString("    </Placemark>\n")
+	}
+	b.WriteString("  </Document>\n</kml>\n")
+	return []byte(b.String())
+}
+
+// newTrackDescription names the track after the marker and the receiver of
+// ReceiverSetup
+func newTrackDescription() trackDescription {
+	description := trackDescription{name: "GNSS track", creator: "Septentrio GNSS adapter"}
+	if state.inventory.MarkerName != "" {
+		description.name = state.inventory.MarkerName
+	}
+	if state.inventory.ProductName != "" {
+		description.creator = "Septentrio " + state.inventory.ProductName
+	}
+	return description
+}
+
+// writeTrackFiles adds a point to the rolling files. The files of the current
+// period are rewritten every trackFileInterval seconds, and a last time when
+// the next period starts. A period gets new files every trackFilePoints
+// points. A file left by an earlier session for the same period is not
+// overwritten, the files of this session and the next files of a period get
+// the time of their first point in their name.
+func writeTrackFiles(point trackPoint) {
+	if settings.TrackDirectory == "" {
+		return
+	}
+	period := point.time.Truncate(24 * time.Hour)
+	layout := "20060102"
+	if settings.TrackRotation == "hourly" {
+		period = point.time.Truncate(time.Hour)
+		layout = "20060102_15"
+	}
+
+	newPeriod := !period.Equal(state.trackPeriod)
+	if newPeriod || len(state.trackPeriodPoints) >= trackFilePoints {
+		if len(state.trackPeriodPoints) > 0 {
+			saveTrackFiles()
+		}
+		state.trackPeriod = period
+		state.trackPeriodPoints = nil
+		state.trackPeriodNames = map[string]string{}
+		directory := receiverDirectory(settings.TrackDirectory)
+		for _, format := range settings.TrackFormats {
+			name := filepath.Join(directory, "track_"+period.Format(layout)+trackFileExtensions[format])
+			if _, err := os.Stat(name); err == nil || !newPeriod {
+				name = filepath.Join(directory, "track_"+period.Format(layout)+"_"+point.time.Format("150405")+trackFileExtensions[format])
+			}
+			state.trackPeriodNames[format] = name
+		}
+		state.trackLastWrite = time.Time{}
+	}
+
+	state.trackPeriodPoints = append(state.trackPeriodPoints, point)
+	if point.time.Sub(state.trackLastWrite).Seconds() >= trackFileInterval {
+		saveTrackFiles()
+		state.trackLastWrite = point.time
+	}
+}
+
+// saveTrackFiles writes the track of the current period in every format. The
+// files are replaced at once so readers never see a partial document.
+func saveTrackFiles() {
+	directory := receiverDirectory(settings.TrackDirectory)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		log.Printf("[ERROR] saveTrackFiles - Error creating track directory %s: %s\n", directory, err.Error())
+		return
+	}
+	for format, name := range state.trackPeriodNames {
+		var data []byte
+		switch format {
+		case "geojson":
+			var err error
+			if data, err = json.Marshal(trackGeoJSON(state.trackPeriodPoints)); err != nil {
+				log.Printf("[ERROR] saveTrackFiles - Error encoding GeoJSON track: %s\n", err.Error())
+				continue
+			}
+		case "gpx":
+			data = trackGPX(state.trackPeriodPoints, state.trackInfo)
+		case "kml":
+			data = trackKML(state.trackPeriodPoints, state.trackInfo)
+		}
+		if err := ioutil.WriteFile(name+".tmp", data, 0644); err != nil {
+			log.Printf("[ERROR] saveTrackFiles - Error writing track file %s: %s\n", name, err.Error())
+			continue
+		}
+		if err := os.Rename(name+".tmp", name); err != nil {
+			log.Printf("[ERROR] saveTrackFiles - Error replacing track file %s: %s\n", name, err.Error())
+		}
+	}
+}
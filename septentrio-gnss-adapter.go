@@ -13,7 +13,10 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 
 	sbf "Septentrio-GNSS-Adapter/sbf"
@@ -32,6 +35,8 @@ const (
 	msgPublishQos                  = 0
 	portRead                       = "receive"
 	portWrite                      = "send"
+	commandRequest                 = "request"
+	trackRequest                   = "request/track"
 	adapterConfigCollectionDefault = "adapter_config"
 )
 
@@ -56,9 +61,13 @@ var (
 
 	cbSubscribeChannel <-chan *mqttTypes.Publish
 	endWorkersChannel  chan string
+	/* Publishes a message on the MQTT broker, replaced by the tests */
+	mqttPublish = adapter_library.Publish
 
 	port   interface{}
 	buffer []byte
+	/* Serializes the writes of the MQTT commands and the NTRIP corrections */
+	portWriteMutex sync.Mutex
 )
 
 func main() {
@@ -90,6 +99,18 @@ func main() {
 	defer close(endWorkersChannel)
 	endWorkersChannel = make(chan string)
 
+	// Workers stopped through endWorkersChannel
+	workers := 1
+	if adapterSettings.NMEATcpPort > 0 {
+		startNMEAServer()
+		workers++
+	}
+
+	if adapterSettings.NtripCaster != "" {
+		go ntripWorker()
+		workers++
+	}
+
 	//Start read loop
 	go readWorker()
 
@@ -101,8 +122,10 @@ func main() {
 	log.Printf("[INFO] OS signal %s received, ending go routines.", sig)
 
 	//End the existing goRoutines
-	endWorkersChannel <- "Stop Channel"
-	endWorkersChannel <- "Stop Channel"
+	for i := 0; i < workers; i++ {
+		endWorkersChannel <- "Stop Channel"
+	}
+	sbf.Close()
 
 	//stop serial data mode when adapter is killed
 	// log.Println("[INFO] Stopping Serial Data Mode...")
@@ -117,7 +140,8 @@ func validateAdapterSettings() {
 	//Validate connection type
 	if adapterSettings.ConnectionType == "" {
 		log.Fatal("[FATAL] Connection type is required in adapter settings\n")
-	} else if !(adapterSettings.ConnectionType == "serial" || adapterSettings.ConnectionType == "tcp") {
+	} else if !(adapterSettings.ConnectionType == "serial" || adapterSettings.ConnectionType == "tcp" ||
+		adapterSettings.ConnectionType == "file" || adapterSettings.ConnectionType == "udp") {
 		log.Fatalf("[FATAL] Invalid connection type specified in adapter settings: %s\n", adapterSettings.ConnectionType)
 	}
 
@@ -163,14 +187,281 @@ func validateAdapterSettings() {
 		}
 	} else if adapterSettings.ConnectionType == "tcp" {
 		//Validate tcp fields
-		if adapterSettings.TcpHost == "" {
+		if adapterSettings.TcpHost == "" && !adapterSettings.TcpListen {
 			log.Fatal("[FATAL] host is required in adapter settings when connection type is set to 'serial'\n")
 		}
 
 		if adapterSettings.TcpPort == 0 {
 			log.Fatal("[FATAL] port is required in adapter settings when connection type is set to 'serial'\n")
 		}
+
+		if adapterSettings.TcpListen {
+			log.Printf("[DEBUG] Accepting receivers on tcp port %d\n", adapterSettings.TcpPort)
+			for source, name := range adapterSettings.Receivers {
+				if net.ParseIP(source) == nil {
+					log.Fatalf("[FATAL] Invalid receivers source IP specified in adapter settings: %s\n", source)
+				}
+				if !validReceiverName(name) {
+					log.Fatalf("[FATAL] Invalid receivers name specified in adapter settings: %s\n", name)
+				}
+				log.Printf("[DEBUG] Naming the receiver connecting from %s %s\n", source, name)
+			}
+		}
+	} else if adapterSettings.ConnectionType == "file" {
+		//Validate file fields
+		if adapterSettings.FilePath == "" {
+			log.Fatal("[FATAL] filePath is required in adapter settings when connection type is set to 'file'\n")
+		}
+
+		if adapterSettings.ReplaySpeed < 0 {
+			log.Fatal("[FATAL] replaySpeed must be positive\n")
+		} else if adapterSettings.ReplaySpeed == 0 {
+			log.Println("[DEBUG] Replaying SBF files as fast as possible")
+		} else {
+			log.Printf("[DEBUG] Replaying SBF files at %f times real time\n", adapterSettings.ReplaySpeed)
+		}
+	} else if adapterSettings.ConnectionType == "udp" {
+		//Validate udp fields
+		if adapterSettings.UdpPort <= 0 || adapterSettings.UdpPort > 65535 {
+			log.Fatal("[FATAL] udpPort is required in adapter settings when connection type is set to 'udp'\n")
+		}
+
+		if len(adapterSettings.UdpAllowedSources) == 0 {
+			log.Fatal("[FATAL] udpAllowedSources is required in adapter settings when connection type is set to 'udp'\n")
+		}
+		var err error
+		udpAllowedNetworks, err = parseAllowedSources(adapterSettings.UdpAllowedSources)
+		if err != nil {
+			log.Fatalf("[FATAL] Invalid udpAllowedSources specified in adapter settings: %s\n", err.Error())
+		}
+		log.Printf("[DEBUG] Accepting udp datagrams from %v\n", adapterSettings.UdpAllowedSources)
+	}
+	//Validate decoder settings
+	if adapterSettings.ExtSensorDecimation <= 0 {
+		log.Println("[DEBUG] Defaulting external sensor decimation to 1")
+		adapterSettings.ExtSensorDecimation = 1
+	} else {
+		log.Printf("[DEBUG] Publishing every %d external sensor measurement blocks\n", adapterSettings.ExtSensorDecimation)
+	}
+
+	if adapterSettings.GeotagDirectory == "" {
+		log.Println("[DEBUG] No geotag directory specified, event geotags will not be written")
+	} else {
+		log.Printf("[DEBUG] Writing event geotags to %s\n", adapterSettings.GeotagDirectory)
+	}
+
+	if adapterSettings.PPSOffsetLimit < 0 {
+		log.Fatal("[FATAL] ppsOffsetLimit must be positive\n")
+	} else if adapterSettings.PPSOffsetLimit > 0 {
+		log.Printf("[DEBUG] Raising a timing alarm when the PPS offset exceeds %f ns\n", adapterSettings.PPSOffsetLimit)
+	}
+
+	if !(adapterSettings.MinTimeSyncLevel == "" || adapterSettings.MinTimeSyncLevel == "none" ||
+		adapterSettings.MinTimeSyncLevel == "coarse" || adapterSettings.MinTimeSyncLevel == "fine") {
+		log.Fatalf("[FATAL] Invalid minTimeSyncLevel specified in adapter settings: %s\n", adapterSettings.MinTimeSyncLevel)
+	} else if adapterSettings.MinTimeSyncLevel != "" {
+		log.Printf("[DEBUG] Raising a timing alarm when the time sync level drops below %s\n", adapterSettings.MinTimeSyncLevel)
+	}
+
+	if adapterSettings.SubscriptionWarningDays <= 0 {
+		log.Println("[DEBUG] Defaulting subscription warning days to 14")
+		adapterSettings.SubscriptionWarningDays = 14
+	} else {
+		log.Printf("[DEBUG] Raising an alarm %d days before a correction subscription expires\n", adapterSettings.SubscriptionWarningDays)
+	}
+
+	if adapterSettings.JammingRaiseBlocks <= 0 {
+		log.Println("[DEBUG] Defaulting jamming raise blocks to 3")
+		adapterSettings.JammingRaiseBlocks = 3
+	} else {
+		log.Printf("[DEBUG] Raising jamming alarms after %d RFStatus blocks\n", adapterSettings.JammingRaiseBlocks)
+	}
+
+	if adapterSettings.JammingClearBlocks <= 0 {
+		log.Println("[DEBUG] Defaulting jamming clear blocks to 10")
+		adapterSettings.JammingClearBlocks = 10
+	} else {
+		log.Printf("[DEBUG] Clearing jamming alarms after %d RFStatus blocks\n", adapterSettings.JammingClearBlocks)
+	}
+
+	if adapterSettings.AGCDropLimit < 0 {
+		log.Fatal("[FATAL] agcDropLimit must be positive\n")
+	} else if adapterSettings.AGCDropLimit > 0 {
+		log.Printf("[DEBUG] Raising a health alarm when an AGC gain drops %f dB below its mean\n", adapterSettings.AGCDropLimit)
+	}
+
+	if adapterSettings.QualityThresholds == nil {
+		log.Println("[DEBUG] Defaulting quality thresholds to an overall score of 5")
+		adapterSettings.QualityThresholds = map[string]int{"overall": 5}
+	}
+	for name, threshold := range adapterSettings.QualityThresholds {
+		if !sbf.IsQualityIndicator(name) {
+			log.Fatalf("[FATAL] Invalid quality indicator specified in qualityThresholds: %s\n", name)
+		}
+		if threshold < 0 || threshold > 10 {
+			log.Fatalf("[FATAL] Quality threshold of %s must be between 0 and 10\n", name)
+		}
+		log.Printf("[DEBUG] Raising a quality alarm when the %s score drops below %d\n", name, threshold)
+	}
+
+	if adapterSettings.DiskFullPercent < 0 || adapterSettings.DiskFullPercent > 100 {
+		log.Fatal("[FATAL] diskFullPercent must be between 0 and 100\n")
+	} else if adapterSettings.DiskFullPercent == 0 {
+		log.Println("[DEBUG] Defaulting disk full percentage to 95")
+		adapterSettings.DiskFullPercent = 95
+	} else {
+		log.Printf("[DEBUG] Raising a disk full alarm when a disk is %f%% used\n", adapterSettings.DiskFullPercent)
+	}
+
+	if adapterSettings.LowBatteryPercent < 0 || adapterSettings.LowBatteryPercent > 100 {
+		log.Fatal("[FATAL] lowBatteryPercent must be between 0 and 100\n")
+	} else if adapterSettings.LowBatteryPercent == 0 {
+		log.Println("[DEBUG] Defaulting low battery percentage to 20")
+		adapterSettings.LowBatteryPercent = 20
+	} else {
+		log.Printf("[DEBUG] Raising a low battery alarm below %f%% charge\n", adapterSettings.LowBatteryPercent)
+	}
+
+	for _, pattern := range adapterSettings.ASCIIInPatterns {
+		if _, err := regexp.Compile(pattern.Pattern); err != nil {
+			log.Fatalf("[FATAL] Invalid asciiInPatterns pattern %s: %s\n", pattern.Name, err.Error())
+		}
+		log.Printf("[DEBUG] Parsing ASCIIIn strings with pattern %s\n", pattern.Name)
+	}
+
+	if adapterSettings.CorrectionsInterval < 0 {
+		log.Fatal("[FATAL] correctionsInterval must be positive\n")
+	} else if adapterSettings.CorrectionsInterval > 0 {
+		if adapterSettings.ReferencePosition == nil {
+			log.Fatal("[FATAL] referencePosition is required in adapter settings when correctionsInterval is set\n")
+		}
+		if adapterSettings.CorrectionsMSM == 0 {
+			log.Println("[DEBUG] Defaulting corrections to MSM4")
+			adapterSettings.CorrectionsMSM = 4
+		} else if adapterSettings.CorrectionsMSM != 4 && adapterSettings.CorrectionsMSM != 7 {
+			log.Fatalf("[FATAL] Invalid correctionsMSM specified in adapter settings: %d\n", adapterSettings.CorrectionsMSM)
+		}
+		if adapterSettings.CorrectionsStationID < 0 || adapterSettings.CorrectionsStationID > 4095 {
+			log.Fatal("[FATAL] correctionsStationId must be between 0 and 4095\n")
+		}
+		if adapterSettings.CorrectionsStationInterval <= 0 {
+			log.Println("[DEBUG] Defaulting corrections station interval to 10 seconds")
+			adapterSettings.CorrectionsStationInterval = 10
+		}
+		log.Printf("[DEBUG] Generating MSM%d corrections for station %d every %f seconds\n", adapterSettings.CorrectionsMSM, adapterSettings.CorrectionsStationID, adapterSettings.CorrectionsInterval)
+	}
+
+	if adapterSettings.RinexDirectory == "" {
+		log.Println("[DEBUG] No RINEX directory specified, RINEX files will not be written")
+	} else {
+		if adapterSettings.RinexVersion == "" {
+			log.Println("[DEBUG] Defaulting RINEX version to 3.05")
+			adapterSettings.RinexVersion = "3.05"
+		} else if adapterSettings.RinexVersion != "3.05" && adapterSettings.RinexVersion != "4.00" {
+			log.Fatalf("[FATAL] Invalid rinexVersion specified in adapter settings: %s\n", adapterSettings.RinexVersion)
+		}
+		if adapterSettings.RinexRotation == "" {
+			log.Println("[DEBUG] Defaulting RINEX rotation to daily")
+			adapterSettings.RinexRotation = "daily"
+		} else if adapterSettings.RinexRotation != "hourly" && adapterSettings.RinexRotation != "daily" {
+			log.Fatalf("[FATAL] Invalid rinexRotation specified in adapter settings: %s\n", adapterSettings.RinexRotation)
+		}
+		log.Printf("[DEBUG] Writing %s RINEX %s files to %s\n", adapterSettings.RinexRotation, adapterSettings.RinexVersion, adapterSettings.RinexDirectory)
+	}
+
+	if adapterSettings.NMEATcpPort < 0 || adapterSettings.NMEATcpPort > 65535 {
+		log.Fatal("[FATAL] nmeaTcpPort must be between 1 and 65535\n")
+	} else if adapterSettings.NMEATcpPort > 0 && adapterSettings.ConnectionType == "tcp" && adapterSettings.TcpListen {
+		// The sentences of the receivers would be interleaved on the one port
+		log.Fatal("[FATAL] nmeaTcpPort cannot be used with tcpListen\n")
+	} else if adapterSettings.NMEATcpPort > 0 && !adapterSettings.NMEAOutput {
+		log.Println("[DEBUG] Enabling NMEA output for the NMEA tcp port")
+		adapterSettings.NMEAOutput = true
+	}
+	if adapterSettings.NMEAOutput {
+		log.Println("[DEBUG] Generating NMEA sentences from the PVT and attitude blocks")
+	}
+
+	if adapterSettings.TrackRetention < 0 {
+		log.Fatal("[FATAL] trackRetention must be positive\n")
+	} else if adapterSettings.TrackRetention == 0 {
+		log.Println("[DEBUG] Defaulting track retention to 86400 seconds")
+		adapterSettings.TrackRetention = 86400
+	} else {
+		log.Printf("[DEBUG] Keeping %f seconds of track for track requests\n", adapterSettings.TrackRetention)
 	}
+
+	if adapterSettings.TrackInterval < 0 {
+		log.Fatal("[FATAL] trackInterval must be positive\n")
+	} else if adapterSettings.TrackInterval == 0 {
+		log.Println("[DEBUG] Defaulting track interval to 1 second")
+		adapterSettings.TrackInterval = 1
+	} else {
+		log.Printf("[DEBUG] Adding a track point every %f seconds\n", adapterSettings.TrackInterval)
+	}
+
+	if adapterSettings.TrackDirectory == "" {
+		log.Println("[DEBUG] No track directory specified, track files will not be written")
+	} else {
+		if adapterSettings.TrackRotation == "" {
+			log.Println("[DEBUG] Defaulting track rotation to daily")
+			adapterSettings.TrackRotation = "daily"
+		} else if adapterSettings.TrackRotation != "hourly" && adapterSettings.TrackRotation != "daily" {
+			log.Fatalf("[FATAL] Invalid trackRotation specified in adapter settings: %s\n", adapterSettings.TrackRotation)
+		}
+		if len(adapterSettings.TrackFormats) == 0 {
+			log.Println("[DEBUG] Defaulting track formats to geojson, gpx and kml")
+			adapterSettings.TrackFormats = sbf.TrackFormats
+		}
+		for _, format := range adapterSettings.TrackFormats {
+			if !(format == "geojson" || format == "gpx" || format == "kml") {
+				log.Fatalf("[FATAL] Invalid track format specified in trackFormats: %s\n", format)
+			}
+		}
+		log.Printf("[DEBUG] Writing %s track files to %s\n", adapterSettings.TrackRotation, adapterSettings.TrackDirectory)
+	}
+
+	if adapterSettings.NtripCaster == "" {
+		log.Println("[DEBUG] No NTRIP caster specified, the NTRIP client is disabled")
+	} else {
+		if !(adapterSettings.ConnectionType == "serial" || (adapterSettings.ConnectionType == "tcp" && !adapterSettings.TcpListen)) {
+			log.Fatal("[FATAL] The NTRIP client requires a serial or tcp connection to the receiver\n")
+		}
+		if adapterSettings.NtripMountpoint == "" {
+			log.Fatal("[FATAL] ntripMountpoint is required in adapter settings when ntripCaster is set\n")
+		}
+		if adapterSettings.NtripPort < 0 || adapterSettings.NtripPort > 65535 {
+			log.Fatal("[FATAL] ntripPort must be between 1 and 65535\n")
+		} else if adapterSettings.NtripPort == 0 {
+			log.Println("[DEBUG] Defaulting NTRIP port to 2101")
+			adapterSettings.NtripPort = 2101
+		}
+		if adapterSettings.NtripVersion == 0 {
+			log.Println("[DEBUG] Defaulting NTRIP version to 2")
+			adapterSettings.NtripVersion = 2
+		} else if adapterSettings.NtripVersion != 1 && adapterSettings.NtripVersion != 2 {
+			log.Fatalf("[FATAL] Invalid ntripVersion specified in adapter settings: %d\n", adapterSettings.NtripVersion)
+		}
+		if adapterSettings.NtripGGAInterval < 0 {
+			log.Fatal("[FATAL] ntripGgaInterval must be positive\n")
+		} else if adapterSettings.NtripGGAInterval == 0 {
+			log.Println("[DEBUG] Defaulting NTRIP GGA interval to 10 seconds")
+			adapterSettings.NtripGGAInterval = 10
+		}
+		if adapterSettings.NTRIPCorrectionAgeLimit == 0 {
+			log.Println("[DEBUG] Defaulting NTRIP correction age limit to 10 seconds")
+			adapterSettings.NTRIPCorrectionAgeLimit = 10
+		}
+		log.Printf("[DEBUG] Writing the corrections of NTRIP %d mountpoint %s on %s:%d to the receiver\n", adapterSettings.NtripVersion, adapterSettings.NtripMountpoint, adapterSettings.NtripCaster, adapterSettings.NtripPort)
+	}
+
+	if adapterSettings.NTRIPCorrectionAgeLimit < 0 {
+		log.Fatal("[FATAL] ntripCorrectionAgeLimit must be positive\n")
+	} else if adapterSettings.NTRIPCorrectionAgeLimit > 0 {
+		log.Printf("[DEBUG] Raising a correction age alarm when the corrections are more than %f seconds old\n", adapterSettings.NTRIPCorrectionAgeLimit)
+	}
+
+	sbf.Configure(adapterSettings.Settings)
 }
 
 func getSerialMode() *serial.Mode {
@@ -213,26 +504,57 @@ func readWorker() {
 	log.Println("[INFO] readWorker - Starting readWorker")
 	if adapterSettings.ConnectionType == "serial" {
 		readFromSerialPort()
+	} else if adapterSettings.ConnectionType == "tcp" && adapterSettings.TcpListen {
+		readFromTcpListener()
 	} else if adapterSettings.ConnectionType == "tcp" {
 		readFromTcpPort()
+	} else if adapterSettings.ConnectionType == "file" {
+		readFromFiles()
+	} else if adapterSettings.ConnectionType == "udp" {
+		readFromUdpPort()
 	}
 }
 
-// Publishes data to a topic
+// Publishes data to a topic, as JSON unless it is binary
 func publish(topic string, data interface{}) {
-	b, err := json.Marshal(data)
-	if err != nil {
-		log.Printf("[ERROR] Failed to stringify JSON: %s\n", err.Error())
-		return
+	// Binary data, such as RTCM3 corrections, is published as is
+	b, ok := data.([]byte)
+	if !ok {
+		var err error
+		b, err = json.Marshal(data)
+		if err != nil {
+			log.Printf("[ERROR] Failed to stringify JSON: %s\n", err.Error())
+			return
+		}
 	}
 
 	log.Printf("[DEBUG] publish - Publishing to topic %s\n", topic)
-	err = adapter_library.Publish(topic, b)
+	err := mqttPublish(topic, b)
 	if err != nil {
 		log.Printf("[ERROR] Failed to publish MQTT message to topic %s: %s\n", topic, err.Error())
 	}
 }
 
+// Publishes the data decoded from the receiver to the receive topic
+func publishPayloads(payloads []interface{}) {
+	publishPayloadsTo(adapterConfig.TopicRoot, payloads)
+}
+
+// Publishes the data decoded from a receiver to the receive topic under root
+func publishPayloadsTo(root string, payloads []interface{}) {
+	for _, p := range payloads {
+		payload, ok := p.(sbf.Payload)
+		if !ok {
+			log.Printf("[ERROR] publishPayloads - Unexpected payload type %T\n", p)
+			continue
+		}
+		publish(root+"/"+portRead+"/"+payload.Topic, payload.Data)
+		if payload.Topic == sbf.NMEAOutputTopic && adapterSettings.NMEATcpPort > 0 {
+			writeToNMEAClients(payload.Data.([]byte))
+		}
+	}
+}
+
 func readFromSerialPort() {
 
 	var err error
@@ -262,7 +584,7 @@ func readFromSerialPort() {
 
 				buffer = append(buffer, buff[:n]...)
 
-				sbf.Parse(&buffer)
+				publishPayloads(sbf.Parse(&buffer))
 			}
 		}
 	}
@@ -317,7 +639,7 @@ func readFromTcpPort() {
 				log.Printf("[DEBUG] %v\n", buff[:n])
 
 				buffer = append(buffer, buff[:n]...)
-				sbf.Parse(&buffer)
+				publishPayloads(sbf.Parse(&buffer))
 			}
 		}
 	}
@@ -330,18 +652,27 @@ func writeToPort(payload []byte) {
 		} else {
 			log.Print("[ERROR] writeToPort - Cannot write to serial port. Port not open.\n")
 		}
+	} else if adapterSettings.ConnectionType == "tcp" && adapterSettings.TcpListen {
+		log.Print("[ERROR] writeToPort - Cannot write to a listening tcp port, send commands to the topics of a receiver.\n")
 	} else if adapterSettings.ConnectionType == "tcp" {
 		if port != nil {
 			writeToTcpPort(payload)
 		} else {
 			log.Print("[ERROR] writeToPort - Cannot write to tcp port. Port not open.\n")
 		}
+	} else if adapterSettings.ConnectionType == "file" {
+		log.Print("[ERROR] writeToPort - Cannot write to a replayed file.\n")
+	} else if adapterSettings.ConnectionType == "udp" {
+		log.Print("[ERROR] writeToPort - Cannot write to a udp stream, send commands over another connection.\n")
 	} else {
 		log.Printf("[ERROR] writeToPort - Invalid port type configured: %s\n", adapterSettings.ConnectionType)
 	}
 }
 
 func writeToSerialPort(bytes []byte) {
+	portWriteMutex.Lock()
+	defer portWriteMutex.Unlock()
+
 	n, err := port.(serial.Port).Write(bytes)
 	if err != nil {
 		log.Printf("[ERROR] writeToSerialPort - Error writing to serial port: %s\n", err.Error())
@@ -350,6 +681,9 @@ func writeToSerialPort(bytes []byte) {
 }
 
 func writeToTcpPort(bytes []byte) {
+	portWriteMutex.Lock()
+	defer portWriteMutex.Unlock()
+
 	n, err := port.(net.Conn).Write(bytes)
 	if err != nil {
 		log.Printf("[ERROR] writeToSerialPort - Error writing to tcp port: %s\n", err.Error())
@@ -357,7 +691,21 @@ func writeToTcpPort(bytes []byte) {
 	log.Printf("[DEBUG] writeToSerialPort - Wrote %d bytes to tcp port\n", n)
 }
 
+// Routes the messages of the adapter's topics. Only commands are written to
+// the receiver, the adapter's own publications on the receive topics are
+// ignored. A listening tcp port routes the topics of every receiver.
 func cbMessageHandler(message *mqttTypes.Publish) {
-	//TODO - Add code to construct the appropriate payload
-	writeToPort(message.Payload)
+	topic := strings.TrimPrefix(message.Topic.Whole, adapterConfig.TopicRoot+"/")
+	if adapterSettings.ConnectionType == "tcp" && adapterSettings.TcpListen {
+		handleReceiverMessage(topic, message.Payload)
+		return
+	}
+	switch topic {
+	case portWrite, commandRequest:
+		writeToPort(message.Payload)
+	case trackRequest:
+		publishPayloads(sbf.HandleTrackRequest(message.Payload))
+	default:
+		log.Printf("[DEBUG] cbMessageHandler - Ignoring message on topic %s\n", message.Topic.Whole)
+	}
 }
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	sbf "Septentrio-GNSS-Adapter/sbf"
+)
+
+/**
+ * Listening mode of the tcp connection type: receivers connect to the adapter,
+ * e.g. with setIPServerSettings, rather than the adapter dialing a receiver.
+ * Every connection is parsed with its own decoder state and published under
+ * its own topics, {TOPIC ROOT}/{receiver}/. A receiver is named by the
+ * receivers setting for its source IP, or by the serial number of its
+ * ReceiverSetup block. Until that block is received it is named by its source
+ * IP.
+ */
+
+// receiverConnection is a receiver connected to the listening tcp port
+type receiverConnection struct {
+	conn       net.Conn
+	receiver   *sbf.Receiver
+	buffer     []byte
+	id         string
+	identified bool // Named by the receivers setting or its serial number, or no longer looking for one
+}
+
+var (
+	receiverConnections      = map[string]*receiverConnection{}
+	receiverConnectionsMutex sync.Mutex
+)
+
+func readFromTcpListener() {
+	address := net.JoinHostPort(adapterSettings.TcpHost, strconv.Itoa(adapterSettings.TcpPort))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Fatalf("[ERROR] readFromTcpListener - Error listening on tcp port %s: %s\n", address, err.Error())
+		return
+	}
+	log.Printf("[INFO] readFromTcpListener - Accepting receivers on tcp port %s\n", address)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				log.Printf("[ERROR] readFromTcpListener - Error accepting receiver: %s\n", err.Error())
+				continue
+			}
+			go readFromReceiver(conn)
+		}
+	}()
+
+	<-endWorkersChannel
+	log.Println("[DEBUG] readFromTcpListener - stopping tcp listen worker")
+	listener.Close()
+	receiverConnectionsMutex.Lock()
+	for _, connection := range receiverConnections {
+		connection.conn.Close()
+	}
+	receiverConnectionsMutex.Unlock()
+}
+
+// Reads and publishes the data of one receiver until it disconnects
+func readFromReceiver(conn net.Conn) {
+	source := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(source); err == nil {
+		source = host
+	}
+	connection := &receiverConnection{conn: conn, id: source}
+	if name, ok := adapterSettings.Receivers[source]; ok {
+		connection.id = name
+		connection.identified = true
+	}
+	connection.receiver = sbf.NewReceiver(connection.id)
+	log.Printf("[INFO] readFromReceiver - Receiver %s connected from %s\n", connection.id, source)
+	registerReceiver(connection, "")
+
+	defer func() {
+		conn.Close()
+		connection.receiver.Close()
+		receiverConnectionsMutex.Lock()
+		if receiverConnections[connection.id] == connection {
+			delete(receiverConnections, connection.id)
+		}
+		receiverConnectionsMutex.Unlock()
+		log.Printf("[INFO] readFromReceiver - Receiver %s disconnected\n", connection.id)
+	}()
+
+	buff := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buff)
+		if n > 0 {
+			log.Printf("[DEBUG] readFromReceiver - %d bytes read from receiver %s\n", n, connection.id)
+
+			connection.buffer = append(connection.buffer, buff[:n]...)
+			payloads := connection.receiver.Parse(&connection.buffer)
+			if !connection.identified {
+				identifyReceiver(connection)
+			}
+			publishPayloadsTo(adapterConfig.TopicRoot+"/"+connection.id, payloads)
+		}
+		if err != nil {
+			log.Printf("[ERROR] readFromReceiver - Error reading from receiver %s: %s\n", connection.id, err.Error())
+			return
+		}
+	}
+}
+
+// Names a receiver by its serial number once its ReceiverSetup block has been
+// received
+func identifyReceiver(connection *receiverConnection) {
+	serial := connection.receiver.Serial()
+	if serial == "" {
+		return
+	}
+	if !validReceiverName(serial) {
+		log.Printf("[ERROR] identifyReceiver - Serial number %q of receiver %s cannot name its topics, keeping the source IP\n", serial, connection.id)
+		connection.identified = true
+		return
+	}
+	previous := connection.id
+	connection.id = serial
+	connection.identified = true
+	connection.receiver.SetName(serial)
+	log.Printf("[INFO] identifyReceiver - Receiver %s identified by serial number %s\n", previous, serial)
+	registerReceiver(connection, previous)
+}
+
+// Whether a name can be used as the topic level and subdirectory of a
+// receiver
+func validReceiverName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "/+#") && name != "." && name != ".." &&
+		name != portRead && name != portWrite && name != commandRequest
+}
+
+// Makes a connection the one of its receiver id, replacing a stale connection
+// of the same receiver
+func registerReceiver(connection *receiverConnection, previous string) {
+	receiverConnectionsMutex.Lock()
+	defer receiverConnectionsMutex.Unlock()
+
+	if previous != "" && receiverConnections[previous] == connection {
+		delete(receiverConnections, previous)
+	}
+	if existing, ok := receiverConnections[connection.id]; ok && existing != connection {
+		log.Printf("[INFO] registerReceiver - Replacing the previous connection of receiver %s\n", connection.id)
+		existing.conn.Close()
+	}
+	receiverConnections[connection.id] = connection
+}
+
+func findReceiver(id string) *receiverConnection {
+	receiverConnectionsMutex.Lock()
+	defer receiverConnectionsMutex.Unlock()
+
+	return receiverConnections[id]
+}
+
+// Routes a message on the topics of a receiver connected to the listening tcp
+// port: {receiver}/send, {receiver}/request and {receiver}/request/track
+func handleReceiverMessage(topic string, payload []byte) {
+	parts := strings.SplitN(topic, "/", 2)
+	if len(parts) < 2 || (parts[1] != portWrite && parts[1] != commandRequest && parts[1] != trackRequest) {
+		log.Printf("[DEBUG] handleReceiverMessage - Ignoring message on topic %s\n", topic)
+		return
+	}
+	connection := findReceiver(parts[0])
+	if connection == nil {
+		log.Printf("[ERROR] handleReceiverMessage - Receiver %s is not connected\n", parts[0])
+		return
+	}
+
+	if parts[1] == trackRequest {
+		publishPayloadsTo(adapterConfig.TopicRoot+"/"+parts[0], connection.receiver.HandleTrackRequest(payload))
+		return
+	}
+	n, err := connection.conn.Write(payload)
+	if err != nil {
+		log.Printf("[ERROR] handleReceiverMessage - Error writing to receiver %s: %s\n", parts[0], err.Error())
+	}
+	log.Printf("[DEBUG] handleReceiverMessage - Wrote %d bytes to receiver %s\n", n, parts[0])
+}
@@ -1,13 +1,32 @@
 package main
 
+import sbf "Septentrio-GNSS-Adapter/sbf"
+
 type SeptentrioGNSSAdapterSettings struct {
-	ConnectionType string  `json:"connectionType"`
-	TcpHost        string  `json:"host"`
-	TcpPort        int     `json:"tcpPort"`
-	SerialPort     string  `json:"serialPort"`
-	BaudRate       int     `json:"baudRate"`
-	Size           int     `json:"dataBits"`
-	Parity         string  `json:"parity"`
-	StopBits       float32 `json:"stopBits"`
-	Timeout        int     `json:"readTimeout"`
+	sbf.Settings
+
+	ConnectionType    string            `json:"connectionType"`
+	TcpHost           string            `json:"host"`
+	TcpPort           int               `json:"tcpPort"`
+	TcpListen         bool              `json:"tcpListen"`
+	Receivers         map[string]string `json:"receivers"`
+	SerialPort        string            `json:"serialPort"`
+	BaudRate          int               `json:"baudRate"`
+	Size              int               `json:"dataBits"`
+	Parity            string            `json:"parity"`
+	StopBits          float32           `json:"stopBits"`
+	Timeout           int               `json:"readTimeout"`
+	NMEATcpPort       int               `json:"nmeaTcpPort"`
+	FilePath          string            `json:"filePath"`
+	ReplaySpeed       float64           `json:"replaySpeed"`
+	UdpHost           string            `json:"udpHost"`
+	UdpPort           int               `json:"udpPort"`
+	UdpAllowedSources []string          `json:"udpAllowedSources"`
+	NtripCaster       string            `json:"ntripCaster"`
+	NtripPort         int               `json:"ntripPort"`
+	NtripMountpoint   string            `json:"ntripMountpoint"`
+	NtripUsername     string            `json:"ntripUsername"`
+	NtripPassword     string            `json:"ntripPassword"`
+	NtripVersion      int               `json:"ntripVersion"`
+	NtripGGAInterval  float64           `json:"ntripGgaInterval"`
 }
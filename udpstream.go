@@ -0,0 +1,208 @@
+package main
+
+import (
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	sbf "Septentrio-GNSS-Adapter/sbf"
+)
+
+/**
+ * UDP connection type: the receiver streams SBF to the adapter with
+ * setIPReceiveSettings/setDataInOut. Datagrams from sources that are not in
+ * udpAllowedSources are dropped. UDP gives no delivery guarantee, so lost
+ * datagrams are detected from the gaps in the TOW of every SBF block type and
+ * reported on connectivity/udp. Only block types output at a stable interval
+ * are checked for gaps.
+ */
+
+const udpStatusTopic = "connectivity/udp"
+
+/* Largest UDP payload, datagrams must be read whole */
+const maxDatagramSize = 65535
+
+/* Seconds between two UDPStatus messages */
+const udpStatusInterval = 10
+
+// UDPStatus counts the datagrams and SBF blocks received on the UDP port
+type UDPStatus struct {
+	Time          time.Time `json:"time"`
+	Datagrams     int       `json:"datagrams"`
+	Bytes         int       `json:"bytes"`
+	Rejected      int       `json:"rejected"`      // Datagrams from sources that are not allowed
+	Blocks        int       `json:"blocks"`        // SBF blocks received
+	MissingBlocks int       `json:"missingBlocks"` // SBF blocks lost, from the TOW gaps
+	LossPercent   float64   `json:"lossPercent"`   // Missing blocks in % of the expected ones
+	LastSource    string    `json:"lastSource,omitempty"`
+}
+
+/* Whitelisted source networks, datagrams from other sources are dropped */
+var udpAllowedNetworks []*net.IPNet
+
+/* Times the same TOW step must be seen in a row to become the interval of a block type */
+const towStableSteps = 3
+
+// towGapCounter counts the SBF blocks missing from a stream. The interval of
+// every block type is learnt once the same TOW step is seen towStableSteps
+// times in a row, a larger step then means blocks were lost. Blocks output on
+// change, such as the navigation messages or ReceiverSetup, never get a stable
+// interval and are not counted.
+type towGapCounter struct {
+	last      map[uint16]time.Time
+	interval  map[uint16]time.Duration
+	candidate map[uint16]time.Duration
+	repeats   map[uint16]int
+}
+
+func newTowGapCounter() *towGapCounter {
+	return &towGapCounter{
+		last:      map[uint16]time.Time{},
+		interval:  map[uint16]time.Duration{},
+		candidate: map[uint16]time.Duration{},
+		repeats:   map[uint16]int{},
+	}
+}
+
+// add returns the number of blocks of the type of block missing before it
+func (c *towGapCounter) add(block sbf.BlockInfo) int {
+	if block.Time == nil {
+		return 0
+	}
+	number := block.ID & 0x1FFF
+	last, seen := c.last[number]
+	c.last[number] = *block.Time
+	if !seen {
+		return 0
+	}
+
+	step := block.Time.Sub(last)
+	if step <= 0 {
+		// Repeated epoch, or the receiver time was reset
+		return 0
+	}
+	if step == c.candidate[number] {
+		c.repeats[number]++
+	} else {
+		c.candidate[number] = step
+		c.repeats[number] = 1
+	}
+	if c.repeats[number] >= towStableSteps {
+		c.interval[number] = step
+	}
+
+	interval, known := c.interval[number]
+	if !known || step < interval*3/2 {
+		return 0
+	}
+	return int((step+interval/2)/interval) - 1
+}
+
+// Parses the udpAllowedSources, IP addresses or CIDR networks
+func parseAllowedSources(sources []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, source := range sources {
+		if _, network, err := net.ParseCIDR(source); err == nil {
+			networks = append(networks, network)
+			continue
+		}
+		ip := net.ParseIP(source)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: source}
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 8 * net.IPv4len
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return networks, nil
+}
+
+func udpSourceAllowed(ip net.IP) bool {
+	for _, network := range udpAllowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func readFromUdpPort() {
+	address := net.JoinHostPort(adapterSettings.UdpHost, strconv.Itoa(adapterSettings.UdpPort))
+	udpAddress, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		log.Fatalf("[ERROR] readFromUdpPort - Invalid udp address %s: %s\n", address, err.Error())
+		return
+	}
+	conn, err := net.ListenUDP("udp", udpAddress)
+	if err != nil {
+		log.Fatalf("[ERROR] readFromUdpPort - Error opening udp port: %s\n", err.Error())
+		return
+	}
+	port = conn
+
+	defer conn.Close()
+
+	status := UDPStatus{}
+	gaps := newTowGapCounter()
+	lastStatus := time.Now()
+	var pending []byte
+	buff := make([]byte, maxDatagramSize)
+	for {
+		select {
+		case <-endWorkersChannel:
+			log.Println("[DEBUG] readFromUdpPort - stopping udp read worker")
+			return
+		default:
+			// The deadline lets the worker check for the end of the adapter
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, source, err := conn.ReadFromUDP(buff)
+			if err != nil {
+				if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+					log.Printf("[ERROR] readFromUdpPort - Error reading from udp port: %s\n", err.Error())
+				}
+			} else if !udpSourceAllowed(source.IP) {
+				log.Printf("[DEBUG] readFromUdpPort - Dropping %d bytes from %s, not an allowed source\n", n, source.String())
+				status.Rejected++
+			} else if n > 0 {
+				log.Printf("[DEBUG] readFromUdpPort - %d bytes read from %s\n", n, source.String())
+				status.Datagrams++
+				status.Bytes += n
+				status.LastSource = source.IP.String()
+
+				// Blocks may span datagrams, they are counted once complete
+				pending = append(pending, buff[:n]...)
+				for {
+					block, ok := sbf.NextBlock(pending)
+					if !ok {
+						break
+					}
+					status.Blocks++
+					if missing := gaps.add(block); missing > 0 {
+						log.Printf("[DEBUG] readFromUdpPort - %d blocks %d missing before TOW %d\n", missing, block.ID&0x1FFF, block.TOW)
+						status.MissingBlocks += missing
+					}
+					pending = pending[block.End:]
+				}
+				if len(pending) > maxDatagramSize {
+					pending = pending[len(pending)-maxDatagramSize:]
+				}
+
+				buffer = append(buffer, buff[:n]...)
+				publishPayloads(sbf.Parse(&buffer))
+			}
+
+			if time.Since(lastStatus).Seconds() >= udpStatusInterval {
+				lastStatus = time.Now()
+				status.Time = lastStatus.UTC()
+				if expected := status.Blocks + status.MissingBlocks; expected > 0 {
+					status.LossPercent = 100 * float64(status.MissingBlocks) / float64(expected)
+				}
+				publish(adapterConfig.TopicRoot+"/"+portRead+"/"+udpStatusTopic, status)
+			}
+		}
+	}
+}